@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/exchange"
+)
+
+// runExport implements the export command. It writes the same JSONL format
+// as the server's export subcommand; the summary goes to stderr unless an
+// output file is given.
+func runExport(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("export")
+	path := flags.String("o", "-", "Output file (- for stdout)")
+	groupID := flags.String("group", "", "Only export this group")
+	includeEmbeddings := flags.Bool("embeddings", true, "Include embedding vectors")
+	flags.Parse(args)
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var w io.Writer = os.Stdout
+	if *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *path, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	stats, err := exchange.Export(store, buffered, exchange.ExportOptions{
+		GroupID:           *groupID,
+		IncludeEmbeddings: *includeEmbeddings,
+		EmbeddingModel:    cfg.EmbeddingConfig.Model,
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	// Stdout carries the export itself, so the summary can't go there
+	if *path == "-" {
+		log.Printf("Exported %d memories and %d edges", stats.Memories, stats.Edges)
+		return nil
+	}
+	return output(*asJSON, stats, func(w io.Writer) {
+		fmt.Fprintf(w, "Exported %d memories and %d edges to %s\n", stats.Memories, stats.Edges, *path)
+	})
+}
+
+// runImport implements the import command, reading stdin when no file is given
+func runImport(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("import")
+	flags.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	stats, importErr := exchange.Import(store, embeddings.NewClient(cfg.EmbeddingConfig), r)
+	err = output(*asJSON, stats, func(w io.Writer) {
+		fmt.Fprintf(w, "Imported %d new and %d updated memories (%d re-embedded), %d edges (%d skipped)\n",
+			stats.Created, stats.Updated, stats.Reembedded, stats.Edges, stats.SkippedEdges)
+	})
+	if importErr != nil {
+		return importErr
+	}
+	return err
+}
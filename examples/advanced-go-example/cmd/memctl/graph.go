@@ -0,0 +1,222 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"advanced-go-example/pkg/analytics"
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/storage"
+)
+
+// runLink implements the link command: from-id TYPE to-id. The type may be
+// an inverse name; it is stored under its canonical type and direction.
+func runLink(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("link")
+	reason := flags.String("reason", "", "Why the memories are related")
+	confidence := flags.Float64("confidence", 0, "Confidence from 0 to 1 (unset when 0)")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		return fmt.Errorf("usage: link [flags] from-id TYPE to-id")
+	}
+	ids, err := parseIDs([]string{flags.Arg(0), flags.Arg(2)})
+	if err != nil {
+		return err
+	}
+	if ids[0] == ids[1] {
+		return fmt.Errorf("a memory cannot be linked to itself")
+	}
+	if *confidence < 0 || *confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", *confidence)
+	}
+
+	properties := map[string]interface{}{}
+	if *reason != "" {
+		properties["reason"] = *reason
+	}
+	if *confidence != 0 {
+		properties["confidence"] = *confidence
+	}
+
+	relationshipTypes, err := cfg.LoadOntology()
+	if err != nil {
+		return fmt.Errorf("failed to load relationship ontology: %w", err)
+	}
+	fromID, toID, relType, err := relationshipTypes.Normalize(ids[0], ids[1], flags.Arg(1), properties)
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	id, err := store.AddRelationship(fromID, toID, relType, properties)
+	if err != nil {
+		return fmt.Errorf("failed to add relationship: %w", err)
+	}
+
+	rel := storage.Relationship{ID: id, FromID: fromID, ToID: toID, Type: relType, Properties: properties}
+	return output(*asJSON, rel, func(w io.Writer) {
+		fmt.Fprintf(w, "Created edge %d: %d -[%s]-> %d\n", id, fromID, relType, toID)
+	})
+}
+
+// runExplore implements the explore command
+func runExplore(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("explore")
+	depth := flags.Int("depth", 2, "Hops to follow")
+	direction := flags.String("direction", storage.DirectionBoth, "out, in or both")
+	types := flags.String("types", "", "Comma-separated relationship types to follow (default: all)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: explore [flags] id")
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *depth < 1 {
+		return fmt.Errorf("-depth must be positive")
+	}
+	if !slices.Contains([]string{storage.DirectionOut, storage.DirectionIn, storage.DirectionBoth}, *direction) {
+		return fmt.Errorf("-direction must be out, in or both, got %q", *direction)
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	subgraph, err := store.Traverse(ids[0], storage.TraversalOptions{
+		MaxDepth:  *depth,
+		Direction: *direction,
+		Types:     splitList(*types),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to explore connections: %w", err)
+	}
+
+	return output(*asJSON, subgraph, func(w io.Writer) {
+		fmt.Fprintln(w, "DEPTH\tID\tGROUP\tTEXT")
+		for _, node := range subgraph.Nodes {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", node.Depth, node.Memory.ID, node.Memory.GroupID, cell(node.Memory.Text, textWidth))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "EDGE\tFROM\tTYPE\tTO")
+		for _, edge := range subgraph.Edges {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%d\n", edge.ID, edge.FromID, edge.Type, edge.ToID)
+		}
+	})
+}
+
+// graphStats is what stats prints
+type graphStats struct {
+	Groups         []storage.GroupCount `json:"groups"`
+	NodeCount      int                  `json:"node_count"`
+	EdgeCount      int                  `json:"edge_count"`
+	EdgesByType    map[string]int       `json:"edges_by_type"`
+	OrphanCount    int                  `json:"orphan_count"`
+	CommunityCount int                  `json:"community_count"`
+	MaxDegree      int                  `json:"max_degree"`
+	MeanDegree     float64              `json:"mean_degree"`
+}
+
+// runStats implements the stats command, a summary of graph_stats
+func runStats(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("stats")
+	groupID := flags.String("group", "", "Only analyze this group")
+	flags.Parse(args)
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	groups, err := store.ListGroups()
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+	if *groupID != "" {
+		groups = slices.DeleteFunc(groups, func(group storage.GroupCount) bool { return group.GroupID != *groupID })
+	}
+
+	snapshot, err := store.GraphSnapshot(*groupID)
+	if err != nil {
+		return fmt.Errorf("failed to export graph: %w", err)
+	}
+
+	stats := graphStats{
+		Groups:      groups,
+		NodeCount:   len(snapshot.MemoryIDs),
+		EdgeCount:   len(snapshot.Edges),
+		EdgesByType: map[string]int{},
+	}
+	edges := make([]analytics.Edge, len(snapshot.Edges))
+	for i, rel := range snapshot.Edges {
+		stats.EdgesByType[rel.Type]++
+		edges[i] = analytics.Edge{From: rel.FromID, To: rel.ToID, Weight: storage.EdgeConfidence(rel)}
+	}
+
+	graph := analytics.NewGraph(snapshot.MemoryIDs, edges)
+	total := 0
+	for _, degree := range graph.Degrees() {
+		if degree == 0 {
+			stats.OrphanCount++
+		}
+		stats.MaxDegree = max(stats.MaxDegree, degree)
+		total += degree
+	}
+	if stats.NodeCount > 0 {
+		stats.MeanDegree = float64(total) / float64(stats.NodeCount)
+	}
+
+	communities := map[int64]bool{}
+	for _, clusterID := range graph.Communities() {
+		communities[clusterID] = true
+	}
+	stats.CommunityCount = len(communities)
+
+	return output(*asJSON, stats, func(w io.Writer) {
+		fmt.Fprintln(w, "GROUP\tMEMORIES")
+		for _, group := range groups {
+			name := group.GroupID
+			if name == "" {
+				name = "(none)"
+			}
+			fmt.Fprintf(w, "%s\t%d\n", name, group.Count)
+		}
+		fmt.Fprintln(w)
+
+		types := make([]string, 0, len(stats.EdgesByType))
+		for relType := range stats.EdgesByType {
+			types = append(types, relType)
+		}
+		slices.SortFunc(types, func(a, b string) int {
+			if c := cmp.Compare(stats.EdgesByType[b], stats.EdgesByType[a]); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		})
+		byType := make([]string, len(types))
+		for i, relType := range types {
+			byType[i] = relType + "=" + strconv.Itoa(stats.EdgesByType[relType])
+		}
+
+		fmt.Fprintf(w, "Memories:\t%d\n", stats.NodeCount)
+		fmt.Fprintf(w, "Edges:\t%d\t%s\n", stats.EdgeCount, strings.Join(byType, " "))
+		fmt.Fprintf(w, "Orphans:\t%d\n", stats.OrphanCount)
+		fmt.Fprintf(w, "Communities:\t%d\n", stats.CommunityCount)
+		fmt.Fprintf(w, "Degree:\tmax %d, mean %.2f\n", stats.MaxDegree, stats.MeanDegree)
+	})
+}
@@ -0,0 +1,146 @@
+// Command memctl manages the memory store from the shell, without an MCP
+// client. It reads the same config file ($CONFIG_FILE) and environment
+// variables as the server.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/storage"
+)
+
+// usages documents each command, in the order usage lists them
+var usages = []struct{ name, usage string }{
+	{"store", "store [-group id] [-tags a,b] [-source s] [-importance 0.5] [-detect queue|now|off] text... (- or no text reads stdin)"},
+	{"search", "search [-group id] [-tags a,b] [-limit 10] [-min-similarity 0] [-neighbors 20] query..."},
+	{"get", "get id..."},
+	{"list", "list [-group id] [-cluster id] [-sort created_at] [-asc] [-limit 20] [-cursor c]"},
+	{"link", "link [-reason text] [-confidence 0.9] from-id TYPE to-id"},
+	{"explore", "explore [-depth 2] [-direction both] [-types A,B] id"},
+	{"delete", "delete [-yes] id..."},
+	{"export", "export [-o memories.jsonl] [-group id] [-embeddings=false]"},
+	{"import", "import [memories.jsonl]"},
+	{"stats", "stats [-group id]"},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("memctl: ")
+
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := map[string]func(config.Config, []string) error{
+		"store":   runStore,
+		"search":  runSearch,
+		"get":     runGet,
+		"list":    runList,
+		"link":    runLink,
+		"explore": runExplore,
+		"delete":  runDelete,
+		"export":  runExport,
+		"import":  runImport,
+		"stats":   runStats,
+	}[os.Args[1]]
+	if !ok {
+		log.Printf("unknown command %q", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(os.Getenv(config.FileEnv))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := run(cfg, os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: memctl <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\nFlags come before arguments. Every command accepts -json for machine-readable output.\n\nCommands:")
+	for _, command := range usages {
+		fmt.Fprintf(os.Stderr, "  %s\n", command.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nConnection settings come from the same config file ($CONFIG_FILE) and environment variables as the server (POSTGRES_*, EMBEDDING_*, LLM_*).")
+}
+
+// newFlags creates the flag set for a command, with the shared -json flag
+func newFlags(name string) (*flag.FlagSet, *bool) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		for _, command := range usages {
+			if command.name == name {
+				fmt.Fprintf(os.Stderr, "Usage: memctl %s\n", command.usage)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "Print JSON instead of a table")
+	return flags, asJSON
+}
+
+func openStore(cfg config.Config) (*storage.PostgresStore, error) {
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return store, nil
+}
+
+// output prints value as indented JSON, or calls table with a tab-aligned writer
+func output(asJSON bool, value interface{}, table func(w io.Writer)) error {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// parseIDs parses memory IDs given as arguments
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid memory id %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cell flattens text to one line of at most width characters for a table
+func cell(text string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/detect"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/jobs"
+	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/storage"
+)
+
+// Relationship detection modes for store
+const (
+	detectQueue = "queue" // Enqueue a job for a running server's workers
+	detectNow   = "now"   // Ask the LLM before returning
+	detectOff   = "off"
+)
+
+// textWidth is the widest memory text shown in a table cell
+const textWidth = 80
+
+// storeResult is what store prints
+type storeResult struct {
+	ID     int64          `json:"id"`
+	JobID  int64          `json:"job_id,omitzero"`
+	Detect *detect.Result `json:"detect,omitzero"`
+}
+
+// runStore implements the store command. The text is the arguments joined
+// by spaces, or stdin when there are none or the only one is "-".
+func runStore(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("store")
+	groupID := flags.String("group", "", "Group for the memory")
+	tags := flags.String("tags", "", "Comma-separated tags")
+	source := flags.String("source", "", "Where the memory came from")
+	importance := flags.Float64("importance", storage.DefaultImportance, "Importance from 0 to 1")
+	detectMode := flags.String("detect", detectQueue, "Relationship detection: queue, now or off")
+	flags.Parse(args)
+
+	if *importance < 0 || *importance > 1 {
+		return fmt.Errorf("importance must be between 0 and 1, got %v", *importance)
+	}
+	if !slices.Contains([]string{detectQueue, detectNow, detectOff}, *detectMode) {
+		return fmt.Errorf("-detect must be queue, now or off, got %q", *detectMode)
+	}
+
+	text := strings.Join(flags.Args(), " ")
+	if flags.NArg() == 0 || text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		text = string(data)
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return fmt.Errorf("text cannot be empty")
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	embedding, err := embeddings.NewClient(cfg.EmbeddingConfig).Generate(text)
+	if err != nil {
+		return fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	id, err := store.StoreMemory(storage.Memory{
+		Text:       text,
+		Embedding:  embedding,
+		GroupID:    *groupID,
+		Tags:       splitList(*tags),
+		Source:     *source,
+		Importance: *importance,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store memory: %w", err)
+	}
+	result := storeResult{ID: id}
+
+	switch *detectMode {
+	case detectQueue:
+		if result.JobID, err = store.EnqueueJob(detect.JobKind, id, detect.Options{}, jobs.DefaultMaxAttempts); err != nil {
+			// The memory is already stored, so report it rather than fail
+			log.Printf("Queueing relationship detection failed: %v", err)
+		}
+	case detectNow:
+		relationshipTypes, err := cfg.LoadOntology()
+		if err != nil {
+			return fmt.Errorf("failed to load relationship ontology: %w", err)
+		}
+		detector := detect.NewDetector(store, llm.NewClient(cfg.LLMConfig), relationshipTypes)
+		detector.SetDefaults(cfg.Settings.Detection)
+		if result.Detect, err = detector.Detect(id, detect.Options{}); err != nil {
+			log.Printf("Relationship detection failed: %v", err)
+		}
+	}
+
+	return output(*asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Stored memory %d\n", id)
+		if result.JobID != 0 {
+			fmt.Fprintf(w, "Relationship detection queued as job %d\n", result.JobID)
+		}
+		if result.Detect != nil {
+			fmt.Fprintf(w, "Checked %d candidates, created %d relationships\n", result.Detect.Candidates, len(result.Detect.Created))
+			for _, rel := range result.Detect.Created {
+				fmt.Fprintf(w, "  %d -[%s]-> %d\n", rel.FromID, rel.Type, rel.ToID)
+			}
+		}
+	})
+}
+
+// runSearch implements the search command
+func runSearch(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("search")
+	groupID := flags.String("group", "", "Only search this group")
+	tags := flags.String("tags", "", "Comma-separated tags; results carry at least one")
+	limit := flags.Int("limit", 10, "Maximum vector results")
+	minSimilarity := flags.Float64("min-similarity", 0, "Minimum similarity from 0 to 1")
+	neighbors := flags.Int("neighbors", storage.DefaultMaxNeighbors, "Graph-discovered results to add (0 disables)")
+	flags.Parse(args)
+
+	query := strings.Join(flags.Args(), " ")
+	if query == "" {
+		return fmt.Errorf("usage: search [flags] query...")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("-limit must be positive")
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	embedding, err := embeddings.NewClient(cfg.EmbeddingConfig).Generate(query)
+	if err != nil {
+		return fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	// SearchMemories treats 0 as the default, so a negative cap disables expansion
+	maxNeighbors := *neighbors
+	if maxNeighbors == 0 {
+		maxNeighbors = -1
+	}
+
+	results, err := store.SearchMemories(embedding, storage.SearchOptions{
+		Limit:         *limit,
+		MinSimilarity: *minSimilarity,
+		GroupID:       *groupID,
+		Tags:          splitList(*tags),
+		MaxNeighbors:  maxNeighbors,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search memories: %w", err)
+	}
+
+	return output(*asJSON, results, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tSIMILARITY\tVIA\tGROUP\tTEXT")
+		for _, result := range results {
+			via := "vector"
+			if result.ViaRelationship {
+				via = fmt.Sprintf("%s from %d", result.RelationshipType, result.SeedID)
+			}
+			fmt.Fprintf(w, "%d\t%.3f\t%s\t%s\t%s\n",
+				result.Memory.ID, result.Similarity, via, result.Memory.GroupID, cell(result.Memory.Text, textWidth))
+		}
+	})
+}
+
+// memoryDetail is what get prints for each memory
+type memoryDetail struct {
+	Memory        storage.Memory         `json:"memory"`
+	Relationships []storage.Relationship `json:"relationships"`
+}
+
+// runGet implements the get command
+func runGet(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("get")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: get id...")
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	details := make([]memoryDetail, 0, len(ids))
+	for _, id := range ids {
+		memory, err := store.GetMemoryByID(id)
+		if err != nil {
+			return fmt.Errorf("memory %d: %w", id, err)
+		}
+		relationships, err := store.ListRelationships(storage.RelationshipFilter{MemoryID: id})
+		if err != nil {
+			return fmt.Errorf("failed to list relationships of memory %d: %w", id, err)
+		}
+		details = append(details, memoryDetail{Memory: *memory, Relationships: relationships})
+	}
+
+	return output(*asJSON, details, func(w io.Writer) {
+		for i, detail := range details {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			memory := detail.Memory
+			fmt.Fprintf(w, "ID:\t%d\n", memory.ID)
+			fmt.Fprintf(w, "Group:\t%s\n", memory.GroupID)
+			fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(memory.Tags, ", "))
+			fmt.Fprintf(w, "Source:\t%s\n", memory.Source)
+			fmt.Fprintf(w, "Importance:\t%.2f\n", memory.Importance)
+			fmt.Fprintf(w, "Created:\t%s\n", memory.CreatedAt.Format("2006-01-02 15:04:05"))
+			if !memory.ExpiresAt.IsZero() {
+				fmt.Fprintf(w, "Expires:\t%s\n", memory.ExpiresAt.Format("2006-01-02 15:04:05"))
+			}
+			for _, rel := range detail.Relationships {
+				fmt.Fprintf(w, "Edge %d:\t%d -[%s]-> %d\n", rel.ID, rel.FromID, rel.Type, rel.ToID)
+			}
+			fmt.Fprintf(w, "Text:\t%s\n", cell(memory.Text, 4*textWidth))
+		}
+	})
+}
+
+// listPage is what list prints
+type listPage struct {
+	Memories   []storage.Memory `json:"memories"`
+	NextCursor string           `json:"next_cursor,omitzero"`
+}
+
+// runList implements the list command. The cursor for the next page goes
+// to stderr so the table stays clean for pipes.
+func runList(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("list")
+	groupID := flags.String("group", "", "Only list this group")
+	clusterID := flags.Int64("cluster", 0, "Only list this graph community")
+	sortBy := flags.String("sort", "created_at", "Sort by "+strings.Join(storage.ListSortFields, ", "))
+	ascending := flags.Bool("asc", false, "Oldest or least important first")
+	limit := flags.Int("limit", 20, "Memories per page")
+	cursor := flags.String("cursor", "", "Cursor printed by the previous page")
+	flags.Parse(args)
+
+	if !slices.Contains(storage.ListSortFields, *sortBy) {
+		return fmt.Errorf("-sort must be one of %s, got %q", strings.Join(storage.ListSortFields, ", "), *sortBy)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("-limit must be positive")
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	memories, next, err := store.ListMemories(storage.ListOptions{
+		GroupID:   *groupID,
+		ClusterID: *clusterID,
+		SortBy:    *sortBy,
+		Ascending: *ascending,
+		Limit:     *limit,
+		Cursor:    *cursor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	if !*asJSON && next != "" {
+		log.Printf("Next page: memctl list -cursor %s", next)
+	}
+	return output(*asJSON, listPage{Memories: memories, NextCursor: next}, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tCREATED\tIMPORTANCE\tGROUP\tTEXT")
+		for _, memory := range memories {
+			fmt.Fprintf(w, "%d\t%s\t%.2f\t%s\t%s\n",
+				memory.ID, memory.CreatedAt.Format("2006-01-02 15:04"), memory.Importance, memory.GroupID, cell(memory.Text, textWidth))
+		}
+	})
+}
+
+// deleteResult is what delete prints
+type deleteResult struct {
+	Deleted []int64 `json:"deleted"`
+}
+
+// runDelete implements the delete command. It asks before deleting unless
+// -yes is given.
+func runDelete(cfg config.Config, args []string) error {
+	flags, asJSON := newFlags("delete")
+	yes := flags.Bool("yes", false, "Don't ask for confirmation")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: delete [-yes] id...")
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	memories, err := store.GetMemoriesByIDs(ids)
+	if err != nil {
+		return fmt.Errorf("failed to get memories: %w", err)
+	}
+	if len(memories) < len(ids) {
+		found := make(map[int64]bool, len(memories))
+		for _, memory := range memories {
+			found[memory.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return fmt.Errorf("memory %d: %w", id, storage.ErrMemoryNotFound)
+			}
+		}
+	}
+
+	if !*yes {
+		for _, memory := range memories {
+			fmt.Fprintf(os.Stderr, "%d\t%s\n", memory.ID, cell(memory.Text, textWidth))
+		}
+		fmt.Fprintf(os.Stderr, "Delete %d memories and their relationships? [y/N] ", len(memories))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	if err := store.DeleteMemories(ids); err != nil {
+		return fmt.Errorf("failed to delete memories: %w", err)
+	}
+
+	return output(*asJSON, deleteResult{Deleted: ids}, func(w io.Writer) {
+		fmt.Fprintf(w, "Deleted %d memories\n", len(ids))
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/detect"
+	"advanced-go-example/pkg/jobs"
+	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/storage"
+)
+
+// runBackfill implements the backfill subcommand:
+//
+//	server backfill [-group id] [-include-linked] [-concurrency 2] [-rate 1] [-dry-run] [-resume job-id]
+//
+// The run is recorded as a backfill_relationships job and checkpointed after
+// every batch. If it is interrupted, `-resume` continues it here, and a
+// running server's job workers pick it up too.
+func runBackfill(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	groupID := flags.String("group", "", "Only backfill memories in this group")
+	includeLinked := flags.Bool("include-linked", false, "Also analyze memories that already have auto-detected edges")
+	minSimilarity := flags.Float64("min-similarity", detect.DefaultMinSimilarity, "Minimum similarity for candidates")
+	maxCandidates := flags.Int("max-candidates", detect.DefaultMaxCandidates, "Maximum candidates per memory")
+	minConfidence := flags.Float64("min-confidence", detect.DefaultMinConfidence, "Minimum LLM confidence to create an edge")
+	dryRun := flags.Bool("dry-run", false, "Count the edges that would be created without creating them")
+	concurrency := flags.Int("concurrency", detect.DefaultBackfillConcurrency, "LLM calls in flight")
+	rate := flags.Float64("rate", detect.DefaultBackfillRateLimit, "LLM calls started per second, up to 50")
+	batchSize := flags.Int("batch", detect.DefaultBackfillBatchSize, "Memories per candidate query and checkpoint")
+	resume := flags.Int64("resume", 0, "Resume an interrupted or failed backfill job with its original options")
+	flags.Parse(args)
+
+	if *concurrency < 1 || *batchSize < 1 {
+		return fmt.Errorf("-concurrency and -batch must be positive")
+	}
+	if !(*rate > 0 && *rate <= detect.MaxBackfillRateLimit) {
+		return fmt.Errorf("-rate must be greater than 0 and at most %v, got %v", detect.MaxBackfillRateLimit, *rate)
+	}
+
+	relationshipTypes, err := cfg.LoadOntology()
+	if err != nil {
+		return fmt.Errorf("failed to load relationship ontology: %w", err)
+	}
+
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	// Hold the job under a lease like the server's workers do, so they leave it alone
+	owner, lease := jobs.Owner(), cfg.JobConfig.Lease
+	var job *storage.Job
+	if *resume != 0 {
+		if job, err = store.GetJob(*resume); err == nil {
+			if job.Kind != detect.BackfillJobKind {
+				return fmt.Errorf("job %d is a %s job, not a backfill", job.ID, job.Kind)
+			}
+			job, err = store.ClaimJobByID(job.ID, owner, lease)
+		}
+	} else {
+		job, err = store.StartJob(detect.BackfillJobKind, 0, detect.BackfillOptions{
+			Options: detect.Options{
+				MinSimilarity: *minSimilarity,
+				MaxCandidates: *maxCandidates,
+				MinConfidence: *minConfidence,
+				DryRun:        *dryRun,
+			},
+			GroupID:       *groupID,
+			IncludeLinked: *includeLinked,
+			Concurrency:   *concurrency,
+			RateLimit:     *rate,
+			BatchSize:     *batchSize,
+		}, jobs.DefaultMaxAttempts, owner, lease)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Backfill running as job %d", job.ID)
+
+	// Stop after the current batch on Ctrl-C; the checkpoint is kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	detector := detect.NewDetector(store, llm.NewClient(cfg.LLMConfig), relationshipTypes)
+	detector.SetDefaults(cfg.Settings.Detection)
+	jobCtx, release := jobs.HoldLease(ctx, store, *job, owner, lease)
+	result, err := detector.HandleBackfillJob(jobCtx, *job)
+	if !release() {
+		return fmt.Errorf("lost the lease on job %d, so a server's job worker continues it", job.ID)
+	}
+	if ctx.Err() != nil {
+		if err := store.ReleaseJob(job.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("interrupted; continue with `backfill -resume %d`", job.ID)
+	}
+	if err != nil {
+		if _, failErr := store.FailJob(job.ID, err, 0); failErr != nil {
+			log.Printf("Recording failure of job %d: %v", job.ID, failErr)
+		}
+		return fmt.Errorf("job %d: %w (continue with `backfill -resume %d`)", job.ID, err, job.ID)
+	}
+	if err := store.CompleteJob(job.ID, result); err != nil {
+		return err
+	}
+
+	progress := result.(detect.BackfillProgress)
+	log.Printf("Backfill scanned %d memories: %d analyzed, %d already linked, %d without candidates, %d failed",
+		progress.Scanned, progress.Analyzed, progress.AlreadyLinked, progress.NoCandidates, progress.Failed)
+	log.Printf("Created %d relationships %v", progress.Created, progress.CreatedByType)
+	for _, message := range progress.Errors {
+		log.Printf("  %s", message)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/exchange"
+	"advanced-go-example/pkg/graphexport"
+	"advanced-go-example/pkg/storage"
+)
+
+// runExport implements the export subcommand:
+//
+//	server export [-o memories.jsonl] [-group id] [-embeddings=false]
+func runExport(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "-", "Output file (- for stdout)")
+	groupID := flags.String("group", "", "Only export this group")
+	includeEmbeddings := flags.Bool("embeddings", true, "Include embedding vectors")
+	flags.Parse(args)
+
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	stats, err := exchange.Export(store, buffered, exchange.ExportOptions{
+		GroupID:           *groupID,
+		IncludeEmbeddings: *includeEmbeddings,
+		EmbeddingModel:    cfg.EmbeddingConfig.Model,
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	log.Printf("Exported %d memories and %d edges", stats.Memories, stats.Edges)
+	return nil
+}
+
+// runExportGraph implements the export-graph subcommand:
+//
+//	server export-graph [-o graph.dot] [-format dot|graphml|cytoscape] [-memory id [-depth n]] [-group id]
+//
+// The format defaults to the output file's extension, or DOT on stdout.
+func runExportGraph(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("export-graph", flag.ExitOnError)
+	output := flags.String("o", "-", "Output file (- for stdout)")
+	format := flags.String("format", "", "dot, graphml or cytoscape (default: from the -o extension, else dot)")
+	memoryID := flags.Int64("memory", 0, "Only export the memories around this one")
+	depth := flags.Int("depth", graphexport.DefaultDepth, "Hops around -memory")
+	groupID := flags.String("group", "", "Only export this group")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = graphexport.FormatDOT
+		if *output != "-" {
+			if *format = graphexport.FormatForPath(*output); *format == "" {
+				return fmt.Errorf("cannot tell the format from %s; use -format", *output)
+			}
+		}
+	}
+	if !slices.Contains(graphexport.Formats, *format) {
+		return fmt.Errorf("-format must be one of %s, got %q", strings.Join(graphexport.Formats, ", "), *format)
+	}
+
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	graph, err := graphexport.Load(store, graphexport.Options{MemoryID: *memoryID, Depth: *depth, GroupID: *groupID})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := graphexport.Write(w, graph, *format); err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d memories and %d edges as %s", len(graph.Nodes), len(graph.Edges), *format)
+	return nil
+}
+
+// runImport implements the import subcommand:
+//
+//	server import [memories.jsonl]
+//
+// The export is read from stdin when no file is given.
+func runImport(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	stats, err := exchange.Import(store, embeddings.NewClient(cfg.EmbeddingConfig), r)
+	log.Printf("Imported %d new and %d updated memories (%d re-embedded), %d edges (%d skipped)",
+		stats.Created, stats.Updated, stats.Reembedded, stats.Edges, stats.SkippedEdges)
+	return err
+}
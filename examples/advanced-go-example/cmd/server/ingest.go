@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/ingest"
+	"advanced-go-example/pkg/storage"
+)
+
+// runIngest implements the ingest subcommand:
+//
+//	server ingest [-group id] [-tags a,b] [-format auto] [-chunk-size 1000] [-overlap 150] path...
+func runIngest(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	groupID := flags.String("group", "", "Group for the chunks")
+	tags := flags.String("tags", "", "Comma-separated tags for every chunk")
+	importance := flags.Float64("importance", storage.DefaultImportance, "Importance from 0 to 1")
+	format := flags.String("format", ingest.FormatAuto, "auto, markdown or text")
+	chunkSize := flags.Int("chunk-size", ingest.DefaultChunkSize, "Maximum chunk size in bytes")
+	overlap := flags.Int("overlap", ingest.DefaultChunkOverlap, "Bytes shared by consecutive chunks (-1 disables)")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: ingest [flags] path...")
+	}
+	if *importance < 0 || *importance > 1 {
+		return fmt.Errorf("importance must be between 0 and 1, got %v", *importance)
+	}
+
+	var tagList []string
+	if *tags != "" {
+		tagList = strings.Split(*tags, ",")
+	}
+
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	ingester := ingest.NewIngester(store, embeddings.NewClient(cfg.EmbeddingConfig))
+	opts := ingest.Options{
+		GroupID:    *groupID,
+		Tags:       tagList,
+		Importance: *importance,
+		Format:     *format,
+		Chunking:   ingest.ChunkOptions{Size: *chunkSize, Overlap: *overlap},
+	}
+
+	for _, path := range flags.Args() {
+		results, err := ingester.IngestPath(path, opts)
+		for _, result := range results {
+			log.Printf("Ingested %s: %d chunks (%d stale removed)", result.Path, result.Chunks, result.Pruned)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/detect"
 	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/jobs"
 	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/metrics"
+	"advanced-go-example/pkg/prompts"
+	"advanced-go-example/pkg/resources"
 	"advanced-go-example/pkg/storage"
 	"advanced-go-example/pkg/tools"
+	"advanced-go-example/pkg/tracing"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -20,31 +31,106 @@ const (
 	ServerVersion = "1.0.0"
 )
 
+// jobShutdownTimeout bounds how long shutdown waits for in-flight jobs; jobs
+// still running after it are requeued on the next start
+const jobShutdownTimeout = 10 * time.Second
+
 func main() {
-	// Load configuration from environment
-	config := loadConfig()
+	// Subcommands run once and exit instead of serving MCP
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		// Load configuration from $CONFIG_FILE and the environment
+		cfg, err := config.Load(os.Getenv(config.FileEnv))
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch os.Args[1] {
+		case "export":
+			err = runExport(cfg, os.Args[2:])
+		case "export-graph":
+			err = runExportGraph(cfg, os.Args[2:])
+		case "import":
+			err = runImport(cfg, os.Args[2:])
+		case "ingest":
+			err = runIngest(cfg, os.Args[2:])
+		case "backfill":
+			err = runBackfill(cfg, os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q (expected export, export-graph, import, ingest or backfill)", os.Args[1])
+		}
+		if err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	var flags serverFlags
+	flag.StringVar(&flags.configFile, "config", os.Getenv(config.FileEnv), "YAML config file; environment variables and flags override it (default: $CONFIG_FILE)")
+	flag.StringVar(&flags.uiAddr, "ui", "", "Serve the web UI on this address, e.g. localhost:8080 (default: ui.addr or $UI_ADDR; off when empty)")
+	flag.StringVar(&flags.metricsAddr, "metrics", "", "Serve Prometheus metrics and health checks on this address, e.g. :9090 (default: metrics.addr or $METRICS_ADDR; off when empty)")
+	flag.StringVar(&flags.logLevel, "log-level", "", "Log level: debug, info, warn or error (default: log_level or $LOG_LEVEL, else info)")
+	flag.Parse()
+
+	// Load configuration from the file, the environment and the flags
+	cfg, err := flags.load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Settings that SIGHUP may change later: log level, search defaults and detection thresholds
+	settings := config.NewLive(cfg.Settings)
+	var logLevel slog.LevelVar
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel})))
+	settings.OnChange(func(s config.Settings) {
+		logLevel.Set(s.LogLevel)
+	})
+
+	// Export spans for tool calls, embeddings, LLM and storage when configured
+	cfg.TracingConfig.ServiceName = ServerName
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing()
 
 	// Initialize storage layer (Postgres + pgvector + Apache AGE)
-	store, err := storage.NewPostgresStore(config.PostgresConfig)
+	store, err := storage.NewPostgresStore(cfg.PostgresConfig)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 	defer store.Close()
 
 	// Initialize embedding client (LM Studio)
-	embeddingClient := embeddings.NewClient(config.EmbeddingConfig)
+	embeddingClient := embeddings.NewClient(cfg.EmbeddingConfig)
+
+	// Relationship types drive the detection prompt and edge validation
+	relationshipTypes, err := cfg.LoadOntology()
+	if err != nil {
+		log.Fatalf("Failed to load relationship ontology: %v", err)
+	}
 
 	// Initialize LLM client for relationship detection
-	llmClient := llm.NewClient(config.LLMConfig)
+	llmClient := llm.NewClient(cfg.LLMConfig)
 
 	// Create MCP server
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    ServerName,
 		Version: ServerVersion,
-	}, nil)
+	}, &mcp.ServerOptions{
+		SubscribeHandler:   resources.Subscribe,
+		UnsubscribeHandler: resources.Unsubscribe,
+	})
+
+	// Trace every tool call, and count calls and their latency for /metrics
+	server.AddReceivingMiddleware(tracing.ToolMiddleware, metrics.ToolMiddleware, logToolCalls)
 
 	// Register memory tools
-	tools.RegisterMemoryTools(server, store, embeddingClient, llmClient)
+	tools.RegisterMemoryTools(server, store, embeddingClient, llmClient, relationshipTypes, settings)
+
+	// Expose memories as browsable resources
+	resources.Register(server, store)
+
+	// Prompt templates that embed retrieved memories
+	prompts.Register(server, store, embeddingClient)
 
 	// Handle graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -59,51 +145,105 @@ func main() {
 		cancel()
 	}()
 
+	// Reload the safe settings on SIGHUP
+	go watchReload(ctx, flags, cfg, settings)
+
+	// Enforce TTLs and retention policies in the background
+	if cfg.RetentionInterval > 0 {
+		go runRetentionSweeper(ctx, store, cfg.RetentionInterval)
+	}
+
+	// Optional web dashboard for browsing and editing memories
+	if cfg.UIAddr != "" {
+		if err := startUI(ctx, cfg.UIAddr, cfg.HTTP, store, embeddingClient); err != nil {
+			log.Fatalf("Failed to start web UI: %v", err)
+		}
+	}
+
+	// Optional Prometheus metrics and health endpoints
+	if cfg.MetricsAddr != "" {
+		if err := startMetrics(ctx, cfg.MetricsAddr, cfg.HTTP, store, embeddingClient); err != nil {
+			log.Fatalf("Failed to start metrics listener: %v", err)
+		}
+	}
+
+	// Work queued jobs such as relationship detection in the background
+	jobsDone := make(chan struct{})
+	if cfg.JobConfig.Workers > 0 {
+		runner := jobs.NewRunner(store, cfg.JobConfig)
+		detector := detect.NewDetector(store, llmClient, relationshipTypes)
+		settings.OnChange(func(s config.Settings) {
+			detector.SetDefaults(s.Detection)
+		})
+		runner.Handle(detect.JobKind, detector.HandleJob)
+		runner.Handle(detect.BackfillJobKind, detector.HandleBackfillJob)
+		go func() {
+			defer close(jobsDone)
+			if err := runner.Run(ctx); err != nil {
+				log.Printf("Job runner failed: %v", err)
+			}
+		}()
+	} else {
+		close(jobsDone)
+		log.Println("Job workers disabled; queued jobs wait until a server with JOB_WORKERS > 0 runs them")
+	}
+
 	// Run server with stdio transport
 	log.Printf("Starting %s v%s", ServerName, ServerVersion)
 	log.Printf("Storage: PostgreSQL with pgvector + Apache AGE")
-	log.Printf("Embeddings: %s", config.EmbeddingConfig.BaseURL)
+	log.Printf("Embeddings: %s", cfg.EmbeddingConfig.BaseURL)
 
 	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
-}
 
-// Config holds all application configuration
-type Config struct {
-	PostgresConfig  storage.PostgresConfig
-	EmbeddingConfig embeddings.Config
-	LLMConfig       llm.Config
-	Debug           bool
+	// Let in-flight jobs finish before the store is closed
+	cancel()
+	select {
+	case <-jobsDone:
+	case <-time.After(jobShutdownTimeout):
+		log.Printf("Jobs still running after %s; they will be retried on the next start", jobShutdownTimeout)
+	}
 }
 
-// loadConfig loads configuration from environment variables
-func loadConfig() Config {
-	return Config{
-		PostgresConfig: storage.PostgresConfig{
-			Host:     getEnv("POSTGRES_HOST", "localhost"),
-			Port:     getEnv("POSTGRES_PORT", "5432"),
-			User:     getEnv("POSTGRES_USER", "memoryuser"),
-			Password: getEnv("POSTGRES_PASSWORD", "memorypass"),
-			Database: getEnv("POSTGRES_DB", "memorydb"),
-		},
-		EmbeddingConfig: embeddings.Config{
-			BaseURL: getEnv("EMBEDDING_BASE_URL", "http://localhost:1234/v1"),
-			Model:   getEnv("EMBEDDING_MODEL", "text-embedding-embeddinggemma-300m-qat"),
-			APIKey:  getEnv("EMBEDDING_API_KEY", "not-needed"),
-		},
-		LLMConfig: llm.Config{
-			BaseURL: getEnv("LLM_BASE_URL", "http://localhost:1234/v1"),
-			Model:   getEnv("LLM_MODEL", "qwen/qwen3-4b-2507"),
-			APIKey:  getEnv("LLM_API_KEY", "not-needed"),
-		},
-		Debug: getEnv("DEBUG", "false") == "true",
+// runRetentionSweeper purges expired memories and enforces retention policies
+// every interval until ctx is cancelled
+func runRetentionSweeper(ctx context.Context, store *storage.PostgresStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		run, err := store.RunRetention(false)
+		if err != nil {
+			log.Printf("Retention sweep failed: %v", err)
+		} else if run.Purged > 0 {
+			log.Printf("Retention sweep purged %d memories %v (audit %d)", run.Purged, run.Reasons, run.AuditID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// logToolCalls logs every tool call at debug level, so LOG_LEVEL=debug (or a
+// reload to it) shows what clients are doing
+func logToolCalls(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Params == nil || !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		failed := err != nil
+		if toolResult, ok := result.(*mcp.CallToolResult); ok && toolResult.IsError {
+			failed = true
+		}
+		slog.DebugContext(ctx, "tool call", "tool", call.Params.Name, "duration", time.Since(start), "failed", failed)
+		return result, err
 	}
-	return defaultValue
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/metrics"
+	"advanced-go-example/pkg/storage"
+)
+
+// startMetrics serves /metrics, /healthz and /readyz on addr until ctx is
+// cancelled. Like startUI, it listens before returning.
+func startMetrics(ctx context.Context, addr string, timeouts config.HTTPConfig, store *storage.PostgresStore, embeddingClient *embeddings.Client) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	metrics.RegisterDBStats(store.Stats)
+	handler := metrics.NewHandler([]metrics.Check{
+		{Name: "postgres", Run: store.Ping},
+		{Name: "graph", Run: store.CheckGraph},
+		{Name: "embeddings", Run: embeddingClient.Ping},
+	})
+
+	serveHTTP(ctx, listener, handler, timeouts, "Metrics listener")
+
+	log.Printf("Metrics: http://%s/metrics (health: /healthz, /readyz)", listener.Addr())
+	return nil
+}
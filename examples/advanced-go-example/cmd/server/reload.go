@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"advanced-go-example/pkg/config"
+)
+
+// serverFlags are the command-line settings, which win over the config
+// file and the environment, also on reload
+type serverFlags struct {
+	configFile  string
+	uiAddr      string
+	metricsAddr string
+	logLevel    string
+}
+
+// load loads the configuration and applies the flags set on the command line
+func (f serverFlags) load() (config.Config, error) {
+	cfg, err := config.Load(f.configFile)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	var flagErr error
+	flag.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "ui":
+			cfg.UIAddr = f.uiAddr
+		case "metrics":
+			cfg.MetricsAddr = f.metricsAddr
+		case "log-level":
+			if err := cfg.Settings.LogLevel.UnmarshalText([]byte(f.logLevel)); err != nil {
+				flagErr = fmt.Errorf("invalid -log-level: %w", err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return config.Config{}, flagErr
+	}
+	return cfg, cfg.Validate()
+}
+
+// watchReload reloads the configuration on SIGHUP until ctx is cancelled.
+// Only Settings take effect; a reload that fails to load or validate keeps
+// the running settings.
+func watchReload(ctx context.Context, flags serverFlags, running config.Config, settings *config.Live) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		next, err := flags.load()
+		if err != nil {
+			log.Printf("Reload failed, keeping the current settings: %v", err)
+			continue
+		}
+		if changed := restartChanges(running, next); len(changed) > 0 {
+			log.Printf("Changes to %s need a restart and were not applied", strings.Join(changed, ", "))
+		}
+		settings.Set(next.Settings)
+		log.Printf("Reloaded settings (log level %s, search limit %d)", next.Settings.LogLevel, next.Settings.Search.Limit)
+	}
+}
+
+// restartChanges names the sections outside Settings that differ between
+// the running and the reloaded configuration
+func restartChanges(running, next config.Config) []string {
+	// Fields filled in at startup rather than loaded
+	next.LLMConfig.Ontology = running.LLMConfig.Ontology
+	next.TracingConfig.ServiceName = running.TracingConfig.ServiceName
+
+	var changed []string
+	a, b := reflect.ValueOf(running), reflect.ValueOf(next)
+	for i := 0; i < a.NumField(); i++ {
+		name := a.Type().Field(i).Name
+		if name != "Settings" && !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
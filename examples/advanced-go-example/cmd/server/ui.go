@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/storage"
+	"advanced-go-example/pkg/webui"
+)
+
+// startUI serves the web UI on addr until ctx is cancelled. It listens
+// before returning, so a bad or busy address fails startup.
+func startUI(ctx context.Context, addr string, timeouts config.HTTPConfig, store *storage.PostgresStore, embeddingClient *embeddings.Client) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	// The UI can edit and delete memories and has no login
+	if host, _, _ := net.SplitHostPort(addr); host == "" || !isLoopback(host) {
+		log.Printf("Web UI is reachable from other machines on %s; use localhost:%d to keep it local", addr, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	serveHTTP(ctx, listener, webui.NewHandler(store, embeddingClient), timeouts, "Web UI")
+
+	log.Printf("Web UI: http://%s", listener.Addr())
+	return nil
+}
+
+// serveHTTP serves handler on listener with the configured timeouts until
+// ctx is cancelled, then waits up to the shutdown timeout for open requests
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler, timeouts config.HTTPConfig, name string) {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		ReadTimeout:       timeouts.ReadTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeouts.ShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("%s failed: %v", name, err)
+		}
+	}()
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
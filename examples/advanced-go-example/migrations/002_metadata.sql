@@ -0,0 +1,11 @@
+-- Rich metadata for memories: tags, source, importance and free-form attributes
+ALTER TABLE public.memories
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS source TEXT,
+    ADD COLUMN IF NOT EXISTS importance REAL NOT NULL DEFAULT 0.5,  -- 0 (trivial) to 1 (critical)
+    ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- GIN indexes let tag (&&, @>) and attribute (@>) filters use an index
+CREATE INDEX IF NOT EXISTS idx_memories_tags ON public.memories USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_memories_attributes ON public.memories USING GIN (attributes jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_memories_importance ON public.memories(importance DESC);
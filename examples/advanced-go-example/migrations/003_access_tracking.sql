@@ -0,0 +1,20 @@
+-- Access tracking: maintained asynchronously whenever a memory is returned by a read
+ALTER TABLE public.memories
+    ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS access_count BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_memories_access_count ON public.memories(access_count DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_last_accessed_at ON public.memories(last_accessed_at);
+
+-- Reading a memory is not an edit: don't bump updated_at for access bookkeeping
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.access_count IS DISTINCT FROM OLD.access_count
+       OR NEW.last_accessed_at IS DISTINCT FROM OLD.last_accessed_at THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
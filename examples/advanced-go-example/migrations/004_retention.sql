@@ -0,0 +1,31 @@
+-- Per-memory expiry: NULL means the memory never expires on its own
+ALTER TABLE public.memories
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_memories_expires_at ON public.memories(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Per-group retention rules enforced by the server's background sweeper.
+-- group_id '' covers memories without a group; '*' is the default for groups
+-- without a policy of their own. A NULL rule is not enforced.
+CREATE TABLE IF NOT EXISTS public.retention_policies (
+    group_id TEXT PRIMARY KEY,
+    max_age INTERVAL,           -- Purge memories older than this
+    max_count INTEGER,          -- Keep only the newest N memories
+    min_importance REAL,        -- Purge memories less important than this
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per sweeper run (or dry run), recording what was purged and why
+CREATE TABLE IF NOT EXISTS public.retention_audit (
+    id BIGSERIAL PRIMARY KEY,
+    run_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    dry_run BOOLEAN NOT NULL,
+    purged_count INTEGER NOT NULL,
+    memory_ids BIGINT[] NOT NULL DEFAULT '{}',
+    reasons JSONB NOT NULL DEFAULT '{}'   -- Purge count per reason
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_audit_run_at ON public.retention_audit(run_at DESC);
+
+GRANT ALL PRIVILEGES ON public.retention_policies, public.retention_audit TO memoryuser;
+GRANT ALL PRIVILEGES ON SEQUENCE public.retention_audit_id_seq TO memoryuser;
@@ -0,0 +1,12 @@
+-- Stable external IDs identify memories across exports and imports, so
+-- re-importing the same file updates memories instead of duplicating them
+ALTER TABLE public.memories
+    ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+UPDATE public.memories SET external_id = gen_random_uuid()::text WHERE external_id IS NULL;
+
+ALTER TABLE public.memories
+    ALTER COLUMN external_id SET DEFAULT gen_random_uuid()::text,
+    ALTER COLUMN external_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memories_external_id ON public.memories(external_id);
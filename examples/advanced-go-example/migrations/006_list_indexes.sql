@@ -0,0 +1,13 @@
+-- Keyset pagination for list_memories orders by (column, id), so the sort
+-- columns must never be NULL and need matching indexes
+UPDATE public.memories SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+UPDATE public.memories SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE public.memories
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_memories_created_at_id ON public.memories(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_memories_updated_at_id ON public.memories(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_memories_importance_id ON public.memories(importance, id);
+CREATE INDEX IF NOT EXISTS idx_memories_group_created_at ON public.memories(group_id, created_at, id);
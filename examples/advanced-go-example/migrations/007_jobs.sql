@@ -0,0 +1,26 @@
+-- Background job queue worked by the server's job runner. Workers claim
+-- pending jobs with FOR UPDATE SKIP LOCKED, so several workers (or servers)
+-- can share the table without handing out the same job twice.
+CREATE TABLE IF NOT EXISTS public.jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,                       -- Selects the handler, e.g. detect_relationships
+    memory_id BIGINT,                         -- Memory the job is about, if any
+    payload JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    last_error TEXT,
+    result JSONB,
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- Retry backoff
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON public.jobs(run_after, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_memory_id ON public.jobs(memory_id, id DESC) WHERE memory_id IS NOT NULL;
+
+GRANT ALL PRIVILEGES ON public.jobs TO memoryuser;
+GRANT ALL PRIVILEGES ON SEQUENCE public.jobs_id_seq TO memoryuser;
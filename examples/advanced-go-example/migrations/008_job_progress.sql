@@ -0,0 +1,4 @@
+-- Checkpoint of a long-running job (e.g. a relationship backfill), saved as
+-- it goes so an interrupted or retried job resumes instead of starting over
+ALTER TABLE public.jobs
+    ADD COLUMN IF NOT EXISTS progress JSONB;
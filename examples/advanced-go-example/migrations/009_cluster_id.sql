@@ -0,0 +1,21 @@
+-- Community saved by graph_stats: the smallest memory ID in the memory's
+-- community, so IDs from separate runs and groups never collide. NULL for
+-- memories without edges or not yet analyzed.
+ALTER TABLE public.memories
+    ADD COLUMN IF NOT EXISTS cluster_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_memories_cluster_id ON public.memories(cluster_id, id) WHERE cluster_id IS NOT NULL;
+
+-- Saving communities is analysis, not an edit: don't bump updated_at either
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.access_count IS DISTINCT FROM OLD.access_count
+       OR NEW.last_accessed_at IS DISTINCT FROM OLD.last_accessed_at
+       OR NEW.cluster_id IS DISTINCT FROM OLD.cluster_id THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
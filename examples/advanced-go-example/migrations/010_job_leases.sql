@@ -0,0 +1,8 @@
+-- Lease on a running job. The claiming worker (locked_by) renews
+-- locked_until while it works; only jobs whose lease has run out are taken
+-- back, so a starting server leaves jobs that other servers are running alone.
+ALTER TABLE public.jobs
+    ADD COLUMN IF NOT EXISTS locked_by TEXT,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON public.jobs(locked_until) WHERE status = 'running';
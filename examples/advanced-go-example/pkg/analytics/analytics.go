@@ -0,0 +1,204 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Edge is an edge of the graph being analyzed. Weight scales its influence
+// on PageRank and community detection; use 1 when edges are unweighted.
+type Edge struct {
+	From   int64
+	To     int64
+	Weight float64
+}
+
+// Graph is an undirected, weighted view of the memory graph. Edge direction
+// is ignored: inverse relationship types are stored in one canonical
+// direction, so direction says little about importance.
+type Graph struct {
+	nodes     []int64       // Node IDs in ascending order
+	index     map[int64]int // Node ID -> position in nodes
+	adjacency [][]neighbor
+}
+
+type neighbor struct {
+	node   int
+	weight float64
+}
+
+// DefaultDamping is the PageRank damping factor
+const DefaultDamping = 0.85
+
+// pageRankTolerance stops PageRank once an iteration changes the ranks by less than this in total
+const pageRankTolerance = 1e-9
+
+// maxIterations bounds PageRank and label propagation
+const maxIterations = 100
+
+// NewGraph builds a graph over nodes. Edges with an end outside nodes, and
+// self-loops, are ignored; parallel edges add up.
+func NewGraph(nodes []int64, edges []Edge) *Graph {
+	sorted := append([]int64(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	g := &Graph{
+		nodes:     sorted,
+		index:     make(map[int64]int, len(sorted)),
+		adjacency: make([][]neighbor, len(sorted)),
+	}
+	for i, id := range sorted {
+		g.index[id] = i
+	}
+
+	for _, edge := range edges {
+		from, ok := g.index[edge.From]
+		if !ok {
+			continue
+		}
+		to, ok := g.index[edge.To]
+		if !ok || from == to {
+			continue
+		}
+		g.adjacency[from] = append(g.adjacency[from], neighbor{to, edge.Weight})
+		g.adjacency[to] = append(g.adjacency[to], neighbor{from, edge.Weight})
+	}
+	return g
+}
+
+// Nodes returns the node IDs in ascending order
+func (g *Graph) Nodes() []int64 {
+	return g.nodes
+}
+
+// Degrees returns the number of edges at each node
+func (g *Graph) Degrees() map[int64]int {
+	degrees := make(map[int64]int, len(g.nodes))
+	for i, id := range g.nodes {
+		degrees[id] = len(g.adjacency[i])
+	}
+	return degrees
+}
+
+// PageRank computes weighted PageRank by power iteration. Ranks sum to 1;
+// the rank of nodes without edges is spread over the whole graph.
+func (g *Graph) PageRank(damping float64) map[int64]float64 {
+	n := len(g.nodes)
+	ranks := make(map[int64]float64, n)
+	if n == 0 {
+		return ranks
+	}
+
+	strength := make([]float64, n)
+	for i, neighbors := range g.adjacency {
+		for _, nb := range neighbors {
+			strength[i] += nb.weight
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+
+	for range maxIterations {
+		dangling := 0.0
+		for i := range rank {
+			if strength[i] == 0 {
+				dangling += rank[i]
+			}
+		}
+
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, neighbors := range g.adjacency {
+			if strength[i] == 0 {
+				continue
+			}
+			for _, nb := range neighbors {
+				next[nb.node] += damping * rank[i] * nb.weight / strength[i]
+			}
+		}
+
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	for i, id := range g.nodes {
+		ranks[id] = rank[i]
+	}
+	return ranks
+}
+
+// Communities detects communities by weighted label propagation: every node
+// starts in its own community and repeatedly joins the one its neighbours
+// weigh most towards. Nodes are visited in ID order and ties go to the
+// current label, then the smallest, so the result is deterministic. Each
+// community is labelled with its smallest node ID; nodes without edges are
+// left out.
+func (g *Graph) Communities() map[int64]int64 {
+	labels := make([]int, len(g.nodes))
+	for i := range labels {
+		labels[i] = i
+	}
+
+	weights := make(map[int]float64)
+	for range maxIterations {
+		changed := false
+		for i, neighbors := range g.adjacency {
+			if len(neighbors) == 0 {
+				continue
+			}
+
+			clear(weights)
+			for _, nb := range neighbors {
+				weights[labels[nb.node]] += nb.weight
+			}
+
+			maxWeight := 0.0
+			for _, weight := range weights {
+				maxWeight = max(maxWeight, weight)
+			}
+			if weights[labels[i]] == maxWeight {
+				continue
+			}
+			best := -1
+			for label, weight := range weights {
+				if weight == maxWeight && (best < 0 || label < best) {
+					best = label
+				}
+			}
+			if best != labels[i] {
+				labels[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Label each community by its smallest member; nodes are sorted, so that
+	// is the first member seen
+	smallest := make(map[int]int64)
+	communities := make(map[int64]int64)
+	for i, id := range g.nodes {
+		if len(g.adjacency[i]) == 0 {
+			continue
+		}
+		if _, ok := smallest[labels[i]]; !ok {
+			smallest[labels[i]] = id
+		}
+		communities[id] = smallest[labels[i]]
+	}
+	return communities
+}
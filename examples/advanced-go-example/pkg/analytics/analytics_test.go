@@ -0,0 +1,190 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// unweighted builds edges of weight 1 from pairs of node IDs
+func unweighted(pairs ...[2]int64) []Edge {
+	edges := make([]Edge, len(pairs))
+	for i, pair := range pairs {
+		edges[i] = Edge{From: pair[0], To: pair[1], Weight: 1}
+	}
+	return edges
+}
+
+func TestNewGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []int64
+		edges   []Edge
+		nodeIDs []int64
+		degrees map[int64]int
+	}{
+		{name: "empty graph", degrees: map[int64]int{}},
+		{
+			name:    "nodes are sorted",
+			nodes:   []int64{30, 10, 20},
+			edges:   unweighted([2]int64{10, 20}),
+			nodeIDs: []int64{10, 20, 30},
+			degrees: map[int64]int{10: 1, 20: 1, 30: 0},
+		},
+		{
+			name:    "self-loops and unknown ends are ignored",
+			nodes:   []int64{1, 2},
+			edges:   unweighted([2]int64{1, 1}, [2]int64{1, 3}, [2]int64{4, 2}, [2]int64{2, 1}),
+			nodeIDs: []int64{1, 2},
+			degrees: map[int64]int{1: 1, 2: 1},
+		},
+		{
+			name:    "parallel edges both count",
+			nodes:   []int64{1, 2},
+			edges:   unweighted([2]int64{1, 2}, [2]int64{2, 1}),
+			nodeIDs: []int64{1, 2},
+			degrees: map[int64]int{1: 2, 2: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGraph(tt.nodes, tt.edges)
+			if got := g.Nodes(); !reflect.DeepEqual(got, tt.nodeIDs) {
+				t.Errorf("Nodes() = %v, want %v", got, tt.nodeIDs)
+			}
+			if got := g.Degrees(); !reflect.DeepEqual(got, tt.degrees) {
+				t.Errorf("Degrees() = %v, want %v", got, tt.degrees)
+			}
+		})
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []int64
+		edges []Edge
+		want  map[int64]float64 // Exact ranks, when known
+		order []int64           // Nodes in strictly decreasing rank
+		equal [][]int64         // Groups of nodes with equal rank
+	}{
+		{name: "empty graph", want: map[int64]float64{}},
+		{name: "single node", nodes: []int64{7}, want: map[int64]float64{7: 1}},
+		{
+			name:  "no edges",
+			nodes: []int64{1, 2, 3, 4},
+			want:  map[int64]float64{1: 0.25, 2: 0.25, 3: 0.25, 4: 0.25},
+		},
+		{
+			name:  "pair",
+			nodes: []int64{1, 2},
+			edges: unweighted([2]int64{1, 2}),
+			want:  map[int64]float64{1: 0.5, 2: 0.5},
+		},
+		{
+			name:  "star",
+			nodes: []int64{1, 2, 3, 4},
+			edges: unweighted([2]int64{1, 2}, [2]int64{1, 3}, [2]int64{1, 4}),
+			order: []int64{1, 2},
+			equal: [][]int64{{2, 3, 4}},
+		},
+		{
+			name:  "direction is ignored",
+			nodes: []int64{1, 2, 3},
+			edges: unweighted([2]int64{1, 2}, [2]int64{3, 2}),
+			order: []int64{2, 1},
+			equal: [][]int64{{1, 3}},
+		},
+		{
+			name:  "weights shift rank",
+			nodes: []int64{1, 2, 3},
+			edges: []Edge{{From: 1, To: 2, Weight: 3}, {From: 1, To: 3, Weight: 1}},
+			order: []int64{1, 2, 3},
+		},
+		{
+			name:  "disconnected graph",
+			nodes: []int64{1, 2, 3, 4, 5, 6},
+			edges: unweighted([2]int64{1, 2}, [2]int64{3, 4}, [2]int64{4, 5}, [2]int64{5, 3}),
+			order: []int64{3, 6},
+			equal: [][]int64{{1, 2}, {3, 4, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranks := NewGraph(tt.nodes, tt.edges).PageRank(DefaultDamping)
+			if len(ranks) != len(tt.nodes) {
+				t.Fatalf("got %d ranks for %d nodes", len(ranks), len(tt.nodes))
+			}
+
+			total := 0.0
+			for _, rank := range ranks {
+				total += rank
+			}
+			if len(ranks) > 0 && !approxEqual(total, 1) {
+				t.Errorf("ranks sum to %v, want 1", total)
+			}
+			for id, want := range tt.want {
+				if !approxEqual(ranks[id], want) {
+					t.Errorf("rank of %d = %v, want %v", id, ranks[id], want)
+				}
+			}
+			for i := 1; i < len(tt.order); i++ {
+				if higher, lower := tt.order[i-1], tt.order[i]; ranks[higher] <= ranks[lower] {
+					t.Errorf("rank of %d (%v) should exceed rank of %d (%v)", higher, ranks[higher], lower, ranks[lower])
+				}
+			}
+			for _, group := range tt.equal {
+				for _, id := range group[1:] {
+					if !approxEqual(ranks[id], ranks[group[0]]) {
+						t.Errorf("rank of %d (%v) should equal rank of %d (%v)", id, ranks[id], group[0], ranks[group[0]])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCommunities(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []int64
+		edges []Edge
+		want  map[int64]int64
+	}{
+		{name: "empty graph", want: map[int64]int64{}},
+		{name: "nodes without edges are left out", nodes: []int64{1, 2, 3}, want: map[int64]int64{}},
+		{
+			name:  "pair",
+			nodes: []int64{5, 9},
+			edges: unweighted([2]int64{9, 5}),
+			want:  map[int64]int64{5: 5, 9: 5},
+		},
+		{
+			name:  "disconnected graph",
+			nodes: []int64{1, 2, 3, 4, 5, 6, 7},
+			edges: unweighted([2]int64{1, 2}, [2]int64{2, 3}, [2]int64{3, 1}, [2]int64{4, 5}, [2]int64{5, 6}, [2]int64{6, 4}),
+			want:  map[int64]int64{1: 1, 2: 1, 3: 1, 4: 4, 5: 4, 6: 4},
+		},
+		{
+			name:  "cliques joined by a weak bridge",
+			nodes: []int64{1, 2, 3, 4, 5, 6, 7, 8},
+			edges: append(unweighted(
+				[2]int64{1, 2}, [2]int64{1, 3}, [2]int64{1, 4}, [2]int64{2, 3}, [2]int64{2, 4}, [2]int64{3, 4},
+				[2]int64{5, 6}, [2]int64{5, 7}, [2]int64{5, 8}, [2]int64{6, 7}, [2]int64{6, 8}, [2]int64{7, 8},
+			), Edge{From: 4, To: 5, Weight: 0.5}),
+			want: map[int64]int64{1: 1, 2: 1, 3: 1, 4: 1, 5: 5, 6: 5, 7: 5, 8: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewGraph(tt.nodes, tt.edges).Communities()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Communities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,268 @@
+// Package config loads the configuration shared by the server and memctl.
+// Settings come from, in increasing precedence: built-in defaults, an
+// optional YAML file, environment variables and (for the server) flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"advanced-go-example/pkg/detect"
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/jobs"
+	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/ontology"
+	"advanced-go-example/pkg/storage"
+	"advanced-go-example/pkg/tracing"
+)
+
+// FileEnv names the environment variable that points at the config file
+const FileEnv = "CONFIG_FILE"
+
+// Config holds all application configuration, shared by the server and memctl
+type Config struct {
+	PostgresConfig  storage.PostgresConfig
+	EmbeddingConfig embeddings.Config
+	LLMConfig       llm.Config
+
+	// OntologyPath is a JSON file of relationship types; empty uses the built-in types
+	OntologyPath string
+
+	// RetentionInterval is how often expired memories are purged; 0 disables the sweeper
+	RetentionInterval time.Duration
+
+	// JobConfig sizes the background job workers
+	JobConfig jobs.Config
+
+	// UIAddr is the listen address of the web UI; empty disables it
+	UIAddr string
+
+	// MetricsAddr is the listen address of /metrics, /healthz and /readyz; empty disables it
+	MetricsAddr string
+
+	// HTTP holds the timeouts of the web UI and metrics listeners
+	HTTP HTTPConfig
+
+	// TracingConfig selects the OpenTelemetry span exporter; tracing is off by default
+	TracingConfig tracing.Config
+
+	// Settings may change while the server runs; SIGHUP reloads them
+	Settings Settings
+}
+
+// HTTPConfig holds timeouts for the server's HTTP listeners
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration // 0 means no limit
+	WriteTimeout      time.Duration // 0 means no limit
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // How long shutdown waits for open requests
+}
+
+// Settings are the settings that are safe to change without a restart
+type Settings struct {
+	LogLevel  slog.Level
+	Search    SearchDefaults
+	Detection detect.Options // Thresholds for relationship detection; DryRun is ignored
+}
+
+// SearchDefaults apply to search_memories arguments that are left out
+type SearchDefaults struct {
+	Limit         int
+	MinSimilarity float64
+	MaxNeighbors  int // Graph-discovered results; 0 disables graph expansion
+}
+
+// Default returns the built-in configuration
+func Default() Config {
+	return Config{
+		PostgresConfig: storage.PostgresConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "memoryuser",
+			Password:        "memorypass",
+			Database:        "memorydb",
+			SSLMode:         storage.DefaultSSLMode,
+			ConnectTimeout:  storage.DefaultConnectTimeout,
+			MaxOpenConns:    storage.DefaultMaxOpenConns,
+			MaxIdleConns:    storage.DefaultMaxIdleConns,
+			ConnMaxLifetime: storage.DefaultConnMaxLifetime,
+		},
+		EmbeddingConfig: embeddings.Config{
+			BaseURL: "http://localhost:1234/v1",
+			Model:   "text-embedding-embeddinggemma-300m-qat",
+			APIKey:  "not-needed",
+			Timeout: embeddings.DefaultTimeout,
+		},
+		LLMConfig: llm.Config{
+			BaseURL: "http://localhost:1234/v1",
+			Model:   "qwen/qwen3-4b-2507",
+			APIKey:  "not-needed",
+			Timeout: llm.DefaultTimeout,
+		},
+		RetentionInterval: time.Hour,
+		JobConfig: jobs.Config{
+			Workers:      2,
+			PollInterval: time.Second,
+			RetryBackoff: 30 * time.Second,
+			Lease:        jobs.DefaultLease,
+		},
+		HTTP: HTTPConfig{
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   5 * time.Second,
+		},
+		TracingConfig: tracing.Config{
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+		},
+		Settings: Settings{
+			LogLevel: slog.LevelInfo,
+			Search: SearchDefaults{
+				Limit:        5,
+				MaxNeighbors: storage.DefaultMaxNeighbors,
+			},
+			Detection: detect.Options{
+				MinSimilarity: detect.DefaultMinSimilarity,
+				MaxCandidates: detect.DefaultMaxCandidates,
+				MinConfidence: detect.DefaultMinConfidence,
+			},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file at path
+// (skipped when path is empty) and the environment, then validates it.
+// All problems found are reported together.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, fmt.Errorf("invalid environment:\n%w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// loadEnv overrides settings with the environment variables that are set
+func (c *Config) loadEnv() error {
+	env := &envReader{}
+	env.string("POSTGRES_HOST", &c.PostgresConfig.Host)
+	env.string("POSTGRES_PORT", &c.PostgresConfig.Port)
+	env.string("POSTGRES_USER", &c.PostgresConfig.User)
+	env.string("POSTGRES_PASSWORD", &c.PostgresConfig.Password)
+	env.string("POSTGRES_DB", &c.PostgresConfig.Database)
+	env.string("POSTGRES_SSLMODE", &c.PostgresConfig.SSLMode)
+	env.string("POSTGRES_SSLROOTCERT", &c.PostgresConfig.SSLRootCert)
+	env.int("POSTGRES_MAX_OPEN_CONNS", &c.PostgresConfig.MaxOpenConns)
+	env.int("POSTGRES_MAX_IDLE_CONNS", &c.PostgresConfig.MaxIdleConns)
+	env.string("EMBEDDING_BASE_URL", &c.EmbeddingConfig.BaseURL)
+	env.string("EMBEDDING_MODEL", &c.EmbeddingConfig.Model)
+	env.string("EMBEDDING_API_KEY", &c.EmbeddingConfig.APIKey)
+	env.string("LLM_BASE_URL", &c.LLMConfig.BaseURL)
+	env.string("LLM_MODEL", &c.LLMConfig.Model)
+	env.string("LLM_API_KEY", &c.LLMConfig.APIKey)
+	env.string("RELATIONSHIP_ONTOLOGY", &c.OntologyPath)
+	env.duration("RETENTION_INTERVAL", &c.RetentionInterval)
+	env.int("JOB_WORKERS", &c.JobConfig.Workers)
+	env.duration("JOB_POLL_INTERVAL", &c.JobConfig.PollInterval)
+	env.duration("JOB_RETRY_BACKOFF", &c.JobConfig.RetryBackoff)
+	env.duration("JOB_LEASE", &c.JobConfig.Lease)
+	env.string("UI_ADDR", &c.UIAddr)
+	env.string("METRICS_ADDR", &c.MetricsAddr)
+	env.string("TRACE_EXPORTER", &c.TracingConfig.Exporter)
+	env.string("TRACE_FILE", &c.TracingConfig.File)
+	env.float("TRACE_SAMPLE_RATIO", &c.TracingConfig.SampleRatio)
+	env.logLevel("LOG_LEVEL", &c.Settings.LogLevel)
+
+	// DEBUG=true predates LOG_LEVEL and still turns on debug logging
+	if os.Getenv("LOG_LEVEL") == "" && os.Getenv("DEBUG") == "true" {
+		c.Settings.LogLevel = slog.LevelDebug
+	}
+	return errors.Join(env.errs...)
+}
+
+// LoadOntology loads the configured relationship types (or the built-in ones)
+// and hands them to the LLM config for the detection prompt
+func (c *Config) LoadOntology() (*ontology.Ontology, error) {
+	types := ontology.Default()
+	if c.OntologyPath != "" {
+		var err error
+		if types, err = ontology.Load(c.OntologyPath); err != nil {
+			return nil, err
+		}
+	}
+	c.LLMConfig.Ontology = types
+	return types, nil
+}
+
+// envReader reads the environment variables that are set into settings,
+// collecting values that don't parse instead of falling back to a default
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration such as 30s or 1h", key, value))
+		return
+	}
+	*dst = duration
+}
+
+func (e *envReader) int(key string, dst *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a number", key, value))
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) logLevel(key string, dst *slog.Level) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	if err := dst.UnmarshalText([]byte(value)); err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a log level (debug, info, warn or error)", key, value))
+	}
+}
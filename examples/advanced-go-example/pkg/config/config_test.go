@@ -0,0 +1,257 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envKeys are the variables loadEnv reads, cleared so the host environment can't leak into tests
+var envKeys = []string{
+	"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB",
+	"POSTGRES_SSLMODE", "POSTGRES_SSLROOTCERT", "POSTGRES_MAX_OPEN_CONNS", "POSTGRES_MAX_IDLE_CONNS",
+	"EMBEDDING_BASE_URL", "EMBEDDING_MODEL", "EMBEDDING_API_KEY", "LLM_BASE_URL", "LLM_MODEL", "LLM_API_KEY",
+	"RELATIONSHIP_ONTOLOGY", "RETENTION_INTERVAL", "JOB_WORKERS", "JOB_POLL_INTERVAL", "JOB_RETRY_BACKOFF", "JOB_LEASE",
+	"UI_ADDR", "METRICS_ADDR", "TRACE_EXPORTER", "TRACE_FILE", "TRACE_SAMPLE_RATIO", "LOG_LEVEL", "DEBUG",
+}
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range envKeys {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefault(t *testing.T) {
+	clearEnv(t)
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() without a file or environment = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		check   func(Config) bool // Inspects a valid configuration
+		wantErr []string          // Every string must appear in the error
+	}{
+		{name: "empty file keeps the defaults", content: "", check: func(c Config) bool { return reflect.DeepEqual(c, Default()) }},
+		{
+			name:    "overrides",
+			content: "postgres:\n  host: db\n  max_open_conns: 20\nretention_interval: 90m\nlog_level: debug\nsearch:\n  limit: 50\n",
+			check: func(c Config) bool {
+				return c.PostgresConfig.Host == "db" && c.PostgresConfig.MaxOpenConns == 20 &&
+					c.PostgresConfig.User == Default().PostgresConfig.User &&
+					c.RetentionInterval == 90*time.Minute && c.Settings.LogLevel == slog.LevelDebug && c.Settings.Search.Limit == 50
+			},
+		},
+		{name: "zero disables the sweeper", content: "retention_interval: 0s\n", check: func(c Config) bool { return c.RetentionInterval == 0 }},
+		{name: "unknown key", content: "postgres:\n  hots: db\n", wantErr: []string{"invalid config file", "field hots not found"}},
+		{name: "not a duration", content: "retention_interval: soon\n", wantErr: []string{"invalid config file"}},
+		{name: "negative duration", content: "retention_interval: -1h\n", wantErr: []string{"retention_interval must not be negative"}},
+		{name: "zero timeout", content: "llm:\n  timeout: 0s\n", wantErr: []string{"llm.timeout must be positive"}},
+		{name: "port out of range", content: "postgres:\n  port: \"70000\"\n", wantErr: []string{`postgres.port must be a port number, got "70000"`}},
+		{name: "idle above open", content: "postgres:\n  max_open_conns: 2\n  max_idle_conns: 3\n", wantErr: []string{"postgres.max_idle_conns must be between 1 and max_open_conns (2), got 3"}},
+		{name: "unknown sslmode", content: "postgres:\n  sslmode: maybe\n", wantErr: []string{"postgres.sslmode must be one of"}},
+		{name: "missing ontology", content: "ontology: /does/not/exist.json\n", wantErr: []string{"ontology:"}},
+		{name: "not a URL", content: "embeddings:\n  base_url: localhost:1234\n", wantErr: []string{"embeddings.base_url must be an http or https URL"}},
+		{name: "bad listen address", content: "ui:\n  addr: localhost\n", wantErr: []string{"ui.addr must be host:port"}},
+		{name: "shared listen address", content: "ui:\n  addr: :8080\nmetrics:\n  addr: :8080\n", wantErr: []string{"ui.addr and metrics.addr must differ"}},
+		{name: "file exporter without a file", content: "tracing:\n  exporter: file\n  file: \"\"\n", wantErr: []string{"tracing.file must be set"}},
+		{name: "sample ratio above 1", content: "tracing:\n  sample_ratio: 1.5\n", wantErr: []string{"tracing.sample_ratio must be between 0 and 1"}},
+		{name: "search limit out of range", content: "search:\n  limit: 0\n", wantErr: []string{"search.limit must be between 1 and 100, got 0"}},
+		{name: "zero lease", content: "jobs:\n  lease: 0s\n", wantErr: []string{"jobs.lease must be positive"}},
+		{name: "negative workers", content: "jobs:\n  workers: -1\n", wantErr: []string{"jobs.workers must not be negative"}},
+		{
+			name:    "every problem is reported",
+			content: "search:\n  limit: 101\n  max_neighbors: -1\ndetection:\n  max_candidates: 0\n",
+			wantErr: []string{"search.limit", "search.max_neighbors", "detection.max_candidates"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			cfg, err := Load(writeConfig(t, tt.content))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if !tt.check(cfg) {
+					t.Errorf("Load() = %+v", cfg)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load() succeeded, want an error containing %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to open config file") {
+		t.Errorf("Load() of a missing file error = %v", err)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Config) bool
+		wantErr string
+	}{
+		{
+			name: "overrides the file",
+			env:  map[string]string{"POSTGRES_HOST": "envdb", "RETENTION_INTERVAL": "2h", "JOB_WORKERS": "4"},
+			check: func(c Config) bool {
+				return c.PostgresConfig.Host == "envdb" && c.RetentionInterval == 2*time.Hour && c.JobConfig.Workers == 4
+			},
+		},
+		{name: "DEBUG turns on debug logging", env: map[string]string{"DEBUG": "true"}, check: func(c Config) bool { return c.Settings.LogLevel == slog.LevelDebug }},
+		{name: "LOG_LEVEL wins over DEBUG", env: map[string]string{"DEBUG": "true", "LOG_LEVEL": "warn"}, check: func(c Config) bool { return c.Settings.LogLevel == slog.LevelWarn }},
+		{name: "bad duration", env: map[string]string{"JOB_POLL_INTERVAL": "1 second"}, wantErr: `JOB_POLL_INTERVAL: "1 second" is not a duration`},
+		{name: "bad integer", env: map[string]string{"JOB_WORKERS": "two"}, wantErr: `JOB_WORKERS: "two" is not an integer`},
+		{name: "bad number", env: map[string]string{"TRACE_SAMPLE_RATIO": "half"}, wantErr: `TRACE_SAMPLE_RATIO: "half" is not a number`},
+		{name: "bad log level", env: map[string]string{"LOG_LEVEL": "loud"}, wantErr: `LOG_LEVEL: "loud" is not a log level`},
+		{name: "validated after the environment", env: map[string]string{"POSTGRES_MAX_OPEN_CONNS": "0"}, wantErr: "postgres.max_open_conns must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := Load(writeConfig(t, "postgres:\n  host: filedb\nretention_interval: 1h\n"))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if !tt.check(cfg) {
+					t.Errorf("Load() = %+v", cfg)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRejectedReload(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, "search:\n  limit: 10\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	live := NewLive(cfg.Settings)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"syntax error", "search: [limit\n"},
+		{"unknown key", "search:\n  limt: 20\n"},
+		{"invalid setting", "search:\n  limit: 20\n  min_similarity: 2\n"},
+		{"invalid restart-only setting", "search:\n  limit: 20\npostgres:\n  port: none\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			next, err := Load(path)
+			if err == nil {
+				live.Set(next.Settings)
+				t.Fatalf("Load() accepted %q", tt.content)
+			}
+			if got := live.Get(); got.Search.Limit != 10 {
+				t.Errorf("settings after a rejected reload have search limit %d, want 10", got.Search.Limit)
+			}
+		})
+	}
+
+	// A file that fails to decode halfway leaves the config untouched
+	broken := Default()
+	if err := broken.loadFile(writeConfig(t, "search:\n  limit: 20\n  max_neighbors: many\n")); err == nil {
+		t.Fatalf("loadFile() succeeded on a bad value")
+	}
+	if !reflect.DeepEqual(broken, Default()) {
+		t.Errorf("loadFile() changed the config before failing: %+v", broken.Settings.Search)
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Settings)
+		wantErr string // Empty when the settings are valid
+	}{
+		{name: "defaults", modify: func(*Settings) {}},
+		{name: "graph expansion off", modify: func(s *Settings) { s.Search.MaxNeighbors = 0 }},
+		{name: "largest limit", modify: func(s *Settings) { s.Search.Limit = 100 }},
+		{name: "limit too large", modify: func(s *Settings) { s.Search.Limit = 101 }, wantErr: "search.limit must be between 1 and 100, got 101"},
+		{name: "negative similarity", modify: func(s *Settings) { s.Search.MinSimilarity = -0.1 }, wantErr: "search.min_similarity must be between 0 and 1"},
+		{name: "too many neighbours", modify: func(s *Settings) { s.Search.MaxNeighbors = 101 }, wantErr: "search.max_neighbors must be between 0 and 100"},
+		{name: "confidence above 1", modify: func(s *Settings) { s.Detection.MinConfidence = 1.1 }, wantErr: "detection.min_confidence must be greater than 0 and at most 1"},
+		{name: "zero confidence", modify: func(s *Settings) { s.Detection.MinConfidence = 0 }, wantErr: "detection.min_confidence must be greater than 0"},
+		{name: "zero detection similarity", modify: func(s *Settings) { s.Detection.MinSimilarity = 0 }, wantErr: "detection.min_similarity must be greater than 0"},
+		{name: "search similarity may be 0", modify: func(s *Settings) { s.Search.MinSimilarity = 0 }},
+		{name: "no candidates", modify: func(s *Settings) { s.Detection.MaxCandidates = 0 }, wantErr: "detection.max_candidates must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := Default().Settings
+			tt.modify(&settings)
+			err := settings.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLive(t *testing.T) {
+	live := NewLive(Settings{LogLevel: slog.LevelInfo})
+
+	var seen []slog.Level
+	live.OnChange(func(s Settings) { seen = append(seen, s.LogLevel) })
+	live.Set(Settings{LogLevel: slog.LevelDebug})
+
+	if want := []slog.Level{slog.LevelInfo, slog.LevelDebug}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("OnChange saw %v, want %v", seen, want)
+	}
+	if got := live.Get().LogLevel; got != slog.LevelDebug {
+		t.Errorf("Get() log level = %v, want debug", got)
+	}
+}
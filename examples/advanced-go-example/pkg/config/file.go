@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSchema is the layout of the YAML config file. Its fields point into
+// a Config, so decoding overwrites only the keys the file sets.
+type fileSchema struct {
+	Postgres postgresSchema `yaml:"postgres"`
+
+	Embeddings serviceSchema `yaml:"embeddings"`
+	LLM        serviceSchema `yaml:"llm"`
+
+	Ontology          *string        `yaml:"ontology"`
+	RetentionInterval *time.Duration `yaml:"retention_interval"`
+
+	Jobs jobsSchema `yaml:"jobs"`
+
+	UI      listenerSchema `yaml:"ui"`
+	Metrics listenerSchema `yaml:"metrics"`
+
+	HTTP httpSchema `yaml:"http"`
+
+	Tracing tracingSchema `yaml:"tracing"`
+
+	LogLevel *slog.Level `yaml:"log_level"`
+
+	Search searchSchema `yaml:"search"`
+
+	Detection detectionSchema `yaml:"detection"`
+}
+
+// serviceSchema is the file layout of an OpenAI-compatible endpoint
+type serviceSchema struct {
+	BaseURL *string        `yaml:"base_url"`
+	Model   *string        `yaml:"model"`
+	APIKey  *string        `yaml:"api_key"`
+	Timeout *time.Duration `yaml:"timeout"`
+}
+
+// postgresSchema is the file layout of the Postgres connection
+type postgresSchema struct {
+	Host            *string        `yaml:"host"`
+	Port            *string        `yaml:"port"`
+	User            *string        `yaml:"user"`
+	Password        *string        `yaml:"password"`
+	Database        *string        `yaml:"database"`
+	SSLMode         *string        `yaml:"sslmode"`
+	SSLRootCert     *string        `yaml:"sslrootcert"`
+	SSLCert         *string        `yaml:"sslcert"`
+	SSLKey          *string        `yaml:"sslkey"`
+	ConnectTimeout  *time.Duration `yaml:"connect_timeout"`
+	MaxOpenConns    *int           `yaml:"max_open_conns"`
+	MaxIdleConns    *int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime *time.Duration `yaml:"conn_max_lifetime"`
+}
+
+// jobsSchema is the file layout of the job workers
+type jobsSchema struct {
+	Workers      *int           `yaml:"workers"`
+	PollInterval *time.Duration `yaml:"poll_interval"`
+	RetryBackoff *time.Duration `yaml:"retry_backoff"`
+	Lease        *time.Duration `yaml:"lease"`
+}
+
+// listenerSchema is the file layout of an optional HTTP listener
+type listenerSchema struct {
+	Addr *string `yaml:"addr"`
+}
+
+// httpSchema is the file layout of the HTTP listener timeouts
+type httpSchema struct {
+	ReadHeaderTimeout *time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       *time.Duration `yaml:"read_timeout"`
+	WriteTimeout      *time.Duration `yaml:"write_timeout"`
+	IdleTimeout       *time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   *time.Duration `yaml:"shutdown_timeout"`
+}
+
+// tracingSchema is the file layout of the span exporter
+type tracingSchema struct {
+	Exporter    *string  `yaml:"exporter"`
+	File        *string  `yaml:"file"`
+	SampleRatio *float64 `yaml:"sample_ratio"`
+}
+
+// searchSchema is the file layout of the search defaults
+type searchSchema struct {
+	Limit         *int     `yaml:"limit"`
+	MinSimilarity *float64 `yaml:"min_similarity"`
+	MaxNeighbors  *int     `yaml:"max_neighbors"`
+}
+
+// detectionSchema is the file layout of the relationship detection thresholds
+type detectionSchema struct {
+	MinSimilarity *float64 `yaml:"min_similarity"`
+	MaxCandidates *int     `yaml:"max_candidates"`
+	MinConfidence *float64 `yaml:"min_confidence"`
+}
+
+// bind points the schema's fields at c
+func (f *fileSchema) bind(c *Config) {
+	pg := &c.PostgresConfig
+	f.Postgres.Host = &pg.Host
+	f.Postgres.Port = &pg.Port
+	f.Postgres.User = &pg.User
+	f.Postgres.Password = &pg.Password
+	f.Postgres.Database = &pg.Database
+	f.Postgres.SSLMode = &pg.SSLMode
+	f.Postgres.SSLRootCert = &pg.SSLRootCert
+	f.Postgres.SSLCert = &pg.SSLCert
+	f.Postgres.SSLKey = &pg.SSLKey
+	f.Postgres.ConnectTimeout = &pg.ConnectTimeout
+	f.Postgres.MaxOpenConns = &pg.MaxOpenConns
+	f.Postgres.MaxIdleConns = &pg.MaxIdleConns
+	f.Postgres.ConnMaxLifetime = &pg.ConnMaxLifetime
+
+	f.Embeddings = serviceSchema{
+		BaseURL: &c.EmbeddingConfig.BaseURL,
+		Model:   &c.EmbeddingConfig.Model,
+		APIKey:  &c.EmbeddingConfig.APIKey,
+		Timeout: &c.EmbeddingConfig.Timeout,
+	}
+	f.LLM = serviceSchema{
+		BaseURL: &c.LLMConfig.BaseURL,
+		Model:   &c.LLMConfig.Model,
+		APIKey:  &c.LLMConfig.APIKey,
+		Timeout: &c.LLMConfig.Timeout,
+	}
+
+	f.Ontology = &c.OntologyPath
+	f.RetentionInterval = &c.RetentionInterval
+
+	f.Jobs.Workers = &c.JobConfig.Workers
+	f.Jobs.PollInterval = &c.JobConfig.PollInterval
+	f.Jobs.RetryBackoff = &c.JobConfig.RetryBackoff
+	f.Jobs.Lease = &c.JobConfig.Lease
+
+	f.UI.Addr = &c.UIAddr
+	f.Metrics.Addr = &c.MetricsAddr
+
+	f.HTTP.ReadHeaderTimeout = &c.HTTP.ReadHeaderTimeout
+	f.HTTP.ReadTimeout = &c.HTTP.ReadTimeout
+	f.HTTP.WriteTimeout = &c.HTTP.WriteTimeout
+	f.HTTP.IdleTimeout = &c.HTTP.IdleTimeout
+	f.HTTP.ShutdownTimeout = &c.HTTP.ShutdownTimeout
+
+	f.Tracing.Exporter = &c.TracingConfig.Exporter
+	f.Tracing.File = &c.TracingConfig.File
+	f.Tracing.SampleRatio = &c.TracingConfig.SampleRatio
+
+	f.LogLevel = &c.Settings.LogLevel
+
+	f.Search.Limit = &c.Settings.Search.Limit
+	f.Search.MinSimilarity = &c.Settings.Search.MinSimilarity
+	f.Search.MaxNeighbors = &c.Settings.Search.MaxNeighbors
+
+	f.Detection.MinSimilarity = &c.Settings.Detection.MinSimilarity
+	f.Detection.MaxCandidates = &c.Settings.Detection.MaxCandidates
+	f.Detection.MinConfidence = &c.Settings.Detection.MinConfidence
+}
+
+// loadFile overrides settings with the keys set in the YAML file at path.
+// Unknown keys are errors, so typos don't silently leave a default in place.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode into a copy so a broken file leaves c untouched
+	loaded := *c
+	var schema fileSchema
+	schema.bind(&loaded)
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&schema); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	*c = loaded
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import "sync"
+
+// Live holds the settings that may change while the server runs. Readers
+// call Get on every use; Set swaps in reloaded settings and notifies the
+// subscribers registered with OnChange.
+type Live struct {
+	mu        sync.RWMutex
+	settings  Settings
+	listeners []func(Settings)
+}
+
+// NewLive creates a Live holding settings
+func NewLive(settings Settings) *Live {
+	return &Live{settings: settings}
+}
+
+// Get returns the current settings
+func (l *Live) Get() Settings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.settings
+}
+
+// Set replaces the settings and calls the OnChange listeners
+func (l *Live) Set(settings Settings) {
+	l.mu.Lock()
+	l.settings = settings
+	listeners := l.listeners
+	l.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(settings)
+	}
+}
+
+// OnChange registers fn to be called with the current settings now and
+// with the new settings after every Set
+func (l *Live) OnChange(fn func(Settings)) {
+	l.mu.Lock()
+	l.listeners = append(l.listeners, fn)
+	settings := l.settings
+	l.mu.Unlock()
+
+	fn(settings)
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+	"advanced-go-example/pkg/tracing"
+)
+
+// Validate checks the whole configuration and reports every problem, each
+// named by its config file key
+func (c Config) Validate() error {
+	v := &validator{}
+
+	pg := c.PostgresConfig
+	v.check(pg.Host != "", "postgres.host must be set")
+	if port, err := strconv.Atoi(pg.Port); err != nil || port < 1 || port > 65535 {
+		v.fail("postgres.port must be a port number, got %q", pg.Port)
+	}
+	v.check(pg.User != "", "postgres.user must be set")
+	v.check(pg.Database != "", "postgres.database must be set")
+	v.check(slices.Contains(storage.SSLModes, pg.SSLMode),
+		"postgres.sslmode must be one of %s, got %q", strings.Join(storage.SSLModes, ", "), pg.SSLMode)
+	v.file("postgres.sslrootcert", pg.SSLRootCert)
+	v.file("postgres.sslcert", pg.SSLCert)
+	v.file("postgres.sslkey", pg.SSLKey)
+	v.check((pg.SSLCert == "") == (pg.SSLKey == ""), "postgres.sslcert and postgres.sslkey must be set together")
+	v.positive("postgres.connect_timeout", pg.ConnectTimeout)
+	v.check(pg.MaxOpenConns >= 1, "postgres.max_open_conns must be at least 1, got %d", pg.MaxOpenConns)
+	v.check(pg.MaxIdleConns >= 1 && pg.MaxIdleConns <= pg.MaxOpenConns,
+		"postgres.max_idle_conns must be between 1 and max_open_conns (%d), got %d", pg.MaxOpenConns, pg.MaxIdleConns)
+	v.positive("postgres.conn_max_lifetime", pg.ConnMaxLifetime)
+
+	v.url("embeddings.base_url", c.EmbeddingConfig.BaseURL)
+	v.check(c.EmbeddingConfig.Model != "", "embeddings.model must be set")
+	v.positive("embeddings.timeout", c.EmbeddingConfig.Timeout)
+	v.url("llm.base_url", c.LLMConfig.BaseURL)
+	v.check(c.LLMConfig.Model != "", "llm.model must be set")
+	v.positive("llm.timeout", c.LLMConfig.Timeout)
+
+	v.file("ontology", c.OntologyPath)
+	v.duration("retention_interval", c.RetentionInterval)
+
+	v.check(c.JobConfig.Workers >= 0, "jobs.workers must not be negative, got %d", c.JobConfig.Workers)
+	v.positive("jobs.poll_interval", c.JobConfig.PollInterval)
+	v.positive("jobs.retry_backoff", c.JobConfig.RetryBackoff)
+	v.positive("jobs.lease", c.JobConfig.Lease)
+
+	v.addr("ui.addr", c.UIAddr)
+	v.addr("metrics.addr", c.MetricsAddr)
+	v.check(c.UIAddr == "" || c.UIAddr != c.MetricsAddr, "ui.addr and metrics.addr must differ")
+	v.duration("http.read_header_timeout", c.HTTP.ReadHeaderTimeout)
+	v.duration("http.read_timeout", c.HTTP.ReadTimeout)
+	v.duration("http.write_timeout", c.HTTP.WriteTimeout)
+	v.duration("http.idle_timeout", c.HTTP.IdleTimeout)
+	v.positive("http.shutdown_timeout", c.HTTP.ShutdownTimeout)
+
+	exporters := []string{tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterFile}
+	v.check(slices.Contains(exporters, c.TracingConfig.Exporter),
+		"tracing.exporter must be empty, %s or %s, got %q", tracing.ExporterOTLP, tracing.ExporterFile, c.TracingConfig.Exporter)
+	v.check(c.TracingConfig.Exporter != tracing.ExporterFile || c.TracingConfig.File != "",
+		"tracing.file must be set for the file exporter")
+	v.fraction("tracing.sample_ratio", c.TracingConfig.SampleRatio)
+
+	if err := c.Settings.Validate(); err != nil {
+		v.errs = append(v.errs, err)
+	}
+
+	if err := errors.Join(v.errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// Validate checks the reloadable settings
+func (s Settings) Validate() error {
+	v := &validator{}
+
+	v.check(s.Search.Limit >= 1 && s.Search.Limit <= 100, "search.limit must be between 1 and 100, got %d", s.Search.Limit)
+	v.fraction("search.min_similarity", s.Search.MinSimilarity)
+	v.check(s.Search.MaxNeighbors >= 0 && s.Search.MaxNeighbors <= 100,
+		"search.max_neighbors must be between 0 and 100, got %d", s.Search.MaxNeighbors)
+
+	// detect.SetDefaults replaces 0 with the built-in default, so reject it
+	v.positiveFraction("detection.min_similarity", s.Detection.MinSimilarity)
+	v.check(s.Detection.MaxCandidates >= 1, "detection.max_candidates must be at least 1, got %d", s.Detection.MaxCandidates)
+	v.positiveFraction("detection.min_confidence", s.Detection.MinConfidence)
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects validation failures
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.fail(format, args...)
+	}
+}
+
+func (v *validator) duration(key string, d time.Duration) {
+	v.check(d >= 0, "%s must not be negative, got %s", key, d)
+}
+
+// positive checks settings where 0 would fall back to a default
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, "%s must be positive, got %s", key, d)
+}
+
+func (v *validator) fraction(key string, f float64) {
+	v.check(f >= 0 && f <= 1, "%s must be between 0 and 1, got %v", key, f)
+}
+
+func (v *validator) positiveFraction(key string, f float64) {
+	v.check(f > 0 && f <= 1, "%s must be greater than 0 and at most 1, got %v", key, f)
+}
+
+// file checks that an optional path names a readable file
+func (v *validator) file(key, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.fail("%s: %v", key, err)
+	}
+}
+
+func (v *validator) url(key, raw string) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.fail("%s must be an http or https URL, got %q", key, raw)
+	}
+}
+
+// addr checks an optional host:port listen address
+func (v *validator) addr(key, addr string) {
+	if addr == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.fail("%s must be host:port or :port, got %q", key, addr)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		v.fail("%s has an invalid port, got %q", key, addr)
+	}
+}
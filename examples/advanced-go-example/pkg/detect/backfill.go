@@ -0,0 +1,294 @@
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+)
+
+// BackfillJobKind is the job queue kind for graph-wide relationship backfills
+const BackfillJobKind = "backfill_relationships"
+
+// Defaults for zero-valued BackfillOptions
+const (
+	DefaultBackfillConcurrency = 2
+	DefaultBackfillRateLimit   = 1.0 // LLM calls per second
+	DefaultBackfillBatchSize   = 50
+)
+
+// MaxBackfillRateLimit caps RateLimit; faster rates would round the ticker
+// interval down to nothing
+const MaxBackfillRateLimit = 50.0
+
+// maxBackfillErrors bounds how many per-memory errors the summary keeps
+const maxBackfillErrors = 20
+
+// maxConsecutiveFailures stops a backfill whose LLM calls keep failing, e.g.
+// because the model is not loaded, instead of marking every memory failed.
+// The job is retried from its last checkpoint.
+const maxConsecutiveFailures = 10
+
+// BackfillOptions select the memories to backfill and pace the LLM calls.
+// They are the payload of backfill_relationships jobs.
+type BackfillOptions struct {
+	Options
+	GroupID       string  `json:"group_id,omitzero"`       // Only memories in this group ("" for all)
+	IncludeLinked bool    `json:"include_linked,omitzero"` // Also analyze memories that already have auto-detected edges
+	Concurrency   int     `json:"concurrency,omitzero"`    // LLM calls in flight (default: 2)
+	RateLimit     float64 `json:"rate_limit,omitzero"`     // LLM calls started per second, up to 50 (default: 1)
+	BatchSize     int     `json:"batch_size,omitzero"`     // Memories per candidate query and checkpoint (default: 50)
+}
+
+// BackfillProgress is both the checkpoint of a running backfill and its
+// final summary. Counts cover batches up to Cursor.
+type BackfillProgress struct {
+	Cursor        string         `json:"cursor,omitzero"`     // list cursor after the last finished batch
+	Scanned       int            `json:"scanned"`             // Memories walked
+	AlreadyLinked int            `json:"already_linked"`      // Skipped: they already had auto-detected edges
+	NoCandidates  int            `json:"no_candidates"`       // Skipped: nothing similar enough
+	Analyzed      int            `json:"analyzed"`            // Sent to the LLM
+	Failed        int            `json:"failed"`              // LLM or edge creation errors
+	FailedIDs     []int64        `json:"failed_ids,omitzero"` // Memories that failed, retried when the backfill resumes
+	Created       int            `json:"created"`             // Edges created (or, in a dry run, that would be)
+	CreatedByType map[string]int `json:"created_by_type"`
+	Errors        []string       `json:"errors,omitzero"` // The first few failures
+	Done          bool           `json:"done"`
+}
+
+// Backfill runs detection for every memory (or every memory in one group)
+// without auto-detected edges, in ID order. Those edges are looked up each
+// time Backfill starts, so a resumed backfill also skips memories linked in
+// the meantime. Candidates for a whole batch come from one pgvector query;
+// LLM calls run with bounded concurrency and a rate limit. After each batch
+// the progress is passed to checkpoint. A backfill started from a saved
+// progress first retries the memories that failed, then resumes after the cursor.
+func (d *Detector) Backfill(ctx context.Context, opts BackfillOptions, progress BackfillProgress, checkpoint func(BackfillProgress) error) (BackfillProgress, error) {
+	// Set defaults
+	opts.Options = opts.Options.withDefaults(*d.defaults.Load())
+	if opts.Concurrency == 0 {
+		opts.Concurrency = DefaultBackfillConcurrency
+	}
+	if opts.RateLimit == 0 {
+		opts.RateLimit = DefaultBackfillRateLimit
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBackfillBatchSize
+	}
+	if opts.Concurrency < 1 || opts.BatchSize < 1 {
+		return progress, fmt.Errorf("concurrency and batch_size must be positive")
+	}
+	if !(opts.RateLimit > 0 && opts.RateLimit <= MaxBackfillRateLimit) {
+		return progress, fmt.Errorf("rate_limit must be greater than 0 and at most %v, got %v", MaxBackfillRateLimit, opts.RateLimit)
+	}
+	if progress.CreatedByType == nil {
+		progress.CreatedByType = map[string]int{}
+	}
+
+	linked := map[int64]bool{}
+	if !opts.IncludeLinked {
+		var err error
+		if linked, err = d.store.AutoDetectedMemoryIDs(); err != nil {
+			return progress, err
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RateLimit))
+	defer ticker.Stop()
+
+	// Retry earlier failures first; those that fail again are kept for the next resume
+	for retry := len(progress.FailedIDs); retry > 0; retry -= opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		ids := progress.FailedIDs[:min(retry, opts.BatchSize)]
+		memories, err := d.store.LookupMemories(ids)
+		if err != nil {
+			return progress, err
+		}
+
+		// The failed attempts no longer count; deleted or expired memories are dropped
+		batch := progress.clone()
+		batch.FailedIDs = batch.FailedIDs[len(ids):]
+		batch.Analyzed -= len(ids)
+		batch.Failed -= len(ids)
+		batch.Errors = slices.DeleteFunc(batch.Errors, func(msg string) bool {
+			return slices.ContainsFunc(ids, func(id int64) bool { return strings.HasPrefix(msg, fmt.Sprintf("memory %d: ", id)) })
+		})
+		if err := d.backfillBatch(ctx, memories, opts, ticker, &batch); err != nil {
+			return progress, err
+		}
+
+		progress = batch
+		if err := checkpoint(progress); err != nil {
+			return progress, err
+		}
+	}
+
+	for !progress.Done {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		memories, next, err := d.store.ListMemories(storage.ListOptions{
+			GroupID:   opts.GroupID,
+			SortBy:    "id",
+			Ascending: true,
+			Limit:     opts.BatchSize,
+			Cursor:    progress.Cursor,
+		})
+		if err != nil {
+			return progress, err
+		}
+
+		batch := progress.clone()
+		var sources []storage.Memory
+		for _, memory := range memories {
+			batch.Scanned++
+			if linked[memory.ID] {
+				batch.AlreadyLinked++
+				continue
+			}
+			sources = append(sources, memory)
+		}
+		if err := d.backfillBatch(ctx, sources, opts, ticker, &batch); err != nil {
+			return progress, err
+		}
+
+		batch.Cursor, batch.Done = next, next == ""
+		progress = batch
+		if err := checkpoint(progress); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+// clone copies p so a batch can update it without touching the last checkpoint
+func (p BackfillProgress) clone() BackfillProgress {
+	p.CreatedByType = maps.Clone(p.CreatedByType)
+	p.FailedIDs = slices.Clone(p.FailedIDs)
+	p.Errors = slices.Clone(p.Errors)
+	return p
+}
+
+// backfillBatch analyzes one batch of memories, adding its counts to progress
+func (d *Detector) backfillBatch(ctx context.Context, sources []storage.Memory, opts BackfillOptions, ticker *time.Ticker, progress *BackfillProgress) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(sources))
+	for i, source := range sources {
+		ids[i] = source.ID
+	}
+
+	neighbors, err := d.store.NearestNeighbors(ids, opts.MaxCandidates, opts.MinSimilarity)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	consecutiveFailures := 0
+	var lastErr error
+	sem := make(chan struct{}, opts.Concurrency)
+
+	for _, source := range sources {
+		if len(neighbors[source.ID]) == 0 {
+			mu.Lock()
+			progress.NoCandidates++
+			mu.Unlock()
+			continue
+		}
+
+		// Pace LLM calls; stop starting new ones on shutdown
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		mu.Lock()
+		tooManyFailures := consecutiveFailures >= maxConsecutiveFailures
+		mu.Unlock()
+		if ctx.Err() != nil || tooManyFailures {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			result, err := d.analyze(source, neighbors[source.ID], opts.Options)
+
+			mu.Lock()
+			defer mu.Unlock()
+			progress.Analyzed++
+			if result != nil {
+				consecutiveFailures = 0
+				for _, rel := range result.Created {
+					progress.Created++
+					progress.CreatedByType[rel.Type]++
+				}
+				if opts.DryRun {
+					for _, suggestion := range result.Suggestions {
+						if suggestion.Confidence >= opts.MinConfidence {
+							progress.Created++
+							progress.CreatedByType[suggestion.Type]++
+						}
+					}
+				}
+			} else {
+				consecutiveFailures++
+			}
+			if err != nil {
+				lastErr = err
+				progress.Failed++
+				progress.FailedIDs = append(progress.FailedIDs, source.ID)
+				if len(progress.Errors) < maxBackfillErrors {
+					progress.Errors = append(progress.Errors, fmt.Sprintf("memory %d: %v", source.ID, err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if consecutiveFailures >= maxConsecutiveFailures {
+		return fmt.Errorf("stopping after %d consecutive failures: %w", consecutiveFailures, lastErr)
+	}
+	return nil
+}
+
+// HandleBackfillJob runs a backfill_relationships job, checkpointing into
+// the job's progress so a retried or interrupted job resumes where it stopped
+func (d *Detector) HandleBackfillJob(ctx context.Context, job storage.Job) (interface{}, error) {
+	var opts BackfillOptions
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &opts); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", BackfillJobKind, err)
+		}
+	}
+	var progress BackfillProgress
+	if len(job.Progress) > 0 {
+		if err := json.Unmarshal(job.Progress, &progress); err != nil {
+			return nil, fmt.Errorf("invalid %s checkpoint: %w", BackfillJobKind, err)
+		}
+	}
+
+	d = d.WithContext(ctx)
+	progress, err := d.Backfill(ctx, opts, progress, func(p BackfillProgress) error {
+		return d.store.SetJobProgress(job.ID, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
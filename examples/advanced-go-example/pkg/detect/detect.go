@@ -0,0 +1,192 @@
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/ontology"
+	"advanced-go-example/pkg/storage"
+)
+
+// JobKind is the job queue kind for background relationship detection
+const JobKind = "detect_relationships"
+
+// Defaults for zero-valued Options
+const (
+	DefaultMinSimilarity = 0.5
+	DefaultMaxCandidates = 10
+	DefaultMinConfidence = 0.7
+)
+
+// Options tune a detection pass; zero values use the defaults. They are also
+// the payload of detect_relationships jobs.
+type Options struct {
+	MinSimilarity float64 `json:"min_similarity,omitzero"` // Minimum similarity for candidates
+	MaxCandidates int     `json:"max_candidates,omitzero"` // Most similar memories shown to the LLM
+	MinConfidence float64 `json:"min_confidence,omitzero"` // Minimum LLM confidence to create an edge
+	DryRun        bool    `json:"dry_run,omitzero"`        // Return suggestions without creating edges
+}
+
+// Result is the outcome of a detection pass
+type Result struct {
+	Candidates  int                          `json:"candidates"`
+	Suggestions []llm.RelationshipSuggestion `json:"suggestions"`
+	Created     []storage.Relationship       `json:"created"`
+	Skipped     string                       `json:"skipped,omitzero"` // Why the pass did nothing, e.g. the memory was deleted
+}
+
+// defaultOptions holds the package defaults as Options
+var defaultOptions = Options{
+	MinSimilarity: DefaultMinSimilarity,
+	MaxCandidates: DefaultMaxCandidates,
+	MinConfidence: DefaultMinConfidence,
+}
+
+// Detector finds relationships between a memory and its nearest neighbours
+// by asking the LLM about them, and stores the confident ones
+type Detector struct {
+	store    *storage.PostgresStore
+	llm      *llm.Client
+	ontology *ontology.Ontology
+	defaults *atomic.Pointer[Options] // Shared with WithContext copies so SetDefaults reaches them
+}
+
+// NewDetector creates a detector
+func NewDetector(store *storage.PostgresStore, llmClient *llm.Client, types *ontology.Ontology) *Detector {
+	d := &Detector{store: store, llm: llmClient, ontology: types, defaults: &atomic.Pointer[Options]{}}
+	d.SetDefaults(Options{})
+	return d
+}
+
+// WithContext returns a copy of the detector whose storage and LLM calls
+// trace as children of the span in ctx
+func (d *Detector) WithContext(ctx context.Context) *Detector {
+	return &Detector{store: d.store.WithContext(ctx), llm: d.llm.WithContext(ctx), ontology: d.ontology, defaults: d.defaults}
+}
+
+// SetDefaults replaces the thresholds used for zero-valued options; its own
+// zero fields fall back to the package defaults. It is safe to call while
+// detection runs, e.g. when the configuration is reloaded.
+func (d *Detector) SetDefaults(opts Options) {
+	opts = opts.withDefaults(defaultOptions)
+	opts.DryRun = false
+	d.defaults.Store(&opts)
+}
+
+// withDefaults fills in zero-valued options from defaults
+func (o Options) withDefaults(defaults Options) Options {
+	if o.MinSimilarity == 0 {
+		o.MinSimilarity = defaults.MinSimilarity
+	}
+	if o.MaxCandidates == 0 {
+		o.MaxCandidates = defaults.MaxCandidates
+	}
+	if o.MinConfidence == 0 {
+		o.MinConfidence = defaults.MinConfidence
+	}
+	return o
+}
+
+// Detect analyzes memoryID against its most similar memories. Edges that
+// fail to store do not stop the others; their errors are returned together.
+func (d *Detector) Detect(memoryID int64, opts Options) (*Result, error) {
+	opts = opts.withDefaults(*d.defaults.Load())
+
+	source, err := d.store.LookupMemory(memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source memory: %w", err)
+	}
+
+	// Find similar memories as candidates
+	neighbors, err := d.store.NearestNeighbors([]int64{memoryID}, opts.MaxCandidates, opts.MinSimilarity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for candidates: %w", err)
+	}
+
+	return d.analyze(*source, neighbors[memoryID], opts)
+}
+
+// analyze asks the LLM how source relates to its neighbours and stores the
+// suggestions that reach opts.MinConfidence
+func (d *Detector) analyze(source storage.Memory, neighbors []storage.SearchResult, opts Options) (*Result, error) {
+	candidates := make([]llm.CandidateMemory, len(neighbors))
+	for i, neighbor := range neighbors {
+		candidates[i] = llm.CandidateMemory{
+			ID:         neighbor.Memory.ID,
+			Text:       neighbor.Memory.Text,
+			Similarity: neighbor.Similarity,
+		}
+	}
+
+	result := &Result{
+		Candidates:  len(candidates),
+		Suggestions: []llm.RelationshipSuggestion{},
+		Created:     []storage.Relationship{},
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	suggestions, err := d.llm.AnalyzeRelationships(source.Text, source.ID, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+	}
+	result.Suggestions = append(result.Suggestions, suggestions...)
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	// Create high-confidence relationships
+	var errs []error
+	for _, suggestion := range suggestions {
+		if suggestion.Confidence < opts.MinConfidence {
+			continue
+		}
+
+		props := map[string]interface{}{
+			"reason":        suggestion.Reason,
+			"confidence":    suggestion.Confidence,
+			"auto_detected": true,
+		}
+		fromID, toID, relType, err := d.ontology.Normalize(source.ID, suggestion.TargetID, suggestion.Type, props)
+		if err == nil {
+			var id int64
+			if id, err = d.store.AddRelationship(fromID, toID, relType, props); err == nil {
+				result.Created = append(result.Created, storage.Relationship{ID: id, FromID: fromID, ToID: toID, Type: relType, Properties: props})
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("failed to create %s from %d to %d: %w", suggestion.Type, source.ID, suggestion.TargetID, err))
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// HandleJob runs a detect_relationships job. A memory deleted before the job
+// ran is not an error; the result records that it was skipped.
+func (d *Detector) HandleJob(ctx context.Context, job storage.Job) (interface{}, error) {
+	var opts Options
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &opts); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", JobKind, err)
+		}
+	}
+
+	result, err := d.WithContext(ctx).Detect(job.MemoryID, opts)
+	if errors.Is(err, storage.ErrMemoryNotFound) {
+		return Result{
+			Suggestions: []llm.RelationshipSuggestion{},
+			Created:     []storage.Relationship{},
+			Skipped:     fmt.Sprintf("memory %d no longer exists", job.MemoryID),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
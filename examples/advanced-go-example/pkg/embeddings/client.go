@@ -2,10 +2,17 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
+
+	"advanced-go-example/pkg/metrics"
+	"advanced-go-example/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Config holds embedding service configuration
@@ -13,26 +20,83 @@ type Config struct {
 	BaseURL string
 	Model   string
 	APIKey  string
+	Timeout time.Duration // Per request; 0 uses DefaultTimeout
 }
 
+// DefaultTimeout bounds an embedding request when Config.Timeout is unset
+const DefaultTimeout = 30 * time.Second
+
 // Client handles embedding generation via LM Studio API
 type Client struct {
 	config Config
 	http   *http.Client
+	ctx    context.Context // Parent of the request spans; see WithContext
 }
 
 // NewClient creates a new embedding client
 func NewClient(config Config) *Client {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
 	return &Client{
 		config: config,
 		http: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout,
 		},
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a copy of the client whose requests trace as children
+// of the span in ctx
+func (c *Client) WithContext(ctx context.Context) *Client {
+	scoped := *c
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// Model returns the name of the embedding model requests are sent to
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
+// Ping checks that the embedding API is reachable by listing its models
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.BaseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
 	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+	return nil
 }
 
 // Generate creates an embedding vector for the given text
 func (c *Client) Generate(text string) ([]float64, error) {
+	_, span := tracing.StartChild(c.ctx, "embeddings.Generate",
+		attribute.String("gen_ai.operation.name", "embeddings"),
+		attribute.String("gen_ai.request.model", c.config.Model),
+	)
+	start := time.Now()
+	embedding, err := c.generate(text)
+	metrics.ObserveEmbedding(start, err)
+	tracing.End(span, err)
+	return embedding, err
+}
+
+func (c *Client) generate(text string) ([]float64, error) {
 	reqBody := map[string]interface{}{
 		"model": c.config.Model,
 		"input": text,
@@ -79,3 +143,81 @@ func (c *Client) Generate(text string) ([]float64, error) {
 
 	return result.Data[0].Embedding, nil
 }
+
+// GenerateBatch creates embeddings for several texts in one request. The
+// results are in the same order as texts.
+func (c *Client) GenerateBatch(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+
+	_, span := tracing.StartChild(c.ctx, "embeddings.GenerateBatch",
+		attribute.String("gen_ai.operation.name", "embeddings"),
+		attribute.String("gen_ai.request.model", c.config.Model),
+		attribute.Int("embeddings.input_count", len(texts)),
+	)
+	start := time.Now()
+	embeddings, err := c.generateBatch(texts)
+	metrics.ObserveEmbedding(start, err)
+	tracing.End(span, err)
+	return embeddings, err
+}
+
+func (c *Client) generateBatch(texts []string) ([][]float64, error) {
+
+	reqBody := map[string]interface{}{
+		"model": c.config.Model,
+		"input": texts,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.config.BaseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	// The API reports each embedding's input index; don't rely on response order
+	embeddings := make([][]float64, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) || embeddings[item.Index] != nil {
+			return nil, fmt.Errorf("invalid embedding index %d in response", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,191 @@
+// Package exchange reads and writes the portable memory export format.
+//
+// An export is a JSONL stream. The first line is a header naming the format,
+// its version and the embedding model; every following line is a memory or
+// an edge record:
+//
+//	{"type":"header","format":"mcp-memory-export","version":1,"embedding_model":"text-embedding-embeddinggemma-300m-qat","embedding_dimensions":768}
+//	{"type":"memory","external_id":"5f0c...","text":"The user loves Go","tags":["preferences"],"importance":0.8,"embedding":[0.01, ...]}
+//	{"type":"edge","from":"5f0c...","to":"9ab1...","relationship_type":"RELATES_TO","properties":{"strength":0.8}}
+//
+// Memories are identified by their external ID, so the same file can be
+// imported repeatedly and into either the SQLite or the Postgres server.
+package exchange
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format identifies the export format in the header
+const Format = "mcp-memory-export"
+
+// Version is the current format version. Readers reject newer versions.
+const Version = 1
+
+// Record types
+const (
+	TypeHeader = "header"
+	TypeMemory = "memory"
+	TypeEdge   = "edge"
+)
+
+// maxLineSize bounds a single record; a 768-dim embedding is roughly 16KB
+const maxLineSize = 16 * 1024 * 1024
+
+// Header is the first line of every export
+type Header struct {
+	Type                string    `json:"type"`
+	Format              string    `json:"format"`
+	Version             int       `json:"version"`
+	ExportedAt          time.Time `json:"exported_at,omitzero"`
+	Backend             string    `json:"backend,omitzero"`              // postgres or sqlite
+	EmbeddingModel      string    `json:"embedding_model,omitzero"`      // Model that produced the embeddings
+	EmbeddingDimensions int       `json:"embedding_dimensions,omitzero"` // 0 when embeddings are not included
+}
+
+// MemoryRecord is one memory with its metadata and optional embedding
+type MemoryRecord struct {
+	Type       string                 `json:"type"`
+	ExternalID string                 `json:"external_id"`
+	Text       string                 `json:"text"`
+	GroupID    string                 `json:"group_id,omitzero"`
+	Tags       []string               `json:"tags,omitzero"`
+	Source     string                 `json:"source,omitzero"`
+	Importance *float64               `json:"importance,omitzero"` // Absent means storage.DefaultImportance; 0 is a real value
+	Attributes map[string]interface{} `json:"attributes,omitzero"`
+	CreatedAt  time.Time              `json:"created_at,omitzero"`
+	UpdatedAt  time.Time              `json:"updated_at,omitzero"`
+	ExpiresAt  time.Time              `json:"expires_at,omitzero"`
+	Embedding  []float64              `json:"embedding,omitzero"`
+}
+
+// EdgeRecord is a directed relationship between two memories, by external ID
+type EdgeRecord struct {
+	Type             string                 `json:"type"`
+	From             string                 `json:"from"`
+	To               string                 `json:"to"`
+	RelationshipType string                 `json:"relationship_type"`
+	Properties       map[string]interface{} `json:"properties,omitzero"`
+}
+
+// Writer writes an export stream
+type Writer struct {
+	encoder *json.Encoder
+}
+
+// NewWriter writes the header and returns a writer for the records
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Type = TypeHeader
+	header.Format = Format
+	header.Version = Version
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write export header: %w", err)
+	}
+	return &Writer{encoder: encoder}, nil
+}
+
+// WriteMemory writes a memory record
+func (w *Writer) WriteMemory(record MemoryRecord) error {
+	record.Type = TypeMemory
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write memory %s: %w", record.ExternalID, err)
+	}
+	return nil
+}
+
+// WriteEdge writes an edge record
+func (w *Writer) WriteEdge(record EdgeRecord) error {
+	record.Type = TypeEdge
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write edge %s -> %s: %w", record.From, record.To, err)
+	}
+	return nil
+}
+
+// Reader reads an export stream
+type Reader struct {
+	Header Header
+
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader reads and checks the header
+func NewReader(r io.Reader) (*Reader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	reader := &Reader{scanner: scanner}
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read export header: %w", err)
+		}
+		return nil, fmt.Errorf("export is empty")
+	}
+	reader.line++
+
+	if err := json.Unmarshal(scanner.Bytes(), &reader.Header); err != nil {
+		return nil, fmt.Errorf("invalid export header: %w", err)
+	}
+	if reader.Header.Type != TypeHeader || reader.Header.Format != Format {
+		return nil, fmt.Errorf("not a memory export: first line must be a %q header", Format)
+	}
+	if reader.Header.Version < 1 || reader.Header.Version > Version {
+		return nil, fmt.Errorf("unsupported export version %d (supported: 1-%d)", reader.Header.Version, Version)
+	}
+
+	return reader, nil
+}
+
+// Next returns the next record, a *MemoryRecord or *EdgeRecord, or io.EOF
+// at the end of the stream. Blank lines are skipped.
+func (r *Reader) Next() (interface{}, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var kind struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(line, &kind); err != nil {
+			return nil, fmt.Errorf("line %d: invalid record: %w", r.line, err)
+		}
+
+		switch kind.Type {
+		case TypeMemory:
+			var record MemoryRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				return nil, fmt.Errorf("line %d: invalid memory record: %w", r.line, err)
+			}
+			if record.ExternalID == "" || record.Text == "" {
+				return nil, fmt.Errorf("line %d: memory records need external_id and text", r.line)
+			}
+			return &record, nil
+		case TypeEdge:
+			var record EdgeRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				return nil, fmt.Errorf("line %d: invalid edge record: %w", r.line, err)
+			}
+			if record.From == "" || record.To == "" || record.RelationshipType == "" {
+				return nil, fmt.Errorf("line %d: edge records need from, to and relationship_type", r.line)
+			}
+			return &record, nil
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %q", r.line, kind.Type)
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: failed to read export: %w", r.line+1, err)
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,215 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/storage"
+)
+
+// graphNamePattern restricts relationship types and property keys read from
+// a file to names that are safe to splice into Cypher
+var graphNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExportOptions controls what Export writes
+type ExportOptions struct {
+	GroupID           string // Only export this group ("" exports everything)
+	IncludeEmbeddings bool
+	EmbeddingModel    string // Recorded in the header when embeddings are included
+}
+
+// ExportStats counts the records written by Export
+type ExportStats struct {
+	Memories int `json:"memories"`
+	Edges    int `json:"edges"`
+}
+
+// Export writes every memory and the edges between exported memories
+func Export(store *storage.PostgresStore, w io.Writer, opts ExportOptions) (*ExportStats, error) {
+	header := Header{ExportedAt: time.Now().UTC(), Backend: "postgres"}
+	if opts.IncludeEmbeddings {
+		header.EmbeddingModel = opts.EmbeddingModel
+		header.EmbeddingDimensions = storage.EmbeddingDimensions
+	}
+
+	writer, err := NewWriter(w, header)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ExportStats{}
+	externalIDs := make(map[int64]string)
+	err = store.ExportMemories(opts.GroupID, opts.IncludeEmbeddings, func(memory storage.Memory) error {
+		externalIDs[memory.ID] = memory.ExternalID
+		stats.Memories++
+		return writer.WriteMemory(MemoryRecord{
+			ExternalID: memory.ExternalID,
+			Text:       memory.Text,
+			GroupID:    memory.GroupID,
+			Tags:       memory.Tags,
+			Source:     memory.Source,
+			Importance: &memory.Importance,
+			Attributes: memory.Attributes,
+			CreatedAt:  memory.CreatedAt,
+			UpdatedAt:  memory.UpdatedAt,
+			ExpiresAt:  memory.ExpiresAt,
+			Embedding:  memory.Embedding,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.ExportRelationships(func(rel storage.Relationship) error {
+		// Skip edges leaving the exported set (group exports)
+		from, fromOK := externalIDs[rel.FromID]
+		to, toOK := externalIDs[rel.ToID]
+		if !fromOK || !toOK {
+			return nil
+		}
+		stats.Edges++
+		return writer.WriteEdge(EdgeRecord{
+			From:             from,
+			To:               to,
+			RelationshipType: rel.Type,
+			Properties:       rel.Properties,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ImportStats counts what Import did
+type ImportStats struct {
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Reembedded   int `json:"reembedded"` // Memories whose embedding was regenerated
+	Edges        int `json:"edges"`
+	SkippedEdges int `json:"skipped_edges"` // Edges whose endpoints don't exist
+}
+
+// Import reads an export and upserts its memories by external ID, then
+// merges its edges. Embeddings are regenerated with embedder when the file
+// has none, or was produced by a different model or dimension. The returned
+// stats are never nil and count what was written before any error.
+func Import(store *storage.PostgresStore, embedder *embeddings.Client, r io.Reader) (*ImportStats, error) {
+	stats := &ImportStats{}
+	reader, err := NewReader(r)
+	if err != nil {
+		return stats, err
+	}
+
+	reuseEmbeddings := reader.Header.EmbeddingModel == embedder.Model() &&
+		reader.Header.EmbeddingDimensions == storage.EmbeddingDimensions
+
+	ids := make(map[string]int64)
+	var edges []*EdgeRecord
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		switch record := record.(type) {
+		case *EdgeRecord:
+			if err := validateEdge(record); err != nil {
+				return stats, err
+			}
+			// Edges may reference memories later in the file
+			edges = append(edges, record)
+		case *MemoryRecord:
+			embedding := record.Embedding
+			if !reuseEmbeddings || len(embedding) != storage.EmbeddingDimensions {
+				if embedding, err = embedder.Generate(record.Text); err != nil {
+					return stats, fmt.Errorf("failed to embed memory %s: %w", record.ExternalID, err)
+				}
+				stats.Reembedded++
+			}
+
+			importance := storage.DefaultImportance
+			if record.Importance != nil {
+				importance = *record.Importance
+			}
+
+			id, created, err := store.ImportMemory(storage.Memory{
+				ExternalID: record.ExternalID,
+				Text:       record.Text,
+				Embedding:  embedding,
+				GroupID:    record.GroupID,
+				Tags:       record.Tags,
+				Source:     record.Source,
+				Importance: importance,
+				Attributes: record.Attributes,
+				CreatedAt:  record.CreatedAt,
+				UpdatedAt:  record.UpdatedAt,
+				ExpiresAt:  record.ExpiresAt,
+			})
+			if err != nil {
+				return stats, err
+			}
+			ids[record.ExternalID] = id
+			if created {
+				stats.Created++
+			} else {
+				stats.Updated++
+			}
+		}
+	}
+
+	// Edge endpoints outside the file may already exist in the store
+	var unknown []string
+	for _, edge := range edges {
+		for _, externalID := range []string{edge.From, edge.To} {
+			if _, ok := ids[externalID]; !ok {
+				unknown = append(unknown, externalID)
+			}
+		}
+	}
+	existing, err := store.MemoryIDsByExternalID(unknown)
+	if err != nil {
+		return stats, err
+	}
+	for externalID, id := range existing {
+		ids[externalID] = id
+	}
+
+	for _, edge := range edges {
+		fromID, fromOK := ids[edge.From]
+		toID, toOK := ids[edge.To]
+		if !fromOK || !toOK {
+			stats.SkippedEdges++
+			continue
+		}
+
+		if _, err := store.AddRelationship(fromID, toID, edge.RelationshipType, edge.Properties); err != nil {
+			return stats, fmt.Errorf("failed to import edge %s -> %s: %w", edge.From, edge.To, err)
+		}
+		stats.Edges++
+	}
+
+	return stats, nil
+}
+
+// validateEdge rejects relationship types and property keys that aren't plain graph names
+func validateEdge(edge *EdgeRecord) error {
+	if !graphNamePattern.MatchString(edge.RelationshipType) {
+		return fmt.Errorf("edge %s -> %s: invalid relationship type %q", edge.From, edge.To, edge.RelationshipType)
+	}
+	for key := range edge.Properties {
+		if !graphNamePattern.MatchString(key) {
+			return fmt.Errorf("edge %s -> %s: invalid property name %q", edge.From, edge.To, key)
+		}
+	}
+	return nil
+}
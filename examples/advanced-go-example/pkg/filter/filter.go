@@ -0,0 +1,292 @@
+// Package filter implements a small JSON filter language for memories.
+//
+// A filter is a tree of AND/OR/NOT nodes over field conditions:
+//
+//	{"and": [
+//	  {"field": "created_at", "op": "gte", "value": "-7d"},
+//	  {"field": "tags", "op": "contains", "value": "deploy"},
+//	  {"not": {"field": "group_id", "op": "eq", "value": "scratch"}}
+//	]}
+//
+// The same expression can be compiled to parameterized SQL (Postgres or
+// SQLite) or evaluated in Go against a Record.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-go-example/pkg/ontology"
+)
+
+// Expr is a node in a filter expression. Exactly one of And, Or, Not or
+// Field must be set.
+type Expr struct {
+	And []Expr `json:"and,omitempty"`
+	Or  []Expr `json:"or,omitempty"`
+	Not *Expr  `json:"not,omitempty"`
+
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Supported fields. Attribute fields are written as "attributes.<key>".
+const (
+	FieldGroupID      = "group_id"
+	FieldSource       = "source"
+	FieldImportance   = "importance"
+	FieldCreatedAt    = "created_at"
+	FieldUpdatedAt    = "updated_at"
+	FieldTags         = "tags"
+	FieldRelationship = "relationship"
+
+	attributePrefix = "attributes."
+)
+
+// Supported operators
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpIn       = "in"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpContains = "contains"
+	OpAny      = "any"
+	OpAll      = "all"
+	OpExists   = "exists"
+)
+
+// maxDepth bounds nesting so a hostile filter can't blow up query size
+const maxDepth = 16
+
+// fieldOps lists the operators each field accepts
+var fieldOps = map[string][]string{
+	FieldGroupID:      {OpEq, OpNe, OpIn},
+	FieldSource:       {OpEq, OpNe, OpIn},
+	FieldImportance:   {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte},
+	FieldCreatedAt:    {OpGt, OpGte, OpLt, OpLte},
+	FieldUpdatedAt:    {OpGt, OpGte, OpLt, OpLte},
+	FieldTags:         {OpContains, OpAny, OpAll},
+	FieldRelationship: {OpExists},
+}
+
+// attributeOps lists the operators accepted by attributes.<key> fields
+var attributeOps = []string{OpEq, OpNe}
+
+// Parse decodes and validates a JSON filter expression
+func Parse(data []byte) (*Expr, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var expr Expr
+	if err := decoder.Decode(&expr); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if err := expr.Validate(); err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// Validate checks that the expression is well formed
+func (e *Expr) Validate() error {
+	return e.validate("filter", 0)
+}
+
+func (e *Expr) validate(path string, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%s: filter nested deeper than %d levels", path, maxDepth)
+	}
+
+	set := 0
+	if e.And != nil {
+		set++
+	}
+	if e.Or != nil {
+		set++
+	}
+	if e.Not != nil {
+		set++
+	}
+	if e.Field != "" {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("%s: exactly one of and, or, not, field must be set", path)
+	}
+
+	switch {
+	case e.And != nil:
+		return validateList(e.And, path+".and", depth)
+	case e.Or != nil:
+		return validateList(e.Or, path+".or", depth)
+	case e.Not != nil:
+		return e.Not.validate(path+".not", depth+1)
+	}
+
+	ops, ok := fieldOps[e.Field]
+	if strings.HasPrefix(e.Field, attributePrefix) && len(e.Field) > len(attributePrefix) {
+		ops, ok = attributeOps, true
+	}
+	if !ok {
+		return fmt.Errorf("%s: unknown field %q", path, e.Field)
+	}
+	if !slices.Contains(ops, e.Op) {
+		return fmt.Errorf("%s: field %q does not support op %q (supported: %s)", path, e.Field, e.Op, strings.Join(ops, ", "))
+	}
+
+	if err := e.validateValue(); err != nil {
+		return fmt.Errorf("%s: %s %s: %w", path, e.Field, e.Op, err)
+	}
+	return nil
+}
+
+func validateList(exprs []Expr, path string, depth int) error {
+	if len(exprs) == 0 {
+		return fmt.Errorf("%s: list must not be empty", path)
+	}
+	for i := range exprs {
+		if err := exprs[i].validate(fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValue checks the value type expected by the field and operator
+func (e *Expr) validateValue() error {
+	switch {
+	case e.Field == FieldImportance:
+		if _, ok := e.Value.(float64); !ok {
+			return fmt.Errorf("value must be a number")
+		}
+	case e.Field == FieldCreatedAt || e.Field == FieldUpdatedAt:
+		if _, err := e.timeValue(time.Now()); err != nil {
+			return err
+		}
+	case e.Field == FieldRelationship:
+		if e.Value == nil {
+			return nil // Any relationship type
+		}
+		relType, ok := e.Value.(string)
+		if !ok || !ontology.ValidName(relType) {
+			return fmt.Errorf("value must be a relationship type name or omitted")
+		}
+	case strings.HasPrefix(e.Field, attributePrefix):
+		switch e.Value.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("value must be a string, number or boolean")
+		}
+	case e.Op == OpIn || e.Op == OpAny || e.Op == OpAll:
+		values, err := e.stringList()
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("value must be a non-empty list of strings")
+		}
+	default:
+		if _, ok := e.Value.(string); !ok {
+			return fmt.Errorf("value must be a string")
+		}
+	}
+	return nil
+}
+
+// stringList returns the value as a list of strings
+func (e *Expr) stringList() ([]string, error) {
+	switch v := e.Value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("value must be a list of strings")
+			}
+			values[i] = s
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("value must be a list of strings")
+}
+
+// timeValue resolves a time value: RFC 3339, a YYYY-MM-DD date, or a
+// duration relative to now such as "-7d", "-36h" or "-2w"
+func (e *Expr) timeValue(now time.Time) (time.Time, error) {
+	s, ok := e.Value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("value must be a timestamp string")
+	}
+
+	if strings.HasPrefix(s, "-") {
+		duration, err := parseRelative(s[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-duration), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("value %q must be RFC 3339, YYYY-MM-DD or relative like -7d", s)
+}
+
+// parseRelative extends time.ParseDuration with day (d) and week (w) units
+func parseRelative(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid relative time %q", "-"+s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid relative time %q", "-"+s)
+	}
+	return duration, nil
+}
+
+// UsesRelationships reports whether any condition needs graph data
+func (e *Expr) UsesRelationships() bool {
+	return e.UsesField(FieldRelationship)
+}
+
+// UsesField reports whether any condition tests field, for callers whose
+// schema lacks some of the supported fields
+func (e *Expr) UsesField(field string) bool {
+	if e == nil {
+		return false
+	}
+	if e.Field == field {
+		return true
+	}
+	if e.Not != nil && e.Not.UsesField(field) {
+		return true
+	}
+	for _, list := range [][]Expr{e.And, e.Or} {
+		for i := range list {
+			if list[i].UsesField(field) {
+				return true
+			}
+		}
+	}
+	return false
+}
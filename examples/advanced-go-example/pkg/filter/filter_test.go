@@ -0,0 +1,335 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr string // Empty when the filter is valid
+	}{
+		{name: "string field", filter: `{"field": "group_id", "op": "eq", "value": "work"}`},
+		{name: "string list", filter: `{"field": "source", "op": "in", "value": ["slack", "email"]}`},
+		{name: "number", filter: `{"field": "importance", "op": "gte", "value": 0.5}`},
+		{name: "relative time", filter: `{"field": "created_at", "op": "gte", "value": "-7d"}`},
+		{name: "date", filter: `{"field": "updated_at", "op": "lt", "value": "2025-01-31"}`},
+		{name: "attribute", filter: `{"field": "attributes.status", "op": "ne", "value": true}`},
+		{name: "any relationship", filter: `{"field": "relationship", "op": "exists"}`},
+		{name: "nested", filter: `{"and": [{"not": {"field": "tags", "op": "contains", "value": "x"}}, {"or": [{"field": "tags", "op": "any", "value": ["a"]}]}]}`},
+
+		{name: "empty input", filter: ``, wantErr: "invalid filter"},
+		{name: "empty object", filter: `{}`, wantErr: "exactly one of"},
+		{name: "two kinds set", filter: `{"and": [], "field": "tags"}`, wantErr: "exactly one of"},
+		{name: "empty list", filter: `{"or": []}`, wantErr: "filter.or: list must not be empty"},
+		{name: "unknown key", filter: `{"field": "tags", "op": "any", "value": ["a"], "extra": 1}`, wantErr: "unknown field \"extra\""},
+		{name: "unknown field", filter: `{"field": "text", "op": "eq", "value": "a"}`, wantErr: "unknown field \"text\""},
+		{name: "bare attribute prefix", filter: `{"field": "attributes.", "op": "eq", "value": "a"}`, wantErr: "unknown field"},
+		{name: "unsupported op", filter: `{"field": "tags", "op": "eq", "value": "a"}`, wantErr: "does not support op \"eq\""},
+		{name: "number as string", filter: `{"field": "importance", "op": "gt", "value": "0.5"}`, wantErr: "value must be a number"},
+		{name: "empty string list", filter: `{"field": "group_id", "op": "in", "value": []}`, wantErr: "non-empty list"},
+		{name: "mixed list", filter: `{"field": "tags", "op": "all", "value": ["a", 1]}`, wantErr: "list of strings"},
+		{name: "bad time", filter: `{"field": "created_at", "op": "gt", "value": "yesterday"}`, wantErr: "must be RFC 3339"},
+		{name: "bad relative time", filter: `{"field": "created_at", "op": "gt", "value": "-xd"}`, wantErr: "invalid relative time"},
+		{name: "attribute object", filter: `{"field": "attributes.a", "op": "eq", "value": {"b": 1}}`, wantErr: "string, number or boolean"},
+		{name: "cypher injection", filter: `{"field": "relationship", "op": "exists", "value": "X]-() DETACH DELETE m //"}`, wantErr: "relationship type name"},
+		{name: "error path", filter: `{"and": [{"field": "tags", "op": "any", "value": ["a"]}, {"not": {"field": "x"}}]}`, wantErr: "filter.and[1].not: unknown field"},
+		{name: "too deep", filter: strings.Repeat(`{"not": `, maxDepth+1) + `{"field": "relationship", "op": "exists"}` + strings.Repeat(`}`, maxDepth+1), wantErr: "nested deeper than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.filter))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Parse() error = %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Parse() succeeded, want an error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		filter   string
+		args     []interface{} // Bound by the caller before the filter
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "eq",
+			filter:   `{"field": "group_id", "op": "eq", "value": "work"}`,
+			wantSQL:  "group_id = $1",
+			wantArgs: []interface{}{"work"},
+		},
+		{
+			name:     "placeholders follow caller args",
+			filter:   `{"field": "source", "op": "ne", "value": "slack"}`,
+			args:     []interface{}{"query", 10},
+			wantSQL:  "source IS DISTINCT FROM $3",
+			wantArgs: []interface{}{"query", 10, "slack"},
+		},
+		{
+			name:     "in",
+			filter:   `{"field": "group_id", "op": "in", "value": ["a", "b"]}`,
+			wantSQL:  "group_id IN ($1, $2)",
+			wantArgs: []interface{}{"a", "b"},
+		},
+		{
+			name:     "importance",
+			filter:   `{"or": [{"field": "importance", "op": "lt", "value": 0.2}, {"field": "importance", "op": "ne", "value": 1}]}`,
+			wantSQL:  "(importance < $1 OR importance <> $2)",
+			wantArgs: []interface{}{0.2, 1.0},
+		},
+		{
+			name:     "tags",
+			filter:   `{"and": [{"field": "tags", "op": "contains", "value": "x"}, {"field": "tags", "op": "any", "value": ["a", "b"]}, {"field": "tags", "op": "all", "value": ["c"]}]}`,
+			wantSQL:  "(tags @> ARRAY[$1::text] AND tags && ARRAY[$2::text, $3::text] AND tags @> ARRAY[$4::text])",
+			wantArgs: []interface{}{"x", "a", "b", "c"},
+		},
+		{
+			name:     "attribute quotes and newlines",
+			filter:   `{"field": "attributes.say \"hi\"", "op": "eq", "value": "line\nbreak"}`,
+			wantSQL:  "attributes @> $1::jsonb",
+			wantArgs: []interface{}{`{"say \"hi\"":"line\nbreak"}`},
+		},
+		{
+			name:     "attribute ne",
+			filter:   `{"field": "attributes.done", "op": "ne", "value": true}`,
+			wantSQL:  "NOT (attributes @> $1::jsonb)",
+			wantArgs: []interface{}{`{"done":true}`},
+		},
+		{
+			name:    "any relationship",
+			filter:  `{"field": "relationship", "op": "exists"}`,
+			wantSQL: "id IN (SELECT connected_id::text::bigint FROM ag_catalog.cypher('memory_graph', $$ MATCH (m:Memory)-[]-() RETURN DISTINCT m.id $$) AS (connected_id ag_catalog.agtype))",
+		},
+		{
+			name:    "typed relationship",
+			filter:  `{"not": {"field": "relationship", "op": "exists", "value": "DEPENDS_ON"}}`,
+			wantSQL: "NOT (id IN (SELECT connected_id::text::bigint FROM ag_catalog.cypher('memory_graph', $$ MATCH (m:Memory)-[:DEPENDS_ON]-() RETURN DISTINCT m.id $$) AS (connected_id ag_catalog.agtype)))",
+		},
+		{
+			name:     "sqlite eq",
+			dialect:  SQLite,
+			filter:   `{"field": "group_id", "op": "eq", "value": "work"}`,
+			wantSQL:  "group_id = ?",
+			wantArgs: []interface{}{"work"},
+		},
+		{
+			name:     "sqlite ne is null-safe",
+			dialect:  SQLite,
+			filter:   `{"field": "source", "op": "ne", "value": "slack"}`,
+			args:     []interface{}{"query"},
+			wantSQL:  "source IS NOT ?",
+			wantArgs: []interface{}{"query", "slack"},
+		},
+		{
+			name:     "sqlite tags",
+			dialect:  SQLite,
+			filter:   `{"or": [{"field": "tags", "op": "any", "value": ["a", "b"]}, {"field": "tags", "op": "all", "value": ["c", "d"]}]}`,
+			wantSQL:  "(EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value IN (?, ?)) OR (EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value = ?) AND EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value = ?)))",
+			wantArgs: []interface{}{"a", "b", "c", "d"},
+		},
+		{
+			name:     "sqlite time",
+			dialect:  SQLite,
+			filter:   `{"field": "created_at", "op": "gte", "value": "2025-03-01T10:00:00+02:00"}`,
+			wantSQL:  "julianday(created_at) >= julianday(?)",
+			wantArgs: []interface{}{"2025-03-01 08:00:00"},
+		},
+		{
+			name:     "sqlite attribute key is quoted",
+			dialect:  SQLite,
+			filter:   `{"field": "attributes.say \"hi\".x", "op": "ne", "value": 2}`,
+			wantSQL:  "json_extract(attributes, ?) IS NOT ?",
+			wantArgs: []interface{}{`$."say \"hi\".x"`, 2.0},
+		},
+		{
+			name:    "sqlite any relationship",
+			dialect: SQLite,
+			filter:  `{"field": "relationship", "op": "exists"}`,
+			wantSQL: "EXISTS (SELECT 1 FROM relationships r WHERE (r.from_id = memories.id OR r.to_id = memories.id))",
+		},
+		{
+			name:     "sqlite typed relationship",
+			dialect:  SQLite,
+			filter:   `{"field": "relationship", "op": "exists", "value": "DEPENDS_ON"}`,
+			wantSQL:  "EXISTS (SELECT 1 FROM relationships r WHERE (r.from_id = memories.id OR r.to_id = memories.id) AND r.type = ?)",
+			wantArgs: []interface{}{"DEPENDS_ON"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse([]byte(tt.filter))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			sql, args, err := Compile(expr, tt.dialect, tt.args)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Compile() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if !equalArgs(args, tt.wantArgs) {
+				t.Errorf("Compile() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantSQL string
+		want    time.Time
+	}{
+		{"timestamp", `{"field": "created_at", "op": "gte", "value": "2025-03-01T10:00:00+02:00"}`, "created_at >= $1", time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{"date", `{"field": "updated_at", "op": "lt", "value": "2025-03-01"}`, "updated_at < $1", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"relative", `{"field": "created_at", "op": "gt", "value": "-2w"}`, "created_at > $1", time.Now().Add(-14 * 24 * time.Hour)},
+		{"fractional days", `{"field": "created_at", "op": "gt", "value": "-1.5d"}`, "created_at > $1", time.Now().Add(-36 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse([]byte(tt.filter))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			sql, args, err := Compile(expr, Postgres, nil)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Compile() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != 1 {
+				t.Fatalf("Compile() bound %d args, want 1", len(args))
+			}
+			bound, ok := args[0].(time.Time)
+			if !ok {
+				t.Fatalf("Compile() bound %T, want time.Time", args[0])
+			}
+			// Relative values depend on when Compile ran
+			if diff := bound.Sub(tt.want).Abs(); diff > time.Minute {
+				t.Errorf("Compile() bound %s, want %s", bound, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	record := Record{
+		GroupID:           "work",
+		Importance:        0.7,
+		Tags:              []string{"go", "db"},
+		Attributes:        map[string]interface{}{"status": "open", "priority": 2.0, "done": false},
+		CreatedAt:         now.Add(-3 * 24 * time.Hour),
+		UpdatedAt:         now.Add(-time.Hour),
+		RelationshipTypes: []string{"DEPENDS_ON"},
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{"eq", `{"field": "group_id", "op": "eq", "value": "work"}`, true},
+		{"ne", `{"field": "group_id", "op": "ne", "value": "work"}`, false},
+		{"in", `{"field": "group_id", "op": "in", "value": ["home", "work"]}`, true},
+		{"missing source is empty", `{"field": "source", "op": "ne", "value": "slack"}`, true},
+		{"importance", `{"field": "importance", "op": "gte", "value": 0.7}`, true},
+		{"importance below", `{"field": "importance", "op": "lt", "value": 0.5}`, false},
+		{"relative time", `{"field": "created_at", "op": "gte", "value": "-7d"}`, true},
+		{"relative time too recent", `{"field": "created_at", "op": "gte", "value": "-2d"}`, false},
+		{"absolute time", `{"field": "updated_at", "op": "gt", "value": "2025-03-10"}`, true},
+		{"tag contains", `{"field": "tags", "op": "contains", "value": "go"}`, true},
+		{"tags any", `{"field": "tags", "op": "any", "value": ["rust", "db"]}`, true},
+		{"tags all", `{"field": "tags", "op": "all", "value": ["go", "rust"]}`, false},
+		{"attribute string", `{"field": "attributes.status", "op": "eq", "value": "open"}`, true},
+		{"attribute number", `{"field": "attributes.priority", "op": "eq", "value": 2}`, true},
+		{"attribute bool", `{"field": "attributes.done", "op": "eq", "value": false}`, true},
+		{"missing attribute ne", `{"field": "attributes.owner", "op": "ne", "value": "me"}`, true},
+		{"any relationship", `{"field": "relationship", "op": "exists"}`, true},
+		{"typed relationship", `{"field": "relationship", "op": "exists", "value": "SOLVES"}`, false},
+		{"and", `{"and": [{"field": "tags", "op": "contains", "value": "go"}, {"field": "importance", "op": "gt", "value": 0.9}]}`, false},
+		{"or", `{"or": [{"field": "tags", "op": "contains", "value": "x"}, {"field": "importance", "op": "gt", "value": 0.5}]}`, true},
+		{"not", `{"not": {"field": "tags", "op": "contains", "value": "go"}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse([]byte(tt.filter))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := expr.Match(record, now); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesRelationships(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`{"field": "tags", "op": "contains", "value": "x"}`, false},
+		{`{"field": "relationship", "op": "exists"}`, true},
+		{`{"or": [{"field": "tags", "op": "contains", "value": "x"}, {"not": {"field": "relationship", "op": "exists"}}]}`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Parse([]byte(tt.filter))
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.filter, err)
+		}
+		if got := expr.UsesRelationships(); got != tt.want {
+			t.Errorf("UsesRelationships(%s) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+	if (*Expr)(nil).UsesRelationships() {
+		t.Errorf("UsesRelationships() on a nil filter = true, want false")
+	}
+}
+
+func TestUsesField(t *testing.T) {
+	expr, err := Parse([]byte(`{"and": [{"field": "tags", "op": "contains", "value": "x"}, {"not": {"or": [{"field": "updated_at", "op": "gt", "value": "-1d"}]}}]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		{FieldTags, true},
+		{FieldUpdatedAt, true},
+		{FieldCreatedAt, false},
+		{"attributes.x", false},
+	}
+	for _, tt := range tests {
+		if got := expr.UsesField(tt.field); got != tt.want {
+			t.Errorf("UsesField(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func equalArgs(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
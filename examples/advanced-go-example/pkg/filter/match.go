@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Record is the view of a memory that Match evaluates against
+type Record struct {
+	GroupID           string
+	Source            string
+	Importance        float64
+	Tags              []string
+	Attributes        map[string]interface{}
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	RelationshipTypes []string // Types of the edges touching the memory, if known
+}
+
+// Match evaluates a validated expression against a record in Go, for callers
+// that filter memories already loaded into memory. now anchors relative times.
+func (e *Expr) Match(r Record, now time.Time) bool {
+	switch {
+	case e.And != nil:
+		for i := range e.And {
+			if !e.And[i].Match(r, now) {
+				return false
+			}
+		}
+		return true
+	case e.Or != nil:
+		for i := range e.Or {
+			if e.Or[i].Match(r, now) {
+				return true
+			}
+		}
+		return false
+	case e.Not != nil:
+		return !e.Not.Match(r, now)
+	}
+
+	switch e.Field {
+	case FieldGroupID:
+		return e.matchString(r.GroupID)
+	case FieldSource:
+		return e.matchString(r.Source)
+	case FieldImportance:
+		return compareOrdered(e.Op, r.Importance, e.Value.(float64))
+	case FieldCreatedAt, FieldUpdatedAt:
+		value := r.CreatedAt
+		if e.Field == FieldUpdatedAt {
+			value = r.UpdatedAt
+		}
+		bound, _ := e.timeValue(now)
+		return compareOrdered(e.Op, value.UnixNano(), bound.UnixNano())
+	case FieldTags:
+		return e.matchTags(r.Tags)
+	case FieldRelationship:
+		if e.Value == nil {
+			return len(r.RelationshipTypes) > 0
+		}
+		return slices.Contains(r.RelationshipTypes, e.Value.(string))
+	}
+
+	// attributes.<key>
+	value, ok := r.Attributes[strings.TrimPrefix(e.Field, attributePrefix)]
+	equal := ok && value == e.Value
+	if e.Op == OpNe {
+		return !equal
+	}
+	return equal
+}
+
+func (e *Expr) matchString(value string) bool {
+	switch e.Op {
+	case OpEq:
+		return value == e.Value.(string)
+	case OpNe:
+		return value != e.Value.(string)
+	}
+	values, _ := e.stringList()
+	return slices.Contains(values, value)
+}
+
+func (e *Expr) matchTags(tags []string) bool {
+	if e.Op == OpContains {
+		return slices.Contains(tags, e.Value.(string))
+	}
+
+	values, _ := e.stringList()
+	for _, value := range values {
+		found := slices.Contains(tags, value)
+		if e.Op == OpAny && found {
+			return true
+		}
+		if e.Op == OpAll && !found {
+			return false
+		}
+	}
+	return e.Op == OpAll
+}
+
+// compareOrdered applies a comparison operator to two ordered values
+func compareOrdered[T int64 | float64](op string, value, bound T) bool {
+	switch op {
+	case OpEq:
+		return value == bound
+	case OpNe:
+		return value != bound
+	case OpGt:
+		return value > bound
+	case OpGte:
+		return value >= bound
+	case OpLt:
+		return value < bound
+	case OpLte:
+		return value <= bound
+	}
+	return false
+}
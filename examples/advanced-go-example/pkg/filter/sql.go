@@ -0,0 +1,208 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Dialect selects the SQL flavour produced by Compile
+type Dialect int
+
+const (
+	// Postgres targets the pgvector schema: TEXT[] tags, JSONB attributes and
+	// relationships stored in the Apache AGE graph 'memory_graph'
+	Postgres Dialect = iota
+
+	// SQLite targets JSON text tags and attributes, with relationships stored
+	// in a relationships(from_id, to_id, type) edge table
+	SQLite
+)
+
+// sqlOps maps comparison operators to SQL
+var sqlOps = map[string]string{
+	OpEq:  "=",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+// Compile translates a validated expression into a parameterized SQL
+// condition over the memories table. Placeholders are numbered after the
+// args already bound by the caller; the returned slice holds those args
+// followed by the filter's own values.
+func Compile(e *Expr, dialect Dialect, args []interface{}) (string, []interface{}, error) {
+	c := &compiler{dialect: dialect, args: args, now: time.Now()}
+	sql, err := c.compile(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, c.args, nil
+}
+
+// compiler holds the state of a single Compile call
+type compiler struct {
+	dialect Dialect
+	args    []interface{}
+	now     time.Time
+}
+
+// bind appends a value and returns its placeholder
+func (c *compiler) bind(value interface{}) string {
+	c.args = append(c.args, value)
+	if c.dialect == Postgres {
+		return fmt.Sprintf("$%d", len(c.args))
+	}
+	return "?"
+}
+
+// bindList binds each value and returns the comma-separated placeholders
+func (c *compiler) bindList(values []string, cast string) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = c.bind(value) + cast
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+func (c *compiler) compile(e *Expr) (string, error) {
+	switch {
+	case e.And != nil:
+		return c.compileList(e.And, " AND ")
+	case e.Or != nil:
+		return c.compileList(e.Or, " OR ")
+	case e.Not != nil:
+		inner, err := c.compile(e.Not)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	}
+
+	switch e.Field {
+	case FieldGroupID, FieldSource:
+		return c.compileString(e), nil
+	case FieldImportance:
+		if e.Op == OpNe {
+			return "importance <> " + c.bind(e.Value), nil
+		}
+		return fmt.Sprintf("importance %s %s", sqlOps[e.Op], c.bind(e.Value)), nil
+	case FieldCreatedAt, FieldUpdatedAt:
+		bound, err := e.timeValue(c.now)
+		if err != nil {
+			return "", err
+		}
+		if c.dialect == Postgres {
+			return fmt.Sprintf("%s %s %s", e.Field, sqlOps[e.Op], c.bind(bound)), nil
+		}
+		// SQLite stores CURRENT_TIMESTAMP text in UTC; compare as Julian days
+		return fmt.Sprintf("julianday(%s) %s julianday(%s)", e.Field, sqlOps[e.Op], c.bind(bound.UTC().Format(time.DateTime))), nil
+	case FieldTags:
+		return c.compileTags(e)
+	case FieldRelationship:
+		return c.compileRelationship(e), nil
+	}
+
+	return c.compileAttribute(e)
+}
+
+func (c *compiler) compileList(exprs []Expr, separator string) (string, error) {
+	parts := make([]string, len(exprs))
+	for i := range exprs {
+		part, err := c.compile(&exprs[i])
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return "(" + strings.Join(parts, separator) + ")", nil
+}
+
+func (c *compiler) compileString(e *Expr) string {
+	switch e.Op {
+	case OpEq:
+		return e.Field + " = " + c.bind(e.Value)
+	case OpNe:
+		// NULL-safe inequality so memories without a value still match
+		if c.dialect == Postgres {
+			return e.Field + " IS DISTINCT FROM " + c.bind(e.Value)
+		}
+		return e.Field + " IS NOT " + c.bind(e.Value)
+	}
+	values, _ := e.stringList()
+	return fmt.Sprintf("%s IN (%s)", e.Field, c.bindList(values, ""))
+}
+
+func (c *compiler) compileTags(e *Expr) (string, error) {
+	values := []string{}
+	if e.Op == OpContains {
+		values = append(values, e.Value.(string))
+	} else {
+		values, _ = e.stringList()
+	}
+
+	if c.dialect == Postgres {
+		// && (overlap) and @> (contains) can both use the GIN index on tags
+		operator := "@>"
+		if e.Op == OpAny {
+			operator = "&&"
+		}
+		return fmt.Sprintf("tags %s ARRAY[%s]", operator, c.bindList(values, "::text")), nil
+	}
+
+	if e.Op == OpAny {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value IN (%s))", c.bindList(values, "")), nil
+	}
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = "EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value = " + c.bind(value) + ")"
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", nil
+}
+
+func (c *compiler) compileAttribute(e *Expr) (string, error) {
+	key := strings.TrimPrefix(e.Field, attributePrefix)
+
+	if c.dialect == Postgres {
+		// JSONB containment of {"key": value} can use the GIN index on attributes
+		containment, err := json.Marshal(map[string]interface{}{key: e.Value})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode attribute filter: %w", err)
+		}
+		condition := "attributes @> " + c.bind(string(containment)) + "::jsonb"
+		if e.Op == OpNe {
+			condition = "NOT (" + condition + ")"
+		}
+		return condition, nil
+	}
+
+	// Quote the key so dots and spaces aren't treated as JSON path syntax
+	path := c.bind(`$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`)
+	if e.Op == OpNe {
+		return fmt.Sprintf("json_extract(attributes, %s) IS NOT %s", path, c.bind(e.Value)), nil
+	}
+	return fmt.Sprintf("json_extract(attributes, %s) = %s", path, c.bind(e.Value)), nil
+}
+
+// compileRelationship matches memories with at least one edge (of the given type) in either direction
+func (c *compiler) compileRelationship(e *Expr) string {
+	relType, _ := e.Value.(string)
+
+	if c.dialect == Postgres {
+		// The type was validated as a plain label name, so it is safe to inline into Cypher
+		pattern := "-[]-"
+		if relType != "" {
+			pattern = "-[:" + relType + "]-"
+		}
+		return fmt.Sprintf("id IN (SELECT connected_id::text::bigint FROM ag_catalog.cypher('memory_graph', $$ "+
+			"MATCH (m:Memory)%s() RETURN DISTINCT m.id $$) AS (connected_id ag_catalog.agtype))", pattern)
+	}
+
+	condition := "EXISTS (SELECT 1 FROM relationships r WHERE (r.from_id = memories.id OR r.to_id = memories.id)"
+	if relType != "" {
+		condition += " AND r.type = " + c.bind(relType)
+	}
+	return condition + ")"
+}
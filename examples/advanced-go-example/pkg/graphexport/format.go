@@ -0,0 +1,318 @@
+package graphexport
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+)
+
+// Export formats
+const (
+	FormatDOT       = "dot"       // Graphviz
+	FormatGraphML   = "graphml"   // Gephi, yEd, Cytoscape desktop, NetworkX
+	FormatCytoscape = "cytoscape" // Cytoscape.js elements JSON
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatDOT, FormatGraphML, FormatCytoscape}
+
+// labelLength is how many characters of a memory's text make up its node label
+const labelLength = 40
+
+// FormatForPath guesses the export format from a file extension, returning
+// "" when the extension is not recognized
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		return FormatDOT
+	case ".graphml":
+		return FormatGraphML
+	case ".json", ".cyjs":
+		return FormatCytoscape
+	default:
+		return ""
+	}
+}
+
+// Write writes the graph in one of Formats
+func Write(w io.Writer, graph *Graph, format string) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(w, graph)
+	case FormatGraphML:
+		return WriteGraphML(w, graph)
+	case FormatCytoscape:
+		return WriteCytoscape(w, graph)
+	default:
+		return fmt.Errorf("format must be one of %s, got %q", strings.Join(Formats, ", "), format)
+	}
+}
+
+// attribute is a node or edge attribute shared by every format
+type attribute struct {
+	Name  string
+	Value interface{} // string, float64, int64 or bool
+}
+
+// attributeKey declares an attribute and its GraphML type
+type attributeKey struct {
+	Name string
+	Type string
+}
+
+// nodeKeys and edgeKeys list every attribute nodeAttributes and
+// edgeAttributes can produce, in output order
+var (
+	nodeKeys = []attributeKey{
+		{"label", "string"},
+		{"text", "string"},
+		{"group_id", "string"},
+		{"tags", "string"},
+		{"source", "string"},
+		{"importance", "double"},
+		{"cluster_id", "long"},
+		{"created_at", "string"},
+	}
+	edgeKeys = []attributeKey{
+		{"type", "string"},
+		{"confidence", "double"},
+		{"reason", "string"},
+		{"auto_detected", "boolean"},
+	}
+)
+
+// nodeAttributes returns the attributes of a memory that are set
+func nodeAttributes(memory storage.Memory) []attribute {
+	attributes := []attribute{
+		{"label", label(memory.Text)},
+		{"text", memory.Text},
+	}
+	if memory.GroupID != "" {
+		attributes = append(attributes, attribute{"group_id", memory.GroupID})
+	}
+	if len(memory.Tags) > 0 {
+		attributes = append(attributes, attribute{"tags", strings.Join(memory.Tags, ",")})
+	}
+	if memory.Source != "" {
+		attributes = append(attributes, attribute{"source", memory.Source})
+	}
+	attributes = append(attributes, attribute{"importance", memory.Importance})
+	if memory.ClusterID != 0 {
+		attributes = append(attributes, attribute{"cluster_id", memory.ClusterID})
+	}
+	return append(attributes, attribute{"created_at", memory.CreatedAt.UTC().Format(time.RFC3339)})
+}
+
+// edgeAttributes returns the attributes of an edge. Edges without a
+// confidence property, such as those added by hand, count as certain.
+func edgeAttributes(rel storage.Relationship) []attribute {
+	attributes := []attribute{
+		{"type", rel.Type},
+		{"confidence", storage.EdgeConfidence(rel)},
+	}
+	if reason, ok := rel.Properties["reason"].(string); ok && reason != "" {
+		attributes = append(attributes, attribute{"reason", reason})
+	}
+	if autoDetected, ok := rel.Properties["auto_detected"].(bool); ok {
+		attributes = append(attributes, attribute{"auto_detected", autoDetected})
+	}
+	return attributes
+}
+
+// label shortens text to a single line for display
+func label(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > labelLength {
+		return string(runes[:labelLength-1]) + "…"
+	}
+	return text
+}
+
+// formatValue renders an attribute value as text
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WriteDOT writes the graph as a Graphviz digraph. Node and edge attributes
+// Graphviz doesn't know are kept for other tools and ignored when rendering.
+func WriteDOT(w io.Writer, graph *Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph memories {")
+	fmt.Fprintln(bw, `  node [shape=box, style="rounded"];`)
+
+	for _, memory := range graph.Nodes {
+		attributes := nodeAttributes(memory)
+		// Show the full text on hover in SVG output
+		attributes = append(attributes, attribute{"tooltip", memory.Text})
+		fmt.Fprintf(bw, "  %d [%s];\n", memory.ID, dotAttributes(attributes))
+	}
+	for _, rel := range graph.Edges {
+		attributes := edgeAttributes(rel)
+		attributes = append(attributes,
+			attribute{"label", rel.Type},
+			attribute{"relationship_id", rel.ID},
+			attribute{"penwidth", 1 + 2*storage.EdgeConfidence(rel)},
+		)
+		fmt.Fprintf(bw, "  %d -> %d [%s];\n", rel.FromID, rel.ToID, dotAttributes(attributes))
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotAttributes renders an attribute list; every value is quoted, which DOT accepts for all types
+func dotAttributes(attributes []attribute) string {
+	parts := make([]string, len(attributes))
+	for i, attr := range attributes {
+		parts[i] = attr.Name + "=" + dotQuote(formatValue(attr.Value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// dotQuote quotes s as a DOT string. Newlines become \n, which Graphviz
+// renders as a centered line break.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as GraphML with typed node and edge attributes
+func WriteGraphML(w io.Writer, graph *Graph) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: "memories", EdgeDefault: "directed"},
+	}
+	// Node and edge keys live in one namespace, so they are prefixed
+	for _, key := range nodeKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "n_" + key.Name, For: "node", Name: key.Name, Type: key.Type})
+	}
+	for _, key := range edgeKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "e_" + key.Name, For: "edge", Name: key.Name, Type: key.Type})
+	}
+
+	for _, memory := range graph.Nodes {
+		node := graphMLNode{ID: nodeID(memory.ID)}
+		for _, attr := range nodeAttributes(memory) {
+			node.Data = append(node.Data, graphMLData{Key: "n_" + attr.Name, Value: formatValue(attr.Value)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, rel := range graph.Edges {
+		edge := graphMLEdge{ID: edgeID(rel.ID), Source: nodeID(rel.FromID), Target: nodeID(rel.ToID)}
+		for _, attr := range edgeAttributes(rel) {
+			edge.Data = append(edge.Data, graphMLData{Key: "e_" + attr.Name, Value: formatValue(attr.Value)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// cytoscapeElement is a Cytoscape.js node or edge
+type cytoscapeElement struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// WriteCytoscape writes the graph in the Cytoscape.js elements format, which
+// cy.add() and the Cytoscape desktop app (as .cyjs) load directly
+func WriteCytoscape(w io.Writer, graph *Graph) error {
+	elements := struct {
+		Nodes []cytoscapeElement `json:"nodes"`
+		Edges []cytoscapeElement `json:"edges"`
+	}{Nodes: []cytoscapeElement{}, Edges: []cytoscapeElement{}}
+
+	for _, memory := range graph.Nodes {
+		data := map[string]interface{}{"id": nodeID(memory.ID), "memory_id": memory.ID}
+		for _, attr := range nodeAttributes(memory) {
+			data[attr.Name] = attr.Value
+		}
+		elements.Nodes = append(elements.Nodes, cytoscapeElement{Data: data})
+	}
+	for _, rel := range graph.Edges {
+		data := map[string]interface{}{
+			"id":              edgeID(rel.ID),
+			"source":          nodeID(rel.FromID),
+			"target":          nodeID(rel.ToID),
+			"relationship_id": rel.ID,
+		}
+		for _, attr := range edgeAttributes(rel) {
+			data[attr.Name] = attr.Value
+		}
+		elements.Edges = append(elements.Edges, cytoscapeElement{Data: data})
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"elements": elements}); err != nil {
+		return fmt.Errorf("failed to write Cytoscape JSON: %w", err)
+	}
+	return nil
+}
+
+// Node and edge IDs are prefixed: Cytoscape.js and GraphML need them unique
+// across nodes and edges, and memory and edge IDs come from separate sequences
+func nodeID(id int64) string {
+	return "m" + strconv.FormatInt(id, 10)
+}
+
+func edgeID(id int64) string {
+	return "e" + strconv.FormatInt(id, 10)
+}
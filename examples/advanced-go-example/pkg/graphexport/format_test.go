@@ -0,0 +1,253 @@
+package graphexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+)
+
+// trickyText has every character the writers must escape
+const trickyText = "Say \"hi\" & <bye>\\n\r\nnext line\rlast\ttab"
+
+func testGraph() *Graph {
+	return &Graph{
+		Nodes: []storage.Memory{
+			{ID: 1, Text: trickyText, GroupID: "g\"1", Tags: []string{"a", "b"}, Importance: 0.5, CreatedAt: time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)},
+			{ID: 2, Text: "plain", Importance: 0, ClusterID: 7, CreatedAt: time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)},
+		},
+		Edges: []storage.Relationship{
+			{ID: 10, FromID: 1, ToID: 2, Type: "DEPENDS_ON", Properties: map[string]interface{}{"confidence": 0.25, "reason": "a \"quoted\"\nreason", "auto_detected": true}},
+			{ID: 11, FromID: 2, ToID: 1, Type: "RELATES_TO"},
+		},
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", `""`},
+		{"plain", "hello world", `"hello world"`},
+		{"double quote", `say "hi"`, `"say \"hi\""`},
+		{"backslash", `C:\dir`, `"C:\\dir"`},
+		{"escaped quote stays escaped", `\"`, `"\\\""`},
+		{"newline", "a\nb", `"a\nb"`},
+		{"crlf is one break", "a\r\nb", `"a\nb"`},
+		{"carriage return", "a\rb", `"a\nb"`},
+		{"literal backslash n", `a\nb`, `"a\\nb"`},
+		{"unicode", "naïve — ok", `"naïve — ok"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dotQuote(tt.in); got != tt.want {
+				t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"whitespace collapsed", "  a\n\tb  c ", "a b c"},
+		{"exactly the limit", strings.Repeat("x", labelLength), strings.Repeat("x", labelLength)},
+		{"truncated", strings.Repeat("x", labelLength+1), strings.Repeat("x", labelLength-1) + "…"},
+		{"truncated on runes", strings.Repeat("é", labelLength+5), strings.Repeat("é", labelLength-1) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := label(tt.in); got != tt.want {
+				t.Errorf("label(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, testGraph()); err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"digraph memories {\n",
+		`  1 [label="Say \"hi\" & <bye>\\n next line last tab", text="Say \"hi\" & <bye>\\n\nnext line\nlast` + "\t" + `tab", group_id="g\"1", tags="a,b", importance="0.5", created_at="2025-03-01T08:00:00Z", tooltip="Say \"hi\"`,
+		`  2 [label="plain", text="plain", importance="0", cluster_id="7", created_at="2025-03-02T08:00:00Z", tooltip="plain"];`,
+		`  1 -> 2 [type="DEPENDS_ON", confidence="0.25", reason="a \"quoted\"\nreason", auto_detected="true", label="DEPENDS_ON", relationship_id="10", penwidth="1.5"];`,
+		`  2 -> 1 [type="RELATES_TO", confidence="1", label="RELATES_TO", relationship_id="11", penwidth="3"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteDOT() output is missing %s\n%s", want, out)
+		}
+	}
+
+	// Raw newlines or stray quotes would leave a string open at the end of a line
+	for i, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !closesStrings(line) {
+			t.Errorf("line %d has an unterminated string: %s", i+1, line)
+		}
+	}
+}
+
+// closesStrings reports whether every DOT string opened on line is closed on it
+func closesStrings(line string) bool {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case inString && line[i] == '\\':
+			i++
+		case line[i] == '"':
+			inString = !inString
+		}
+	}
+	return !inString
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, testGraph()); err != nil {
+		t.Fatalf("WriteGraphML() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("WriteGraphML() output doesn't start with the XML header")
+	}
+	if strings.Contains(buf.String(), "<bye>") {
+		t.Errorf("WriteGraphML() left markup in text unescaped")
+	}
+
+	// Escaped values must read back exactly
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteGraphML() wrote invalid XML: %v", err)
+	}
+	if len(doc.Keys) != len(nodeKeys)+len(edgeKeys) {
+		t.Errorf("got %d keys, want %d", len(doc.Keys), len(nodeKeys)+len(edgeKeys))
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("got %d nodes and %d edges, want 2 and 2", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	tests := []struct {
+		name string
+		data []graphMLData
+		key  string
+		want string
+	}{
+		// \r is written as a character reference, so it survives end-of-line normalization
+		{"node text", doc.Graph.Nodes[0].Data, "n_text", trickyText},
+		{"node group", doc.Graph.Nodes[0].Data, "n_group_id", `g"1`},
+		{"node importance", doc.Graph.Nodes[0].Data, "n_importance", "0.5"},
+		{"zero importance", doc.Graph.Nodes[1].Data, "n_importance", "0"},
+		{"cluster", doc.Graph.Nodes[1].Data, "n_cluster_id", "7"},
+		{"edge reason", doc.Graph.Edges[0].Data, "e_reason", "a \"quoted\"\nreason"},
+		{"edge confidence", doc.Graph.Edges[0].Data, "e_confidence", "0.25"},
+		{"default confidence", doc.Graph.Edges[1].Data, "e_confidence", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, data := range tt.data {
+				if data.Key == tt.key {
+					if data.Value != tt.want {
+						t.Errorf("%s = %q, want %q", tt.key, data.Value, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("%s is missing", tt.key)
+		})
+	}
+
+	edge := doc.Graph.Edges[0]
+	if edge.ID != "e10" || edge.Source != "m1" || edge.Target != "m2" {
+		t.Errorf("edge = %s %s -> %s, want e10 m1 -> m2", edge.ID, edge.Source, edge.Target)
+	}
+}
+
+func TestWriteCytoscape(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCytoscape(&buf, testGraph()); err != nil {
+		t.Fatalf("WriteCytoscape() error = %v", err)
+	}
+
+	var doc struct {
+		Elements struct {
+			Nodes []cytoscapeElement `json:"nodes"`
+			Edges []cytoscapeElement `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteCytoscape() wrote invalid JSON: %v", err)
+	}
+	if got := doc.Elements.Nodes[0].Data["text"]; got != trickyText {
+		t.Errorf("text = %q, want %q", got, trickyText)
+	}
+	if got := doc.Elements.Edges[0].Data["source"]; got != "m1" {
+		t.Errorf("source = %v, want m1", got)
+	}
+}
+
+func TestWriteEmptyGraph(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatDOT, "digraph memories {\n  node [shape=box, style=\"rounded\"];\n}\n"},
+		{FormatCytoscape, `{"elements":{"nodes":[],"edges":[]}}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, &Graph{}, tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Write() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, &Graph{}, FormatGraphML); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("empty GraphML is invalid XML: %v", err)
+	}
+
+	if err := Write(&buf, &Graph{}, "svg"); err == nil {
+		t.Errorf("Write() with an unknown format succeeded")
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"graph.dot", FormatDOT},
+		{"graph.GV", FormatDOT},
+		{"out/graph.graphml", FormatGraphML},
+		{"graph.json", FormatCytoscape},
+		{"graph.cyjs", FormatCytoscape},
+		{"graph.svg", ""},
+		{"graph", ""},
+	}
+	for _, tt := range tests {
+		if got := FormatForPath(tt.path); got != tt.want {
+			t.Errorf("FormatForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package graphexport
+
+import (
+	"fmt"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+)
+
+// DefaultDepth is how many hops around Options.MemoryID are exported
+const DefaultDepth = 2
+
+// Options selects the part of the memory graph to export
+type Options struct {
+	MemoryID int64  // Export the neighbourhood of this memory (0 for the whole graph)
+	Depth    int    // Hops around MemoryID (default: 2)
+	GroupID  string // Only memories in this group, and the edges between them
+}
+
+// Graph is the exported memories and the edges between them
+type Graph struct {
+	Nodes []storage.Memory
+	Edges []storage.Relationship
+}
+
+// Load reads the selected part of the graph. Expired memories are left out.
+func Load(store *storage.PostgresStore, opts Options) (*Graph, error) {
+	if opts.MemoryID != 0 {
+		return loadNeighborhood(store, opts)
+	}
+
+	graph := &Graph{}
+	inScope := make(map[int64]bool)
+	now := time.Now()
+	err := store.ExportMemories(opts.GroupID, false, func(memory storage.Memory) error {
+		if !memory.ExpiresAt.IsZero() && !memory.ExpiresAt.After(now) {
+			return nil
+		}
+		inScope[memory.ID] = true
+		graph.Nodes = append(graph.Nodes, memory)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.ExportRelationships(func(rel storage.Relationship) error {
+		if inScope[rel.FromID] && inScope[rel.ToID] {
+			graph.Edges = append(graph.Edges, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// loadNeighborhood exports the memories within opts.Depth hops of
+// opts.MemoryID, following edges in either direction
+func loadNeighborhood(store *storage.PostgresStore, opts Options) (*Graph, error) {
+	if opts.Depth == 0 {
+		opts.Depth = DefaultDepth
+	}
+
+	subgraph, err := store.Traverse(opts.MemoryID, storage.TraversalOptions{MaxDepth: opts.Depth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subgraph: %w", err)
+	}
+	if len(subgraph.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: %d", storage.ErrMemoryNotFound, opts.MemoryID)
+	}
+
+	graph := &Graph{}
+	inScope := make(map[int64]bool)
+	for _, node := range subgraph.Nodes {
+		if opts.GroupID == "" || node.Memory.GroupID == opts.GroupID {
+			inScope[node.Memory.ID] = true
+			graph.Nodes = append(graph.Nodes, node.Memory)
+		}
+	}
+	for _, edge := range subgraph.Edges {
+		if inScope[edge.FromID] && inScope[edge.ToID] {
+			graph.Edges = append(graph.Edges, storage.Relationship{
+				ID:         edge.ID,
+				FromID:     edge.FromID,
+				ToID:       edge.ToID,
+				Type:       edge.Type,
+				Properties: edge.Properties,
+			})
+		}
+	}
+	return graph, nil
+}
@@ -0,0 +1,183 @@
+// Package ingest splits documents into overlapping chunks and stores them as
+// memories linked in reading order.
+package ingest
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Chunking defaults, in bytes of text
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// Chunk is a piece of a document. Start and End are byte offsets into the
+// original document, so chunks can be mapped back to their source.
+type Chunk struct {
+	Text    string
+	Start   int
+	End     int
+	Heading string // Markdown heading path, e.g. "Setup > Docker"
+}
+
+// ChunkOptions controls chunk size and overlap. Zero values use the
+// defaults; a negative overlap disables overlapping.
+type ChunkOptions struct {
+	Size    int
+	Overlap int
+}
+
+// withDefaults fills unset options and keeps the overlap under half a chunk
+func (o ChunkOptions) withDefaults() ChunkOptions {
+	if o.Size <= 0 {
+		o.Size = DefaultChunkSize
+	}
+	switch {
+	case o.Overlap < 0:
+		o.Overlap = 0
+	case o.Overlap == 0:
+		o.Overlap = DefaultChunkOverlap
+	}
+	o.Overlap = min(o.Overlap, o.Size/2)
+	return o
+}
+
+// SplitText splits plain text into overlapping chunks, breaking at paragraph,
+// line, sentence or word boundaries where possible
+func SplitText(text string, opts ChunkOptions) []Chunk {
+	return splitRange(text, 0, len(text), "", opts.withDefaults())
+}
+
+// headingPattern matches ATX Markdown headings ("## Title")
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// SplitMarkdown splits Markdown at headings first, so no chunk spans two
+// sections, then splits long sections like SplitText. Headings inside fenced
+// code blocks are ignored.
+func SplitMarkdown(text string, opts ChunkOptions) []Chunk {
+	opts = opts.withDefaults()
+
+	var chunks []Chunk
+	var headings []string // Current heading at each level
+	sectionStart, sectionHeading := 0, ""
+	inFence := false
+
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		match := headingPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			continue
+		}
+
+		// Close the previous section at this heading
+		chunks = append(chunks, splitRange(text, sectionStart, lineStart, sectionHeading, opts)...)
+
+		level := len(match[1])
+		if len(headings) >= level {
+			headings = headings[:level-1]
+		}
+		for len(headings) < level-1 {
+			headings = append(headings, "")
+		}
+		headings = append(headings, match[2])
+		sectionStart, sectionHeading = lineStart, joinHeadings(headings)
+	}
+
+	return append(chunks, splitRange(text, sectionStart, len(text), sectionHeading, opts)...)
+}
+
+// joinHeadings renders the heading path, skipping levels that were never set
+func joinHeadings(headings []string) string {
+	var parts []string
+	for _, heading := range headings {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// splitRange chunks text[start:end], skipping whitespace-only chunks
+func splitRange(text string, start, end int, heading string, opts ChunkOptions) []Chunk {
+	var chunks []Chunk
+	for start < end {
+		// Skip leading whitespace so chunks start on content
+		for start < end && isSpace(text[start]) {
+			start++
+		}
+		if start >= end {
+			break
+		}
+
+		chunkEnd := end
+		if end-start > opts.Size {
+			chunkEnd = breakPoint(text, start, start+opts.Size)
+		}
+
+		if chunk := strings.TrimRight(text[start:chunkEnd], " \t\r\n"); chunk != "" {
+			chunks = append(chunks, Chunk{
+				Text:    chunk,
+				Start:   start,
+				End:     start + len(chunk),
+				Heading: heading,
+			})
+		}
+		if chunkEnd >= end {
+			break
+		}
+
+		// Step back by the overlap, then forward to a word start so the
+		// overlapping text doesn't begin mid-word
+		next := chunkEnd - opts.Overlap
+		if next <= start {
+			next = chunkEnd
+		}
+		for next < chunkEnd && !isSpace(text[next-1]) {
+			next++
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint picks where a chunk starting at start should end, at most at
+// limit. It prefers the last paragraph break, then line break, sentence end
+// and word break in the second half of the window.
+func breakPoint(text string, start, limit int) int {
+	window := text[start:limit]
+	minimum := len(window) / 2
+
+	for _, separator := range []string{"\n\n", "\n", ". ", "? ", "! ", " "} {
+		if i := strings.LastIndex(window, separator); i >= minimum {
+			return start + i + len(separator)
+		}
+	}
+
+	// No natural break: cut at the limit, backing off to a UTF-8 boundary
+	for limit > start+1 && !isRuneStart(text[limit]) {
+		limit--
+	}
+	return limit
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
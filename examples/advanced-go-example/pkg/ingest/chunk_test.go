@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkOptionsWithDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ChunkOptions
+		want ChunkOptions
+	}{
+		{"zero values", ChunkOptions{}, ChunkOptions{Size: DefaultChunkSize, Overlap: DefaultChunkOverlap}},
+		{"negative size", ChunkOptions{Size: -1, Overlap: 10}, ChunkOptions{Size: DefaultChunkSize, Overlap: 10}},
+		{"negative overlap disables it", ChunkOptions{Size: 100, Overlap: -1}, ChunkOptions{Size: 100, Overlap: 0}},
+		{"overlap larger than the chunk", ChunkOptions{Size: 100, Overlap: 500}, ChunkOptions{Size: 100, Overlap: 50}},
+		{"default overlap capped", ChunkOptions{Size: 100}, ChunkOptions{Size: 100, Overlap: 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.withDefaults(); got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	words := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+
+	tests := []struct {
+		name string
+		text string
+		opts ChunkOptions
+		want []string // nil skips the exact comparison
+		n    int      // Expected number of chunks
+	}{
+		{name: "empty input", text: "", n: 0},
+		{name: "whitespace only", text: " \n\t\r\n ", n: 0},
+		{name: "short text", text: "  one chunk  \n", want: []string{"one chunk"}, n: 1},
+		{
+			name: "paragraph break",
+			text: "first paragraph here\n\nsecond one",
+			opts: ChunkOptions{Size: 25, Overlap: -1},
+			want: []string{"first paragraph here", "second one"},
+			n:    2,
+		},
+		{
+			// The overlap only starts at a word boundary, so the first step back is skipped
+			name: "sentence break with overlap",
+			text: "One two three. Four five six. Seven eight.",
+			opts: ChunkOptions{Size: 20, Overlap: 6},
+			want: []string{"One two three.", "Four five six.", "six. Seven eight."},
+			n:    3,
+		},
+		{name: "overlap larger than the chunk", text: words, opts: ChunkOptions{Size: 40, Overlap: 400}, n: 25},
+		{name: "no overlap", text: words, opts: ChunkOptions{Size: 40, Overlap: -1}, n: 15},
+		{name: "no natural break", text: strings.Repeat("x", 25), opts: ChunkOptions{Size: 10, Overlap: -1}, want: []string{"xxxxxxxxxx", "xxxxxxxxxx", "xxxxx"}, n: 3},
+		{name: "multibyte runes", text: strings.Repeat("é", 25), opts: ChunkOptions{Size: 9, Overlap: -1}, n: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitText(tt.text, tt.opts)
+			if len(chunks) != tt.n {
+				t.Fatalf("got %d chunks, want %d: %q", len(chunks), tt.n, chunkTexts(chunks))
+			}
+			if tt.want != nil && !reflect.DeepEqual(chunkTexts(chunks), tt.want) {
+				t.Errorf("chunks = %q, want %q", chunkTexts(chunks), tt.want)
+			}
+			checkChunks(t, tt.text, chunks, tt.opts.withDefaults())
+		})
+	}
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		opts     ChunkOptions
+		headings []string
+		texts    []string
+	}{
+		{name: "empty input"},
+		{
+			name:     "no headings",
+			text:     "Just a paragraph.",
+			headings: []string{""},
+			texts:    []string{"Just a paragraph."},
+		},
+		{
+			name:     "nested headings",
+			text:     "Intro\n# Setup\nInstall it.\n## Docker\nRun it.\n# Usage ##\nUse it.\n",
+			headings: []string{"", "Setup", "Setup > Docker", "Usage"},
+			texts:    []string{"Intro", "# Setup\nInstall it.", "## Docker\nRun it.", "# Usage ##\nUse it."},
+		},
+		{
+			name:     "skipped level",
+			text:     "# Top\n### Deep\ntext\n## Middle\nmore",
+			headings: []string{"Top", "Top > Deep", "Top > Middle"},
+			texts:    []string{"# Top", "### Deep\ntext", "## Middle\nmore"},
+		},
+		{
+			name:     "heading inside a code fence",
+			text:     "# Code\n```\n# not a heading\n```\n~~~\n## nor this\n~~~\n",
+			headings: []string{"Code"},
+			texts:    []string{"# Code\n```\n# not a heading\n```\n~~~\n## nor this\n~~~"},
+		},
+		{
+			name:     "empty section",
+			text:     "# A\n\n# B\nbody",
+			headings: []string{"A", "B"},
+			texts:    []string{"# A", "# B\nbody"},
+		},
+		{
+			name:     "long section",
+			text:     "# Long\nalpha beta gamma delta epsilon zeta eta theta",
+			opts:     ChunkOptions{Size: 20, Overlap: -1},
+			headings: []string{"Long", "Long", "Long"},
+			texts:    []string{"# Long\nalpha beta", "gamma delta epsilon", "zeta eta theta"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitMarkdown(tt.text, tt.opts)
+			var headings []string
+			for _, chunk := range chunks {
+				headings = append(headings, chunk.Heading)
+			}
+			if !reflect.DeepEqual(headings, tt.headings) {
+				t.Errorf("headings = %q, want %q", headings, tt.headings)
+			}
+			if texts := chunkTexts(chunks); !reflect.DeepEqual(texts, tt.texts) {
+				t.Errorf("chunks = %q, want %q", texts, tt.texts)
+			}
+			checkChunks(t, tt.text, chunks, tt.opts.withDefaults())
+		})
+	}
+}
+
+// checkChunks verifies the invariants every chunking must keep
+func checkChunks(t *testing.T, text string, chunks []Chunk, opts ChunkOptions) {
+	t.Helper()
+	for i, chunk := range chunks {
+		if chunk.Start < 0 || chunk.End > len(text) || chunk.Start >= chunk.End {
+			t.Fatalf("chunk %d has invalid range [%d, %d)", i, chunk.Start, chunk.End)
+		}
+		if text[chunk.Start:chunk.End] != chunk.Text {
+			t.Errorf("chunk %d text %q doesn't match its range %q", i, chunk.Text, text[chunk.Start:chunk.End])
+		}
+		if len(chunk.Text) > opts.Size {
+			t.Errorf("chunk %d is %d bytes, over the size of %d", i, len(chunk.Text), opts.Size)
+		}
+		if !utf8.ValidString(chunk.Text) {
+			t.Errorf("chunk %d splits a rune: %q", i, chunk.Text)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := chunks[i-1]
+		if chunk.Start <= previous.Start {
+			t.Errorf("chunk %d starts at %d, not after chunk %d at %d", i, chunk.Start, i-1, previous.Start)
+		}
+		if overlap := previous.End - chunk.Start; overlap > opts.Overlap {
+			t.Errorf("chunks %d and %d overlap by %d bytes, over %d", i-1, i, overlap, opts.Overlap)
+		}
+	}
+}
+
+func chunkTexts(chunks []Chunk) []string {
+	var texts []string
+	for _, chunk := range chunks {
+		texts = append(texts, chunk.Text)
+	}
+	return texts
+}
@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/storage"
+)
+
+// NextRelationship links each chunk to the one that follows it
+const NextRelationship = "NEXT"
+
+// DefaultBatchSize is how many chunks are embedded per request
+const DefaultBatchSize = 32
+
+// Supported formats
+const (
+	FormatAuto     = "auto"
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+)
+
+// extensionFormats lists the files picked up when ingesting a directory
+var extensionFormats = map[string]string{
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".txt":      FormatText,
+	".text":     FormatText,
+}
+
+// Options controls how documents are chunked and stored
+type Options struct {
+	GroupID    string
+	Tags       []string
+	Importance float64
+	Format     string // auto (by extension), markdown or text
+	Chunking   ChunkOptions
+	BatchSize  int
+}
+
+// DocumentResult reports the chunks stored for one file
+type DocumentResult struct {
+	Path   string  `json:"path"`
+	Chunks int     `json:"chunks"`
+	IDs    []int64 `json:"ids"` // Memory IDs in reading order
+	Pruned int     `json:"pruned,omitzero"`
+}
+
+// Ingester chunks documents, embeds the chunks and stores them as memories
+type Ingester struct {
+	store    *storage.PostgresStore
+	embedder *embeddings.Client
+}
+
+// NewIngester creates an ingester
+func NewIngester(store *storage.PostgresStore, embedder *embeddings.Client) *Ingester {
+	return &Ingester{store: store, embedder: embedder}
+}
+
+// IngestPath ingests a file, or every Markdown and text file under a
+// directory (hidden directories are skipped)
+func (i *Ingester) IngestPath(path string, opts Options) ([]DocumentResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		result, err := i.IngestFile(path, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []DocumentResult{*result}, nil
+	}
+
+	results := []DocumentResult{}
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if file != path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := extensionFormats[strings.ToLower(filepath.Ext(file))]; !ok {
+			return nil
+		}
+
+		result, err := i.IngestFile(file, opts)
+		if err != nil {
+			return err
+		}
+		results = append(results, *result)
+		return nil
+	})
+	if err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+// IngestFile chunks and stores one document. Chunks are keyed by file path
+// and position, so ingesting a file again updates its chunks in place and
+// removes chunks left over from a longer earlier version.
+func (i *Ingester) IngestFile(path string, opts Options) (*DocumentResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	format := opts.Format
+	if format == "" || format == FormatAuto {
+		format = extensionFormats[strings.ToLower(filepath.Ext(path))]
+	}
+
+	var chunks []Chunk
+	switch format {
+	case FormatMarkdown:
+		chunks = SplitMarkdown(string(content), opts.Chunking)
+	case FormatText, "":
+		chunks = SplitText(string(content), opts.Chunking)
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected auto, markdown or text)", opts.Format)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	prefix := documentPrefix(absPath)
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	result := &DocumentResult{Path: path, Chunks: len(chunks), IDs: make([]int64, 0, len(chunks))}
+	for start := 0; start < len(chunks); start += batchSize {
+		batch := chunks[start:min(start+batchSize, len(chunks))]
+
+		// Heading paths give short chunks the context of their section
+		texts := make([]string, len(batch))
+		for j, chunk := range batch {
+			texts[j] = chunk.Text
+			if chunk.Heading != "" {
+				texts[j] = chunk.Heading + "\n\n" + chunk.Text
+			}
+		}
+
+		vectors, err := i.embedder.GenerateBatch(texts)
+		if err != nil {
+			return result, fmt.Errorf("failed to embed chunks of %s: %w", path, err)
+		}
+
+		for j, chunk := range batch {
+			index := start + j
+			attributes := map[string]interface{}{
+				"document":     absPath,
+				"chunk_index":  index,
+				"chunk_count":  len(chunks),
+				"offset_start": chunk.Start,
+				"offset_end":   chunk.End,
+			}
+			if chunk.Heading != "" {
+				attributes["heading"] = chunk.Heading
+			}
+
+			id, _, err := i.store.ImportMemory(storage.Memory{
+				ExternalID: fmt.Sprintf("%s%d", prefix, index),
+				Text:       chunk.Text,
+				Embedding:  vectors[j],
+				GroupID:    opts.GroupID,
+				Tags:       opts.Tags,
+				Source:     path,
+				Importance: opts.Importance,
+				Attributes: attributes,
+			})
+			if err != nil {
+				return result, fmt.Errorf("failed to store chunk %d of %s: %w", index, path, err)
+			}
+
+			// Link chunks in reading order so graph expansion reaches neighbours
+			if len(result.IDs) > 0 {
+				previous := result.IDs[len(result.IDs)-1]
+				if _, err := i.store.AddRelationship(previous, id, NextRelationship, nil); err != nil {
+					return result, fmt.Errorf("failed to link chunk %d of %s: %w", index, path, err)
+				}
+			}
+			result.IDs = append(result.IDs, id)
+		}
+	}
+
+	// Drop chunks beyond the new end of the document
+	existing, err := i.store.ExternalIDsWithPrefix(prefix)
+	if err != nil {
+		return result, err
+	}
+	var stale []int64
+	for externalID, id := range existing {
+		var index int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(externalID, prefix), "%d", &index); err != nil || index >= len(chunks) {
+			stale = append(stale, id)
+		}
+	}
+	if err := i.store.DeleteMemories(stale); err != nil {
+		return result, fmt.Errorf("failed to remove stale chunks of %s: %w", path, err)
+	}
+	result.Pruned = len(stale)
+
+	return result, nil
+}
+
+// documentPrefix derives the external ID prefix shared by a document's chunks
+func documentPrefix(absPath string) string {
+	sum := sha256.Sum256([]byte(absPath))
+	return "ingest:" + hex.EncodeToString(sum[:8]) + ":"
+}
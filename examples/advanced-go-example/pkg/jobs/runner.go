@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+	"advanced-go-example/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Handler runs one job. The returned result is stored as JSON on success;
+// an error fails the attempt and the job is retried while attempts remain.
+// Long jobs should checkpoint with SetJobProgress and stop when ctx is done;
+// ctx is also cancelled if the runner loses the job's lease.
+type Handler func(ctx context.Context, job storage.Job) (interface{}, error)
+
+// Config controls the worker pool
+type Config struct {
+	Workers      int           // Concurrent jobs; 0 disables the runner
+	PollInterval time.Duration // How often idle workers look for new jobs (default: 1s)
+	RetryBackoff time.Duration // Delay before the first retry, doubled for each later one (default: 30s)
+	Lease        time.Duration // How long a claimed job stays reserved without renewal (default: 1m)
+}
+
+// DefaultLease is how long a job stays reserved for its worker. Leases are
+// renewed while the job runs, so this only bounds how long the jobs of a
+// crashed server wait before another worker takes them over.
+const DefaultLease = time.Minute
+
+// DefaultMaxAttempts is how often a job is tried before it is marked failed
+const DefaultMaxAttempts = 3
+
+// maxRetryBackoff caps the exponential retry delay
+const maxRetryBackoff = time.Hour
+
+// Runner works the jobs table with a pool of goroutines
+type Runner struct {
+	store    *storage.PostgresStore
+	config   Config
+	owner    string // Identifies this runner's leases
+	handlers map[string]Handler
+}
+
+// NewRunner creates a runner; register handlers with Handle before calling Run
+func NewRunner(store *storage.PostgresStore, config Config) *Runner {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 30 * time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = DefaultLease
+	}
+	return &Runner{store: store, config: config, owner: Owner(), handlers: map[string]Handler{}}
+}
+
+// Owner returns a lease holder name for this process, e.g. "host:1234"
+func Owner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// Handle registers the handler for a job kind
+func (r *Runner) Handle(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Run works jobs until ctx is cancelled. It returns once every in-flight job
+// has finished.
+func (r *Runner) Run(ctx context.Context) error {
+	if err := r.requeueExpired(); err != nil {
+		return err
+	}
+
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.reap(ctx)
+	}()
+	for range r.config.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, kinds)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// reap requeues jobs whose lease has run out, once per lease period, so the
+// jobs of a crashed server are picked up while this one keeps running
+func (r *Runner) reap(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Lease)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.requeueExpired(); err != nil {
+			log.Printf("Job reaper: %v", err)
+		}
+	}
+}
+
+func (r *Runner) requeueExpired() error {
+	requeued, err := r.store.RequeueExpiredJobs()
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		log.Printf("Requeued %d interrupted jobs whose lease ran out", requeued)
+	}
+	return nil
+}
+
+// work claims and runs jobs until ctx is cancelled, sleeping for the poll
+// interval whenever the queue is empty
+func (r *Runner) work(ctx context.Context, kinds []string) {
+	for {
+		job, err := r.store.ClaimJob(kinds, r.owner, r.config.Lease)
+		if err != nil {
+			log.Printf("Job worker: %v", err)
+		} else if job != nil {
+			r.run(ctx, *job)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+// run executes a claimed job and records its outcome
+func (r *Runner) run(ctx context.Context, job storage.Job) {
+	jobCtx, release := HoldLease(ctx, r.store, job, r.owner, r.config.Lease)
+	result, err := r.call(jobCtx, job)
+	if !release() {
+		// Another worker took the job over; its outcome is theirs to record
+		log.Printf("Job %d: lost the lease, leaving the job to its new worker", job.ID)
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown; another worker picks it up from its checkpoint
+		if err := r.store.ReleaseJob(job.ID); err != nil {
+			log.Printf("Job %d: %v", job.ID, err)
+		}
+		return
+	}
+	if err == nil {
+		if err := r.store.CompleteJob(job.ID, result); err != nil {
+			log.Printf("Job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	status, failErr := r.store.FailJob(job.ID, err, r.backoff(job.Attempts))
+	if failErr != nil {
+		log.Printf("Job %d: %v (recording failure: %v)", job.ID, err, failErr)
+		return
+	}
+	log.Printf("Job %d (%s, attempt %d/%d) %s: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, status, err)
+}
+
+// call runs the job's handler, turning a panic into an error so one bad job
+// cannot take down the server
+func (r *Runner) call(ctx context.Context, job storage.Job) (result interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "job "+job.Kind,
+		attribute.Int64("job.id", job.ID),
+		attribute.Int("job.attempt", job.Attempts),
+	)
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+		tracing.End(span, err)
+	}()
+	return r.handlers[job.Kind](ctx, job)
+}
+
+// backoff is the delay before retrying a job that has failed attempts times
+func (r *Runner) backoff(attempts int) time.Duration {
+	delay := r.config.RetryBackoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// HoldLease renews owner's lease on a claimed job in the background until
+// release is called. The returned context is cancelled if the lease is lost,
+// e.g. because renewals failed for longer than the lease and another worker
+// claimed the job; release then reports false and the caller must not record
+// an outcome for the job.
+func HoldLease(ctx context.Context, store *storage.PostgresStore, job storage.Job, owner string, lease time.Duration) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	held := true
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		// Renew well before the lease runs out, so one slow renewal doesn't lose it
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			renewed, err := store.RenewJobLease(job.ID, owner, lease)
+			if err != nil {
+				log.Printf("Job %d: %v", job.ID, err)
+				continue
+			}
+			if !renewed {
+				held = false
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, func() bool {
+		close(done)
+		<-stopped
+		cancel()
+		return held
+	}
+}
@@ -2,10 +2,18 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"advanced-go-example/pkg/metrics"
+	"advanced-go-example/pkg/ontology"
+	"advanced-go-example/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Config holds LLM service configuration
@@ -13,24 +21,48 @@ type Config struct {
 	BaseURL string
 	Model   string
 	APIKey  string
+	Timeout time.Duration // Per request; 0 uses DefaultTimeout
+
+	// Relationship types offered to the model; nil uses ontology.Default()
+	Ontology *ontology.Ontology
 }
 
+// DefaultTimeout bounds a completion request when Config.Timeout is unset.
+// It is longer than the embedding timeout because generation is slower.
+const DefaultTimeout = 60 * time.Second
+
 // Client handles LLM interactions for relationship classification
 type Client struct {
 	config Config
 	http   *http.Client
+	ctx    context.Context // Parent of the request spans; see WithContext
 }
 
 // NewClient creates a new LLM client
 func NewClient(config Config) *Client {
+	if config.Ontology == nil {
+		config.Ontology = ontology.Default()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
 	return &Client{
 		config: config,
 		http: &http.Client{
-			Timeout: 60 * time.Second, // Longer timeout for LLM
+			Timeout: config.Timeout,
 		},
+		ctx: context.Background(),
 	}
 }
 
+// WithContext returns a copy of the client whose requests trace as children
+// of the span in ctx
+func (c *Client) WithContext(ctx context.Context) *Client {
+	scoped := *c
+	scoped.ctx = ctx
+	return &scoped
+}
+
 // RelationshipSuggestion represents a suggested relationship between memories
 type RelationshipSuggestion struct {
 	TargetID   int64             `json:"target_id"`
@@ -51,24 +83,33 @@ func (c *Client) AnalyzeRelationships(sourceText string, sourceID int64, candida
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
 
-	// Log raw response for debugging
-	fmt.Printf("[LLM] Raw response length: %d bytes\n", len(response))
-	if len(response) > 500 {
-		fmt.Printf("[LLM] Response preview: %s...\n", response[:500])
-	} else {
-		fmt.Printf("[LLM] Full response: %s\n", response)
+	// Log raw response for debugging. Never print to stdout: the server
+	// speaks MCP over it and memctl writes JSON there.
+	preview := response
+	if len(preview) > 500 {
+		preview = preview[:500] + "..."
 	}
+	slog.Debug("LLM response", "bytes", len(response), "response", preview)
 
 	// Parse JSON response
 	var suggestions []RelationshipSuggestion
 	if err := json.Unmarshal([]byte(response), &suggestions); err != nil {
-		fmt.Printf("[LLM] JSON parse error: %v\n", err)
-		fmt.Printf("[LLM] Failed to parse: %s\n", response)
+		slog.Debug("LLM response is not JSON", "error", err, "response", response)
 		return nil, fmt.Errorf("failed to parse LLM response as JSON: %w", err)
 	}
 
-	fmt.Printf("[LLM] Successfully parsed %d suggestions\n", len(suggestions))
-	return suggestions, nil
+	// Drop types the ontology doesn't know, so they never reach the graph
+	known := suggestions[:0]
+	for _, suggestion := range suggestions {
+		if t, _, ok := c.config.Ontology.Lookup(suggestion.Type); !ok || t.Internal {
+			slog.Debug("Ignoring LLM suggestion with unknown type", "type", suggestion.Type)
+			continue
+		}
+		known = append(known, suggestion)
+	}
+
+	slog.Debug("Parsed LLM suggestions", "count", len(known))
+	return known, nil
 }
 
 // CandidateMemory represents a memory that might be related to the source
@@ -94,20 +135,12 @@ CANDIDATE MEMORIES:
 Analyze each candidate and determine if there's a meaningful relationship with the source memory.
 
 RELATIONSHIP TYPES:
-- RELATES_TO: General semantic connection
-- BUILDS_ON: Extends or improves the source concept
-- CONTRADICTS: Presents conflicting information
-- EXEMPLIFIES: Provides a specific example of the source concept
-- DEPENDS_ON: Source requires understanding this first
-- SIMILAR_TO: Very similar but different context
-- CAUSES: Source leads to this outcome
-- SOLVED_BY: Source problem is resolved by this
-
+` + c.config.Ontology.PromptSection() + `
 Return ONLY a JSON array (no markdown, no explanation) with this format:
 [
   {
     "target_id": 123,
-    "type": "RELATES_TO",
+    "type": "` + c.config.Ontology.Names()[0] + `",
     "reason": "Brief explanation why",
     "confidence": 0.85
   }
@@ -118,7 +151,31 @@ Only include relationships with confidence >= 0.7. Return empty array [] if no s
 	return prompt
 }
 
+// complete sends prompt as a single user message and returns the reply
 func (c *Client) complete(prompt string) (string, error) {
+	_, span := tracing.StartChild(c.ctx, "llm.complete",
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.request.model", c.config.Model),
+	)
+	start := time.Now()
+	content, usage, err := c.chat(prompt)
+	metrics.ObserveLLM(start, err)
+
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+	)
+	tracing.End(span, err)
+	return content, err
+}
+
+// tokenUsage is the usage block of a chat completion response
+type tokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (c *Client) chat(prompt string) (string, tokenUsage, error) {
 	reqBody := map[string]interface{}{
 		"model": c.config.Model,
 		"messages": []map[string]string{
@@ -130,12 +187,12 @@ func (c *Client) complete(prompt string) (string, error) {
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", c.config.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -145,12 +202,12 @@ func (c *Client) complete(prompt string) (string, error) {
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d", resp.StatusCode)
+		return "", tokenUsage{}, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
 	var result struct {
@@ -159,15 +216,16 @@ func (c *Client) complete(prompt string) (string, error) {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage tokenUsage `json:"usage"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no completion returned")
+		return "", tokenUsage{}, fmt.Errorf("no completion returned")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	return result.Choices[0].Message.Content, result.Usage, nil
 }
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// checkTimeout bounds each readiness check
+const checkTimeout = 5 * time.Second
+
+// Check is a named readiness check, such as pinging a dependency
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// readiness is the /readyz response body
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewHandler serves /metrics for Prometheus, /healthz for liveness and
+// /readyz, which runs checks concurrently and answers 503 if any fails
+func NewHandler(checks []Check) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+
+	// Liveness only says the process is serving; dependencies belong in /readyz
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		response := readiness{Status: "ok", Checks: make(map[string]string, len(checks))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result := "ok"
+				if err := check.Run(ctx); err != nil {
+					result = err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				response.Checks[check.Name] = result
+				if result != "ok" {
+					response.Status = "unavailable"
+				}
+			}()
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if response.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+
+	return mux
+}
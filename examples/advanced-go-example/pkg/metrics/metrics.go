@@ -0,0 +1,185 @@
+// Package metrics defines the server's Prometheus metrics. The collectors
+// are package-level so storage, clients and tool handlers can record into
+// them without extra wiring; they cost next to nothing when nobody scrapes.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// namespace prefixes every metric name
+const namespace = "memory"
+
+// Outcome label values
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Registry holds the server's collectors plus the Go runtime and process ones
+var Registry = prometheus.NewRegistry()
+
+var (
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "MCP tool calls by tool name and outcome.",
+	}, []string{"tool", "outcome"})
+
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tool_call_duration_seconds",
+		Help:      "MCP tool call latency by tool name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+
+	embeddingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "embedding_request_duration_seconds",
+		Help:      "Latency of embedding API requests.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	embeddingErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_errors_total",
+		Help:      "Embedding API requests that failed.",
+	})
+
+	llmDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of LLM completion requests.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80},
+	})
+
+	llmErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_errors_total",
+		Help:      "LLM completion requests that failed.",
+	})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of storage operations by method and outcome.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"method", "outcome"})
+
+	edgesCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relationships_created_total",
+		Help:      "Relationship edges created or merged, by type and whether auto-detection created them.",
+	}, []string{"type", "auto_detected"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		toolCalls, toolDuration,
+		embeddingDuration, embeddingErrors,
+		llmDuration, llmErrors,
+		dbDuration, edgesCreated,
+	)
+}
+
+// RegisterDBStats exports connection pool statistics read from stats.
+// Call it once per process, for the store the server uses.
+func RegisterDBStats(stats func() sql.DBStats) {
+	gauge := func(name, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "db", Name: name, Help: help,
+		}, func() float64 { return value(stats()) })
+	}
+	counter := func(name, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "db", Name: name, Help: help,
+		}, func() float64 { return value(stats()) })
+	}
+
+	Registry.MustRegister(
+		gauge("max_open_connections", "Maximum number of open connections.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		gauge("open_connections", "Established connections, in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		gauge("in_use_connections", "Connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		gauge("idle_connections", "Idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		counter("wait_count_total", "Connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		counter("wait_duration_seconds_total", "Time spent waiting for a connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		counter("max_idle_closed_total", "Connections closed due to the idle limit.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		counter("max_lifetime_closed_total", "Connections closed due to the lifetime limit.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	)
+}
+
+// outcome returns the outcome label for err
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+// ObserveEmbedding records an embedding request that started at start
+func ObserveEmbedding(start time.Time, err error) {
+	embeddingDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		embeddingErrors.Inc()
+	}
+}
+
+// ObserveLLM records an LLM completion request that started at start
+func ObserveLLM(start time.Time, err error) {
+	llmDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		llmErrors.Inc()
+	}
+}
+
+// ObserveQuery records a storage method call that started at start
+func ObserveQuery(method string, start time.Time, err error) {
+	dbDuration.WithLabelValues(method, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// EdgeCreated counts a relationship edge of type relType
+func EdgeCreated(relType string, autoDetected bool) {
+	label := "false"
+	if autoDetected {
+		label = "true"
+	}
+	edgesCreated.WithLabelValues(relType, label).Inc()
+}
+
+// ToolMiddleware counts tools/call requests and their latency. A call fails
+// if the SDK rejects it (e.g. invalid arguments) or the tool reports an error.
+func ToolMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		toolDuration.WithLabelValues(call.Params.Name).Observe(time.Since(start).Seconds())
+
+		status := outcome(err)
+		if toolResult, ok := result.(*mcp.CallToolResult); ok && toolResult.IsError {
+			status = OutcomeError
+		}
+		toolCalls.WithLabelValues(call.Params.Name, status).Inc()
+		return result, err
+	}
+}
@@ -0,0 +1,237 @@
+// Package ontology defines the relationship types the memory graph may use:
+// their meaning, direction, inverse names and allowed properties. It drives
+// the relationship detection prompt and validates edges created by tools.
+package ontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// namePattern matches type and property names that are safe to splice into Cypher
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidName reports whether name is usable as a relationship type or
+// property name, which also makes it safe to splice into Cypher
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// SystemProperties are set by auto-detection and allowed on every type
+var SystemProperties = []string{"reason", "confidence", "auto_detected"}
+
+// RelationshipType describes one kind of edge
+type RelationshipType struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Symmetric types mean the same in both directions (A SIMILAR_TO B is
+	// B SIMILAR_TO A), so edges are stored once, from the lower memory ID
+	Symmetric bool `json:"symmetric,omitzero"`
+
+	// Inverse names the same relationship read backwards (SOLVED_BY / SOLVES).
+	// Edges given with the inverse name are stored under this type, reversed.
+	Inverse string `json:"inverse,omitzero"`
+
+	// Properties the edge may carry besides SystemProperties; empty allows any
+	Properties []string `json:"properties,omitzero"`
+
+	// Internal types are managed by the server (like NEXT from ingestion):
+	// they are not offered to the LLM and can't be added by hand
+	Internal bool `json:"internal,omitzero"`
+}
+
+// Ontology is a validated set of relationship types
+type Ontology struct {
+	types   []RelationshipType
+	byName  map[string]int // Type name -> index
+	inverse map[string]int // Inverse name -> index of the type it inverts
+}
+
+// Default returns the built-in relationship types
+func Default() *Ontology {
+	o, err := New([]RelationshipType{
+		{Name: "RELATES_TO", Description: "General semantic connection", Symmetric: true},
+		{Name: "BUILDS_ON", Description: "Extends or improves the source concept", Inverse: "EXTENDED_BY"},
+		{Name: "CONTRADICTS", Description: "Presents conflicting information", Symmetric: true},
+		{Name: "EXEMPLIFIES", Description: "Provides a specific example of the source concept", Inverse: "EXEMPLIFIED_BY"},
+		{Name: "DEPENDS_ON", Description: "Source requires understanding this first", Inverse: "REQUIRED_BY"},
+		{Name: "SIMILAR_TO", Description: "Very similar but different context", Symmetric: true},
+		{Name: "CAUSES", Description: "Source leads to this outcome", Inverse: "CAUSED_BY"},
+		{Name: "SOLVED_BY", Description: "Source problem is resolved by this", Inverse: "SOLVES"},
+		{Name: "SUPERSEDES", Description: "Source replaces this outdated information", Inverse: "SUPERSEDED_BY"},
+		{Name: "NEXT", Description: "Next chunk of the same ingested document", Internal: true},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// New validates types and builds an ontology from them
+func New(types []RelationshipType) (*Ontology, error) {
+	o := &Ontology{
+		types:   make([]RelationshipType, len(types)),
+		byName:  make(map[string]int),
+		inverse: make(map[string]int),
+	}
+	copy(o.types, types)
+
+	for i, t := range o.types {
+		if !namePattern.MatchString(t.Name) {
+			return nil, fmt.Errorf("invalid relationship type name %q", t.Name)
+		}
+		if _, exists := o.byName[t.Name]; exists {
+			return nil, fmt.Errorf("relationship type %s is defined twice", t.Name)
+		}
+		o.byName[t.Name] = i
+	}
+
+	if len(o.Names()) == 0 {
+		return nil, fmt.Errorf("ontology needs at least one relationship type that isn't internal")
+	}
+
+	for i, t := range o.types {
+		if t.Inverse != "" {
+			if t.Symmetric {
+				return nil, fmt.Errorf("relationship type %s is symmetric, so it can't have an inverse", t.Name)
+			}
+			if !namePattern.MatchString(t.Inverse) {
+				return nil, fmt.Errorf("invalid inverse name %q for %s", t.Inverse, t.Name)
+			}
+			if _, exists := o.byName[t.Inverse]; exists {
+				return nil, fmt.Errorf("inverse %s of %s is also defined as a type; define only one direction", t.Inverse, t.Name)
+			}
+			if _, exists := o.inverse[t.Inverse]; exists {
+				return nil, fmt.Errorf("inverse name %s is used twice", t.Inverse)
+			}
+			o.inverse[t.Inverse] = i
+		}
+		for _, property := range t.Properties {
+			if !namePattern.MatchString(property) {
+				return nil, fmt.Errorf("invalid property name %q for %s", property, t.Name)
+			}
+		}
+	}
+
+	return o, nil
+}
+
+// Load reads an ontology from a JSON file of the form {"types": [...]}
+func Load(path string) (*Ontology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ontology: %w", err)
+	}
+
+	var file struct {
+		Types []RelationshipType `json:"types"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ontology %s: %w", path, err)
+	}
+	if len(file.Types) == 0 {
+		return nil, fmt.Errorf("ontology %s defines no relationship types", path)
+	}
+
+	o, err := New(file.Types)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ontology %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// Types returns every relationship type in definition order
+func (o *Ontology) Types() []RelationshipType {
+	return append([]RelationshipType{}, o.types...)
+}
+
+// Lookup returns the type called name, which may be an inverse name.
+// reversed reports whether the edge must be stored the other way round.
+func (o *Ontology) Lookup(name string) (t RelationshipType, reversed bool, ok bool) {
+	if i, found := o.byName[name]; found {
+		return o.types[i], false, true
+	}
+	if i, found := o.inverse[name]; found {
+		return o.types[i], true, true
+	}
+	return RelationshipType{}, false, false
+}
+
+// Normalize resolves relType to the stored type and direction of an edge
+// between fromID and toID, and checks the properties against the type.
+// Inverse names are swapped to their type; symmetric edges point from the
+// lower ID so each pair is stored once.
+func (o *Ontology) Normalize(fromID, toID int64, relType string, properties map[string]interface{}) (int64, int64, string, error) {
+	t, reversed, ok := o.Lookup(relType)
+	if !ok {
+		return 0, 0, "", fmt.Errorf("unknown relationship type %q (known: %s)", relType, strings.Join(o.Names(), ", "))
+	}
+	if t.Internal {
+		return 0, 0, "", fmt.Errorf("relationship type %s is managed by the server", t.Name)
+	}
+	if reversed || (t.Symmetric && fromID > toID) {
+		fromID, toID = toID, fromID
+	}
+	if err := t.CheckProperties(properties); err != nil {
+		return 0, 0, "", err
+	}
+	return fromID, toID, t.Name, nil
+}
+
+// CheckProperties rejects properties the type doesn't allow
+func (t RelationshipType) CheckProperties(properties map[string]interface{}) error {
+	if len(t.Properties) == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for key := range properties {
+		if !slices.Contains(t.Properties, key) && !slices.Contains(SystemProperties, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		allowed := append(append([]string{}, t.Properties...), SystemProperties...)
+		return fmt.Errorf("%s doesn't allow properties %s (allowed: %s)",
+			t.Name, strings.Join(unknown, ", "), strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// Names lists the type names and inverse names that can be used for edges
+func (o *Ontology) Names() []string {
+	var names []string
+	for _, t := range o.types {
+		if t.Internal {
+			continue
+		}
+		names = append(names, t.Name)
+		if t.Inverse != "" {
+			names = append(names, t.Inverse)
+		}
+	}
+	return names
+}
+
+// PromptSection lists the types the LLM may suggest, one per line
+func (o *Ontology) PromptSection() string {
+	var b strings.Builder
+	for _, t := range o.types {
+		if t.Internal {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %s", t.Name, t.Description)
+		if t.Symmetric {
+			b.WriteString(" (symmetric)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
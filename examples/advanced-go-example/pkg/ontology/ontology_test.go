@@ -0,0 +1,179 @@
+package ontology
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	o, err := New([]RelationshipType{
+		{Name: "RELATES_TO", Symmetric: true},
+		{Name: "SOLVED_BY", Inverse: "SOLVES"},
+		{Name: "CITES", Properties: []string{"page"}},
+		{Name: "NEXT", Internal: true},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		from, to   int64
+		relType    string
+		properties map[string]interface{}
+		wantFrom   int64
+		wantTo     int64
+		wantType   string
+		wantErr    string
+	}{
+		{name: "plain type", from: 1, to: 2, relType: "SOLVED_BY", wantFrom: 1, wantTo: 2, wantType: "SOLVED_BY"},
+		{name: "plain type keeps a descending direction", from: 9, to: 2, relType: "SOLVED_BY", wantFrom: 9, wantTo: 2, wantType: "SOLVED_BY"},
+		{name: "inverse name is reversed", from: 1, to: 2, relType: "SOLVES", wantFrom: 2, wantTo: 1, wantType: "SOLVED_BY"},
+		{name: "symmetric from the lower ID", from: 2, to: 1, relType: "RELATES_TO", wantFrom: 1, wantTo: 2, wantType: "RELATES_TO"},
+		{name: "symmetric already ordered", from: 1, to: 2, relType: "RELATES_TO", wantFrom: 1, wantTo: 2, wantType: "RELATES_TO"},
+		{
+			name: "allowed and system properties", from: 1, to: 2, relType: "CITES",
+			properties: map[string]interface{}{"page": 3.0, "confidence": 0.9, "reason": "r", "auto_detected": true},
+			wantFrom:   1, wantTo: 2, wantType: "CITES",
+		},
+		{
+			name: "any property without a list", from: 1, to: 2, relType: "RELATES_TO",
+			properties: map[string]interface{}{"anything": "goes"},
+			wantFrom:   1, wantTo: 2, wantType: "RELATES_TO",
+		},
+		{name: "unknown type", from: 1, to: 2, relType: "LIKES", wantErr: `unknown relationship type "LIKES" (known: RELATES_TO, SOLVED_BY, SOLVES, CITES)`},
+		{name: "empty type", from: 1, to: 2, relType: "", wantErr: "unknown relationship type"},
+		{name: "names are case sensitive", from: 1, to: 2, relType: "solves", wantErr: "unknown relationship type"},
+		{name: "internal type", from: 1, to: 2, relType: "NEXT", wantErr: "NEXT is managed by the server"},
+		{
+			name: "disallowed properties", from: 1, to: 2, relType: "CITES",
+			properties: map[string]interface{}{"z": 1, "a": 2, "page": 3},
+			wantErr:    "CITES doesn't allow properties a, z (allowed: page, reason, confidence, auto_detected)",
+		},
+		{
+			name: "properties checked on inverse names", from: 1, to: 2, relType: "SOLVES",
+			properties: map[string]interface{}{"anything": "goes"},
+			wantFrom:   2, wantTo: 1, wantType: "SOLVED_BY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, relType, err := o.Normalize(tt.from, tt.to, tt.relType, tt.properties)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Normalize() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if from != tt.wantFrom || to != tt.wantTo || relType != tt.wantType {
+				t.Errorf("Normalize() = %d -[%s]-> %d, want %d -[%s]-> %d", from, relType, to, tt.wantFrom, tt.wantType, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   []RelationshipType
+		wantErr string
+	}{
+		{name: "no types", wantErr: "at least one relationship type"},
+		{name: "only internal types", types: []RelationshipType{{Name: "NEXT", Internal: true}}, wantErr: "at least one relationship type"},
+		{name: "invalid name", types: []RelationshipType{{Name: "HAS SPACE"}}, wantErr: `invalid relationship type name "HAS SPACE"`},
+		{name: "cypher in a name", types: []RelationshipType{{Name: "A]->(b) DELETE b//"}}, wantErr: "invalid relationship type name"},
+		{name: "duplicate", types: []RelationshipType{{Name: "A"}, {Name: "A"}}, wantErr: "A is defined twice"},
+		{name: "symmetric with inverse", types: []RelationshipType{{Name: "A", Symmetric: true, Inverse: "B"}}, wantErr: "symmetric, so it can't have an inverse"},
+		{name: "invalid inverse", types: []RelationshipType{{Name: "A", Inverse: "b-a"}}, wantErr: `invalid inverse name "b-a"`},
+		{name: "inverse is a type", types: []RelationshipType{{Name: "A", Inverse: "B"}, {Name: "B"}}, wantErr: "inverse B of A is also defined as a type"},
+		{name: "inverse used twice", types: []RelationshipType{{Name: "A", Inverse: "C"}, {Name: "B", Inverse: "C"}}, wantErr: "inverse name C is used twice"},
+		{name: "invalid property", types: []RelationshipType{{Name: "A", Properties: []string{"ok", "not ok"}}}, wantErr: `invalid property name "not ok"`},
+		{name: "valid", types: []RelationshipType{{Name: "A", Inverse: "B", Properties: []string{"weight"}}, {Name: "NEXT", Internal: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.types)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("New() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	o := Default()
+	names := o.Names()
+	for _, name := range []string{"RELATES_TO", "SOLVED_BY", "SOLVES", "SUPERSEDED_BY"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("Names() = %v, missing %s", names, name)
+		}
+	}
+	if slices.Contains(names, "NEXT") {
+		t.Errorf("Names() = %v, want the internal NEXT type left out", names)
+	}
+	if prompt := o.PromptSection(); strings.Contains(prompt, "NEXT") || !strings.Contains(prompt, "- SIMILAR_TO: Very similar but different context (symmetric)\n") {
+		t.Errorf("PromptSection() = %q", prompt)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"RELATES_TO", true},
+		{"_private", true},
+		{"v2", true},
+		{"", false},
+		{"2ND", false},
+		{"HAS SPACE", false},
+		{"A]->(b) DELETE b//", false},
+		{"naïve", false},
+	}
+	for _, tt := range tests {
+		if got := ValidName(tt.name); got != tt.want {
+			t.Errorf("ValidName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `{"types": [{"name": "CITES", "description": "Quotes the target", "inverse": "CITED_BY"}]}`},
+		{name: "empty file", content: ``, wantErr: "failed to parse ontology"},
+		{name: "no types", content: `{"types": []}`, wantErr: "defines no relationship types"},
+		{name: "invalid type", content: `{"types": [{"name": "1ST"}]}`, wantErr: "invalid ontology"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ontology.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Load() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "failed to read ontology") {
+		t.Errorf("Load() of a missing file error = %v", err)
+	}
+}
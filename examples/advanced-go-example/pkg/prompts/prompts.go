@@ -0,0 +1,293 @@
+// Package prompts registers MCP prompt templates that run memory searches
+// server-side and embed the results, so any client gets memory-aware
+// workflows without a custom system prompt.
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Register adds the memory prompts to the server
+func Register(server *mcp.Server, store *storage.PostgresStore, embClient *embeddings.Client) {
+	h := &promptHandler{
+		store:      store,
+		embeddings: embClient,
+	}
+
+	groupArgument := &mcp.PromptArgument{
+		Name:        "group_id",
+		Description: "Only use memories from this group",
+	}
+	limitArgument := &mcp.PromptArgument{
+		Name:        "limit",
+		Description: "Maximum number of memories to retrieve (1-50)",
+	}
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "recall_context",
+		Title:       "Recall context for this task",
+		Description: "Retrieve memories relevant to a task and start from them",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "task", Description: "What you are about to work on", Required: true},
+			groupArgument,
+			limitArgument,
+		},
+	}, h.recallContext)
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "summarize_topic",
+		Title:       "Summarize what you know about a topic",
+		Description: "Gather memories and their graph connections about a topic and summarize them",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "topic", Description: "The topic to summarize", Required: true},
+			groupArgument,
+			limitArgument,
+		},
+	}, h.summarizeTopic)
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "review_conflicts",
+		Title:       "Review conflicting memories",
+		Description: "Find memories about a topic that contradict each other and propose how to resolve them",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "topic", Description: "The topic to check for conflicts", Required: true},
+			groupArgument,
+			limitArgument,
+		},
+	}, h.reviewConflicts)
+}
+
+// promptHandler holds dependencies for prompt handlers
+type promptHandler struct {
+	store      *storage.PostgresStore
+	embeddings *embeddings.Client
+}
+
+// promptArgs holds the parsed arguments shared by all prompts
+type promptArgs struct {
+	query   string
+	groupID string
+	limit   int
+}
+
+// parseArgs validates the required query argument and the optional group and limit
+func parseArgs(req *mcp.GetPromptRequest, queryArg string, defaultLimit int) (promptArgs, error) {
+	args := promptArgs{
+		query:   strings.TrimSpace(req.Params.Arguments[queryArg]),
+		groupID: req.Params.Arguments["group_id"],
+		limit:   defaultLimit,
+	}
+	if args.query == "" {
+		return promptArgs{}, fmt.Errorf("argument %q is required", queryArg)
+	}
+	if value := req.Params.Arguments["limit"]; value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 50 {
+			return promptArgs{}, fmt.Errorf("limit must be a number between 1 and 50, got %q", value)
+		}
+		args.limit = limit
+	}
+	return args, nil
+}
+
+// search embeds the query and runs a graph-enhanced memory search
+func (h *promptHandler) search(args promptArgs) ([]storage.SearchResult, error) {
+	queryEmbedding, err := h.embeddings.Generate(args.query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	results, err := h.store.SearchMemories(queryEmbedding, storage.SearchOptions{
+		Limit:   args.limit,
+		GroupID: args.groupID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+	return results, nil
+}
+
+func (h *promptHandler) recallContext(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := parseArgs(req, "task", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := h.search(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "I'm about to work on this task:\n\n%s\n\n", args.query)
+	if len(results) == 0 {
+		b.WriteString("The memory server has no memories relevant to it. ")
+		b.WriteString("Work from the task description, and use store_memory to save decisions, preferences and facts worth keeping for next time.")
+	} else {
+		fmt.Fprintf(&b, "These %d memories were retrieved as relevant context (most similar first; entries marked \"via graph\" are connected to a search hit):\n\n", len(results))
+		writeResults(&b, results)
+		b.WriteString("\nBefore starting, briefly state which of these memories apply to the task and how they change your approach. ")
+		b.WriteString("Ignore memories that turn out to be irrelevant, don't contradict ones that apply without saying so, ")
+		b.WriteString("and use store_memory to save any new decisions or facts that come up while working.")
+	}
+
+	return promptResult(fmt.Sprintf("Context for: %s", args.query), b.String()), nil
+}
+
+func (h *promptHandler) summarizeTopic(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := parseArgs(req, "topic", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := h.search(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk two hops from the best direct hits to pick up background the
+	// search's own one-hop expansion misses
+	seen := make(map[int64]bool)
+	for _, result := range results {
+		seen[result.Memory.ID] = true
+	}
+	var connected []storage.Memory
+	for i, result := range results {
+		if i == 3 || result.ViaRelationship {
+			break
+		}
+		memories, err := h.store.ExploreConnections(result.Memory.ID, 2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explore connections of memory %d: %w", result.Memory.ID, err)
+		}
+		for _, memory := range memories {
+			if seen[memory.ID] || (args.groupID != "" && memory.GroupID != args.groupID) {
+				continue
+			}
+			seen[memory.ID] = true
+			connected = append(connected, memory)
+		}
+	}
+
+	var b strings.Builder
+	if len(results) == 0 {
+		fmt.Fprintf(&b, "Summarize what you know about: %s\n\n", args.query)
+		b.WriteString("The memory server has no memories about this topic. Say so plainly rather than guessing, ")
+		b.WriteString("and list the questions whose answers would be worth storing.")
+		return promptResult(fmt.Sprintf("Summary of: %s", args.query), b.String()), nil
+	}
+
+	fmt.Fprintf(&b, "Summarize what is known about: %s\n\n", args.query)
+	fmt.Fprintf(&b, "Memories matching the topic (%d):\n\n", len(results))
+	writeResults(&b, results)
+	if len(connected) > 0 {
+		fmt.Fprintf(&b, "\nMemories connected to them in the knowledge graph (%d):\n\n", len(connected))
+		for _, memory := range connected {
+			writeMemory(&b, memory, "")
+		}
+	}
+	b.WriteString("\nWrite a concise summary organized by theme. Cite memory IDs like [#12] for every claim, ")
+	b.WriteString("prefer newer and more important memories when they disagree and point out the disagreement, ")
+	b.WriteString("and end with the gaps: what these memories don't tell you about the topic.")
+
+	return promptResult(fmt.Sprintf("Summary of: %s", args.query), b.String()), nil
+}
+
+func (h *promptHandler) reviewConflicts(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := parseArgs(req, "topic", 15)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := h.search(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if len(results) < 2 {
+		fmt.Fprintf(&b, "Review conflicting memories about: %s\n\n", args.query)
+		fmt.Fprintf(&b, "The memory server has %d memories about this topic, so there is nothing to compare. Say so and stop.", len(results))
+		return promptResult(fmt.Sprintf("Conflict review: %s", args.query), b.String()), nil
+	}
+
+	ids := make([]int64, len(results))
+	for i, result := range results {
+		ids[i] = result.Memory.ID
+	}
+	relationships, err := h.store.RelationshipsAmong(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "Review these memories about \"%s\" for conflicts:\n\n", args.query)
+	writeResults(&b, results)
+	if len(relationships) > 0 {
+		b.WriteString("\nRecorded relationships between them:\n\n")
+		for _, rel := range relationships {
+			fmt.Fprintf(&b, "- [#%d] -%s-> [#%d]", rel.FromID, rel.Type, rel.ToID)
+			if reason, ok := rel.Properties["reason"].(string); ok && reason != "" {
+				fmt.Fprintf(&b, ": %s", reason)
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\nFind pairs of memories that contradict each other, including outdated facts superseded by newer ones. ")
+	b.WriteString("Treat existing CONTRADICTS and SUPERSEDES edges as known conflicts. For each conflict, quote both memory IDs, ")
+	b.WriteString("explain the disagreement and recommend which one to keep, using creation dates and importance as evidence. ")
+	b.WriteString("Then propose concrete fixes: add_relationship calls (CONTRADICTS or SUPERSEDES) for conflicts not yet recorded, ")
+	b.WriteString("and corrected memories to store. Don't make changes until I confirm. If nothing conflicts, say so.")
+
+	return promptResult(fmt.Sprintf("Conflict review: %s", args.query), b.String()), nil
+}
+
+// writeResults lists search results in rank order
+func writeResults(b *strings.Builder, results []storage.SearchResult) {
+	for _, result := range results {
+		note := fmt.Sprintf("similarity %.2f", result.Similarity)
+		if result.ViaRelationship {
+			note = "via graph"
+		}
+		writeMemory(b, result.Memory, note)
+	}
+}
+
+// writeMemory formats one memory as a header line of metadata followed by its text
+func writeMemory(b *strings.Builder, memory storage.Memory, note string) {
+	var details []string
+	if note != "" {
+		details = append(details, note)
+	}
+	if memory.GroupID != "" {
+		details = append(details, "group "+memory.GroupID)
+	}
+	if len(memory.Tags) > 0 {
+		details = append(details, "tags "+strings.Join(memory.Tags, ", "))
+	}
+	if memory.Importance > 0 {
+		details = append(details, fmt.Sprintf("importance %.1f", memory.Importance))
+	}
+	if !memory.CreatedAt.IsZero() {
+		details = append(details, "stored "+memory.CreatedAt.Format("2006-01-02"))
+	}
+
+	fmt.Fprintf(b, "[#%d] (%s)\n%s\n\n", memory.ID, strings.Join(details, "; "), strings.TrimSpace(memory.Text))
+}
+
+// promptResult wraps text in a single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text}},
+		},
+	}
+}
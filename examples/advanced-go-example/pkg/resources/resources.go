@@ -0,0 +1,242 @@
+// Package resources exposes memories as MCP resources, so clients can browse
+// and pin memories into context without a tool call:
+//
+//	memory://recent            the newest memories
+//	memory://{id}              one memory
+//	memory://group/{group_id}  the newest memories in a group
+//
+// resources/list pages through every memory with a cursor, and subscribed
+// clients receive resources/updated notifications when memories change.
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	scheme    = "memory://"
+	recentURI = scheme + "recent"
+	mimeType  = "application/json"
+
+	recentLimit = 20  // Memories in memory://recent
+	groupLimit  = 100 // Memories in memory://group/{group_id}
+	pageSize    = 50  // Memories per resources/list page
+)
+
+// Register adds the memory resources and templates, replaces resources/list
+// with a paginated listing of stored memories, and sends resources/updated
+// notifications whenever the store changes.
+//
+// Subscriptions need ServerOptions.SubscribeHandler and UnsubscribeHandler,
+// which must be set when the server is created; see Subscribe and Unsubscribe.
+func Register(server *mcp.Server, store *storage.PostgresStore) {
+	r := &memoryResources{store: store}
+
+	server.AddResource(&mcp.Resource{
+		URI:         recentURI,
+		Name:        "recent-memories",
+		Title:       "Recent memories",
+		Description: fmt.Sprintf("The %d most recently stored memories", recentLimit),
+		MIMEType:    mimeType,
+	}, r.readRecent)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: scheme + "{id}",
+		Name:        "memory",
+		Title:       "Memory by ID",
+		Description: "A single memory with its metadata",
+		MIMEType:    mimeType,
+	}, r.readMemory)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: scheme + "group/{group_id}",
+		Name:        "memory-group",
+		Title:       "Memories in a group",
+		Description: fmt.Sprintf("The %d most recent memories in a group", groupLimit),
+		MIMEType:    mimeType,
+	}, r.readGroup)
+
+	server.AddReceivingMiddleware(r.listMiddleware)
+
+	store.OnChange(func(change storage.MemoryChange) {
+		// Notifications can wait on slow clients; don't hold up the writer
+		go notify(server, change)
+	})
+}
+
+// Subscribe accepts subscriptions to memory resources
+func Subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	if !strings.HasPrefix(req.Params.URI, scheme) {
+		return fmt.Errorf("cannot subscribe to %s: only %s resources are supported", req.Params.URI, scheme)
+	}
+	return nil
+}
+
+// Unsubscribe accepts unsubscribing from any resource
+func Unsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	return nil
+}
+
+// MemoryURI returns the resource URI of a memory
+func MemoryURI(id int64) string {
+	return scheme + strconv.FormatInt(id, 10)
+}
+
+// GroupURI returns the resource URI of a group
+func GroupURI(groupID string) string {
+	return scheme + "group/" + url.PathEscape(groupID)
+}
+
+// memoryResources holds dependencies for resource handlers
+type memoryResources struct {
+	store *storage.PostgresStore
+}
+
+func (r *memoryResources) readRecent(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	memories, _, err := r.store.ListMemories(storage.ListOptions{SortBy: "id", Limit: recentLimit})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(req.Params.URI, memories)
+}
+
+func (r *memoryResources) readMemory(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(req.Params.URI, scheme), 10, 64)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+
+	memory, err := r.store.GetMemoryByID(id)
+	if errors.Is(err, storage.ErrMemoryNotFound) {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(req.Params.URI, memory)
+}
+
+func (r *memoryResources) readGroup(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	groupID, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, scheme+"group/"))
+	if err != nil || groupID == "" {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+
+	memories, _, err := r.store.ListMemories(storage.ListOptions{GroupID: groupID, SortBy: "id", Limit: groupLimit})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(req.Params.URI, memories)
+}
+
+// listMiddleware answers resources/list from the database instead of the
+// server's static resource list, so every memory is listed a page at a time
+func (r *memoryResources) listMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "resources/list" {
+			return next(ctx, method, req)
+		}
+
+		var cursor string
+		if params, ok := req.GetParams().(*mcp.ListResourcesParams); ok && params != nil {
+			cursor = params.Cursor
+		}
+		return r.list(cursor)
+	}
+}
+
+// list returns one page of resources: memory://recent first, then every
+// memory newest first
+func (r *memoryResources) list(cursor string) (*mcp.ListResourcesResult, error) {
+	result := &mcp.ListResourcesResult{Resources: []*mcp.Resource{}}
+	if cursor == "" {
+		result.Resources = append(result.Resources, &mcp.Resource{
+			URI:      recentURI,
+			Name:     "recent-memories",
+			Title:    "Recent memories",
+			MIMEType: mimeType,
+		})
+	}
+
+	// The store's keyset cursor is opaque, so it doubles as the list cursor
+	memories, next, err := r.store.ListMemories(storage.ListOptions{SortBy: "id", Limit: pageSize, Cursor: cursor})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, memory := range memories {
+		result.Resources = append(result.Resources, &mcp.Resource{
+			URI:         MemoryURI(memory.ID),
+			Name:        fmt.Sprintf("memory-%d", memory.ID),
+			Title:       title(memory.Text),
+			Description: describe(memory),
+			MIMEType:    mimeType,
+		})
+	}
+	result.NextCursor = next
+
+	return result, nil
+}
+
+// notify sends resources/updated for the changed memories, their groups and
+// memory://recent
+func notify(server *mcp.Server, change storage.MemoryChange) {
+	ctx := context.Background()
+	uris := []string{recentURI}
+	for _, id := range change.IDs {
+		uris = append(uris, MemoryURI(id))
+	}
+	for _, groupID := range change.GroupIDs {
+		if groupID != "" {
+			uris = append(uris, GroupURI(groupID))
+		}
+	}
+
+	for _, uri := range uris {
+		server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+}
+
+// jsonResult renders value as an indented JSON resource
+func jsonResult(uri string, value interface{}) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: mimeType, Text: string(data)}},
+	}, nil
+}
+
+// title shortens memory text to a one-line resource title
+func title(text string) string {
+	const maxTitle = 60
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxTitle {
+		return string(runes[:maxTitle-1]) + "…"
+	}
+	return text
+}
+
+// describe summarizes a memory's group and tags for the resource list
+func describe(memory storage.Memory) string {
+	var parts []string
+	if memory.GroupID != "" {
+		parts = append(parts, "group: "+memory.GroupID)
+	}
+	if len(memory.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(memory.Tags, ", "))
+	}
+	parts = append(parts, "stored "+memory.CreatedAt.Format("2006-01-02"))
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// accessFlushInterval is how often buffered access events are written
+const accessFlushInterval = 2 * time.Second
+
+// accessFlushSize flushes early once this many distinct memories are pending
+const accessFlushSize = 500
+
+// accessTracker records memory reads off the read path. Reads hand their IDs
+// to a buffered channel and a single goroutine aggregates them into batched
+// UPDATEs, so a search never waits on a write.
+type accessTracker struct {
+	db     *sql.DB
+	events chan []int64
+	done   chan struct{}
+
+	// Reads may still be running when the store closes, e.g. in HTTP
+	// handlers or job workers that outlive shutdown; closed makes their
+	// record calls no-ops instead of sends on a closed channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAccessTracker(db *sql.DB) *accessTracker {
+	t := &accessTracker{
+		db:     db,
+		events: make(chan []int64, 1024),
+		done:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// record queues an access for each ID. It never blocks: when the buffer is
+// full the event is dropped, since access counts are a ranking signal, not a ledger.
+func (t *accessTracker) record(ids []int64) {
+	if len(ids) == 0 {
+		return
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.events <- ids:
+	default:
+		log.Printf("access tracker: buffer full, dropping %d access events", len(ids))
+	}
+}
+
+// close flushes pending events and stops the goroutine. Later record calls
+// are dropped.
+func (t *accessTracker) close() {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.events)
+	}
+	t.mu.Unlock()
+	<-t.done
+}
+
+func (t *accessTracker) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(accessFlushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[int64]int64) // memory ID -> accesses since last flush
+	for {
+		select {
+		case ids, ok := <-t.events:
+			if !ok {
+				t.flush(pending)
+				return
+			}
+			for _, id := range ids {
+				pending[id]++
+			}
+			if len(pending) >= accessFlushSize {
+				t.flush(pending)
+				pending = make(map[int64]int64)
+			}
+		case <-ticker.C:
+			if len(pending) > 0 {
+				t.flush(pending)
+				pending = make(map[int64]int64)
+			}
+		}
+	}
+}
+
+// flush applies the aggregated counts in a single UPDATE
+func (t *accessTracker) flush(pending map[int64]int64) {
+	if len(pending) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(pending))
+	counts := make([]int64, 0, len(pending))
+	for id, count := range pending {
+		ids = append(ids, id)
+		counts = append(counts, count)
+	}
+
+	query := `
+		UPDATE memories m
+		SET access_count = m.access_count + a.n, last_accessed_at = now()
+		FROM unnest($1::bigint[], $2::bigint[]) AS a(id, n)
+		WHERE m.id = a.id
+	`
+	if _, err := t.db.Exec(query, pq.Array(ids), pq.Array(counts)); err != nil {
+		log.Printf("access tracker: failed to record %d accesses: %v", len(ids), err)
+	}
+}
+
+// AccessReport lists the hottest and coldest memories
+type AccessReport struct {
+	Hot  []Memory `json:"hot"`  // Most frequently accessed
+	Cold []Memory `json:"cold"` // Never accessed, or not since the cutoff
+}
+
+// GetAccessReport returns up to limit hot and cold memories, optionally within
+// one group. A memory is cold when it has not been accessed for coldAfter.
+func (s *PostgresStore) GetAccessReport(limit int, coldAfter time.Duration, groupID string) (_ *AccessReport, err error) {
+	defer s.observe("GetAccessReport")(&err)
+	groupCondition := ""
+	args := queryArgs{}
+	if groupID != "" {
+		groupCondition = " AND group_id = " + args.add(groupID)
+	}
+	limitArg := args.add(limit)
+
+	hotQuery := fmt.Sprintf(`
+		SELECT %s FROM memories
+		WHERE access_count > 0%s
+		ORDER BY access_count DESC, last_accessed_at DESC
+		LIMIT %s
+	`, memoryColumns, groupCondition, limitArg)
+
+	hot, err := s.queryMemories(hotQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hot memories: %w", err)
+	}
+
+	cutoff := args.add(time.Now().Add(-coldAfter))
+	coldQuery := fmt.Sprintf(`
+		SELECT %s FROM memories
+		WHERE (last_accessed_at IS NULL OR last_accessed_at < %s)%s
+		ORDER BY last_accessed_at ASC NULLS FIRST, created_at ASC
+		LIMIT %s
+	`, memoryColumns, cutoff, groupCondition, limitArg)
+
+	cold, err := s.queryMemories(coldQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cold memories: %w", err)
+	}
+
+	return &AccessReport{Hot: hot, Cold: cold}, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// GraphSnapshot is the memory graph exported for analysis in Go
+type GraphSnapshot struct {
+	MemoryIDs []int64        // Unexpired memories in scope, in ID order
+	Edges     []Relationship // Edges with both ends in scope
+}
+
+// GraphSnapshot exports the IDs of unexpired memories, optionally only those
+// in one group, and the edges between them
+func (s *PostgresStore) GraphSnapshot(groupID string) (_ *GraphSnapshot, err error) {
+	defer s.observe("GraphSnapshot")(&err)
+	args := queryArgs{}
+	conditions, err := metadataConditions(SearchOptions{GroupID: groupID}, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT id FROM memories WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id"
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memory ids: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot := &GraphSnapshot{}
+	inScope := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan memory id: %w", err)
+		}
+		snapshot.MemoryIDs = append(snapshot.MemoryIDs, id)
+		inScope[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memory ids: %w", err)
+	}
+
+	err = s.ExportRelationships(func(rel Relationship) error {
+		if inScope[rel.FromID] && inScope[rel.ToID] {
+			snapshot.Edges = append(snapshot.Edges, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// SetClusters replaces the saved communities of the memories in scope
+// (groupID "" for all groups): members of clusters get its value as their
+// cluster_id, every other memory in scope is cleared
+func (s *PostgresStore) SetClusters(groupID string, clusters map[int64]int64) (err error) {
+	defer s.observe("SetClusters")(&err)
+	ids := make([]int64, 0, len(clusters))
+	clusterIDs := make([]int64, 0, len(clusters))
+	for id, clusterID := range clusters {
+		ids = append(ids, id)
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reset := "UPDATE memories SET cluster_id = NULL WHERE cluster_id IS NOT NULL"
+	args := []interface{}{}
+	if groupID != "" {
+		reset += " AND group_id = $1"
+		args = append(args, groupID)
+	}
+	if _, err := tx.Exec(reset, args...); err != nil {
+		return fmt.Errorf("failed to clear clusters: %w", err)
+	}
+
+	if len(ids) > 0 {
+		_, err := tx.Exec(`
+			UPDATE memories m SET cluster_id = c.cluster_id
+			FROM unnest($1::bigint[], $2::bigint[]) AS c(id, cluster_id)
+			WHERE m.id = c.id
+		`, pq.Array(ids), pq.Array(clusterIDs))
+		if err != nil {
+			return fmt.Errorf("failed to save clusters: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit clusters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// AutoDetectedMemoryIDs returns the memories at either end of an
+// auto-detected edge
+func (s *PostgresStore) AutoDetectedMemoryIDs() (_ map[int64]bool, err error) {
+	defer s.observe("AutoDetectedMemoryIDs")(&err)
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	query := `
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]-(b:Memory)
+			WHERE r.auto_detected = true
+			RETURN DISTINCT a.id
+		$$) as (id agtype);
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find auto-detected memories: %w", err)
+	}
+	defer rows.Close()
+
+	ids := map[int64]bool{}
+	for rows.Next() {
+		// agtype scalars are JSON-compatible
+		var idJSON string
+		if err := rows.Scan(&idJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan memory id: %w", err)
+		}
+		var id int64
+		if err := json.Unmarshal([]byte(idJSON), &id); err != nil {
+			return nil, fmt.Errorf("failed to parse memory id %s: %w", idJSON, err)
+		}
+		ids[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memory ids: %w", err)
+	}
+	return ids, nil
+}
+
+// NearestNeighbors finds up to k unexpired memories most similar to each of
+// ids in a single query, using the stored embeddings. Neighbours below
+// minSimilarity are left out; results are ordered by similarity.
+func (s *PostgresStore) NearestNeighbors(ids []int64, k int, minSimilarity float64) (_ map[int64][]SearchResult, err error) {
+	defer s.observe("NearestNeighbors")(&err)
+	query := fmt.Sprintf(`
+		SELECT n.*, s.id
+		FROM memories s
+		CROSS JOIN LATERAL (
+			SELECT %s, 1 - (m.embedding <=> s.embedding) AS similarity
+			FROM memories m
+			WHERE m.id <> s.id AND (m.expires_at IS NULL OR m.expires_at > now())
+			ORDER BY m.embedding <=> s.embedding
+			LIMIT $2
+		) n
+		WHERE s.id = ANY($1) AND n.similarity >= $3
+		ORDER BY s.id, n.similarity DESC
+	`, memoryColumns)
+
+	rows, err := s.db.Query(query, pq.Array(ids), k, minSimilarity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find nearest neighbors: %w", err)
+	}
+	defer rows.Close()
+
+	neighbors := make(map[int64][]SearchResult, len(ids))
+	for rows.Next() {
+		var similarity float64
+		var sourceID int64
+		memory, err := scanMemory(rows, &similarity, &sourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan neighbor: %w", err)
+		}
+		neighbors[sourceID] = append(neighbors[sourceID], SearchResult{Memory: memory, Similarity: similarity})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating neighbors: %w", err)
+	}
+	return neighbors, nil
+}
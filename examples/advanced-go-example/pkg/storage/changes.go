@@ -0,0 +1,29 @@
+package storage
+
+import "slices"
+
+// MemoryChange describes memories that were created, updated or deleted
+type MemoryChange struct {
+	IDs      []int64
+	GroupIDs []string // Distinct groups touched; "" stands for memories without a group
+	Deleted  bool
+}
+
+// OnChange registers fn to be called after memories are written or deleted.
+// Listeners must be registered before the store is shared between goroutines.
+func (s *PostgresStore) OnChange(fn func(MemoryChange)) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// notifyChange calls every listener with the change
+func (s *PostgresStore) notifyChange(ids []int64, groupIDs []string, deleted bool) {
+	if len(ids) == 0 || len(s.listeners) == 0 {
+		return
+	}
+
+	slices.Sort(groupIDs)
+	change := MemoryChange{IDs: ids, GroupIDs: slices.Compact(groupIDs), Deleted: deleted}
+	for _, fn := range s.listeners {
+		fn(change)
+	}
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ExportMemories calls fn for every memory in ID order, including expired
+// ones. groupID "" exports all groups. Embeddings are only read when requested.
+func (s *PostgresStore) ExportMemories(groupID string, includeEmbeddings bool, fn func(Memory) error) (err error) {
+	defer s.observe("ExportMemories")(&err)
+	columns := memoryColumns + ", NULL::text"
+	if includeEmbeddings {
+		columns = memoryColumns + ", embedding::text"
+	}
+
+	args := queryArgs{}
+	query := fmt.Sprintf("SELECT %s FROM memories", columns)
+	if groupID != "" {
+		query += " WHERE group_id = " + args.add(groupID)
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export memories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var embedding sql.NullString
+		memory, err := scanMemory(rows, &embedding)
+		if err != nil {
+			return fmt.Errorf("failed to scan memory: %w", err)
+		}
+
+		if embedding.Valid {
+			if memory.Embedding, err = parseVector(embedding.String); err != nil {
+				return fmt.Errorf("failed to parse embedding of memory %d: %w", memory.ID, err)
+			}
+		}
+
+		if err := fn(memory); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating memories: %w", err)
+	}
+	return nil
+}
+
+// ExportRelationships calls fn for every edge in the memory graph
+func (s *PostgresStore) ExportRelationships(fn func(Relationship) error) (err error) {
+	defer s.observe("ExportRelationships")(&err)
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	query := `
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]->(b:Memory)
+			RETURN id(r), a.id, b.id, type(r), properties(r)
+		$$) as (edge_id agtype, from_id agtype, to_id agtype, rel_type agtype, properties agtype);
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to export relationships: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(rel); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating relationships: %w", err)
+	}
+	return nil
+}
+
+// ImportMemory inserts a memory, or updates the one with the same external ID.
+// Timestamps from the memory are kept when set. It reports whether the memory was new.
+func (s *PostgresStore) ImportMemory(memory Memory) (_ int64, _ bool, err error) {
+	defer s.observe("ImportMemory")(&err)
+	if memory.ExternalID == "" {
+		return 0, false, fmt.Errorf("imported memories need an external ID")
+	}
+
+	attributes, err := marshalAttributes(memory.Attributes)
+	if err != nil {
+		return 0, false, err
+	}
+
+	query := `
+		INSERT INTO memories (external_id, text, embedding, group_id, tags, source, importance, attributes, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, now()), COALESCE($11, now()))
+		ON CONFLICT (external_id) DO UPDATE SET
+			text = EXCLUDED.text,
+			embedding = EXCLUDED.embedding,
+			group_id = EXCLUDED.group_id,
+			tags = EXCLUDED.tags,
+			source = EXCLUDED.source,
+			importance = EXCLUDED.importance,
+			attributes = EXCLUDED.attributes,
+			expires_at = EXCLUDED.expires_at
+		RETURNING id, (xmax = 0) AS inserted
+	`
+
+	var id int64
+	var inserted bool
+	err = s.db.QueryRow(
+		query,
+		memory.ExternalID,
+		memory.Text,
+		toVector(memory.Embedding),
+		memory.GroupID,
+		pq.Array(normalizeTags(memory.Tags)),
+		memory.Source,
+		memory.Importance,
+		string(attributes),
+		sql.NullTime{Time: memory.ExpiresAt, Valid: !memory.ExpiresAt.IsZero()},
+		sql.NullTime{Time: memory.CreatedAt, Valid: !memory.CreatedAt.IsZero()},
+		sql.NullTime{Time: memory.UpdatedAt, Valid: !memory.UpdatedAt.IsZero()},
+	).Scan(&id, &inserted)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to import memory %s: %w", memory.ExternalID, err)
+	}
+
+	// The node text follows the row, so updates keep the graph in sync
+	if err := s.upsertMemoryNode(id, memory.Text); err != nil {
+		return 0, false, err
+	}
+
+	s.notifyChange([]int64{id}, []string{memory.GroupID}, false)
+	return id, inserted, nil
+}
+
+// MemoryIDsByExternalID resolves external IDs to memory IDs. Unknown IDs are left out.
+func (s *PostgresStore) MemoryIDsByExternalID(externalIDs []string) (_ map[string]int64, err error) {
+	defer s.observe("MemoryIDsByExternalID")(&err)
+	ids := make(map[string]int64)
+	if len(externalIDs) == 0 {
+		return ids, nil
+	}
+
+	rows, err := s.db.Query("SELECT external_id, id FROM memories WHERE external_id = ANY($1)", pq.Array(externalIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve external IDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID string
+		var id int64
+		if err := rows.Scan(&externalID, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan external ID: %w", err)
+		}
+		ids[externalID] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating external IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// ExternalIDsWithPrefix returns the memories whose external ID starts with prefix
+func (s *PostgresStore) ExternalIDsWithPrefix(prefix string) (_ map[string]int64, err error) {
+	defer s.observe("ExternalIDsWithPrefix")(&err)
+	// starts_with avoids treating % and _ in the prefix as LIKE wildcards
+	rows, err := s.db.Query("SELECT external_id, id FROM memories WHERE starts_with(external_id, $1)", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up external IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var externalID string
+		var id int64
+		if err := rows.Scan(&externalID, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan external ID: %w", err)
+		}
+		ids[externalID] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating external IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteMemories removes memories and their graph nodes and edges
+func (s *PostgresStore) DeleteMemories(ids []int64) (err error) {
+	defer s.observe("DeleteMemories")(&err)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin delete transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	groupIDs, err := deleteMemoriesTx(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
+	}
+
+	s.notifyChange(ids, groupIDs, true)
+	return nil
+}
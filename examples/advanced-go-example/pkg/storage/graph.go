@@ -0,0 +1,900 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"advanced-go-example/pkg/ontology"
+
+	"github.com/lib/pq"
+)
+
+// ErrRelationshipNotFound is returned when an edge ID doesn't exist
+var ErrRelationshipNotFound = errors.New("relationship not found")
+
+// Relationship is a directed graph edge between two memories. The ID is
+// Apache AGE's edge ID: it stays the same until the edge is deleted.
+type Relationship struct {
+	ID         int64                  `json:"id,omitzero"`
+	FromID     int64                  `json:"from_id"`
+	ToID       int64                  `json:"to_id"`
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties,omitzero"`
+}
+
+// RelationshipFilter selects edges for ListRelationships; zero values match everything
+type RelationshipFilter struct {
+	MemoryID     int64  // Edges starting or ending at this memory
+	Type         string // Edges of this type
+	AutoDetected *bool  // Edges created (true) or not created (false) by auto-detection
+	Limit        int    // 0 means no limit
+}
+
+// RelationshipUpdate describes a change to an edge
+type RelationshipUpdate struct {
+	Type   string                 // New type; changing it replaces the edge, giving it a new ID
+	Set    map[string]interface{} // Properties to add or overwrite
+	Remove []string               // Properties to remove
+}
+
+// maxTraversalNodes bounds how much of the graph a traversal loads before
+// giving up on reaching further
+const maxTraversalNodes = 10000
+
+// Traversal directions, relative to the stored direction of each edge
+const (
+	DirectionOut  = "out"  // Follow edges from source to target
+	DirectionIn   = "in"   // Follow edges from target back to source
+	DirectionBoth = "both" // Ignore edge direction
+)
+
+// TraversalOptions controls which edges Traverse follows
+type TraversalOptions struct {
+	MaxDepth      int
+	Direction     string   // DirectionOut, DirectionIn or DirectionBoth (default)
+	Types         []string // Only follow these relationship types (all when empty)
+	ExcludeTypes  []string // Never follow these relationship types
+	MinConfidence float64  // Skip edges with a lower confidence property; edges without one always pass
+}
+
+// Subgraph is the part of the graph reached by a traversal
+type Subgraph struct {
+	Nodes []SubgraphNode `json:"nodes"`
+	Edges []SubgraphEdge `json:"edges"`
+}
+
+// SubgraphNode is a memory reached by a traversal
+type SubgraphNode struct {
+	Memory Memory `json:"memory"`
+	Depth  int    `json:"depth"` // Hops from the start memory
+}
+
+// SubgraphEdge is an edge followed by a traversal, in its stored direction
+type SubgraphEdge struct {
+	ID         int64                  `json:"id"`
+	FromID     int64                  `json:"from_id"`
+	ToID       int64                  `json:"to_id"`
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties,omitzero"`
+	Depth      int                    `json:"depth"` // Hop at which the edge was followed
+}
+
+// PathOptions controls FindPaths
+type PathOptions struct {
+	MaxDepth int      // Longest path considered, in edges
+	Limit    int      // Number of paths to return, shortest first
+	Types    []string // Only follow these relationship types (all when empty)
+}
+
+// Path is a chain of memories joined by edges. Edges keep their stored
+// direction, which may run against the path.
+type Path struct {
+	MemoryIDs []int64        `json:"memory_ids"`
+	Edges     []Relationship `json:"edges"`
+}
+
+// RelationshipsAmong returns the edges whose endpoints are both in ids
+func (s *PostgresStore) RelationshipsAmong(ids []int64) (_ []Relationship, err error) {
+	defer s.observe("RelationshipsAmong")(&err)
+	if len(ids) < 2 {
+		return []Relationship{}, nil
+	}
+
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	idList := make([]string, len(ids))
+	for i, id := range ids {
+		idList[i] = strconv.FormatInt(id, 10)
+	}
+	query := fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]->(b:Memory)
+			WHERE a.id IN [%[1]s] AND b.id IN [%[1]s]
+			RETURN id(r), a.id, b.id, type(r), properties(r)
+		$$) as (edge_id agtype, from_id agtype, to_id agtype, rel_type agtype, properties agtype);
+	`, strings.Join(idList, ", "))
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relationships: %w", err)
+	}
+	defer rows.Close()
+
+	relationships := []Relationship{}
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, rel)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// FindPaths returns up to opts.Limit simple paths between two memories,
+// shortest first, following edges in either direction. Paths of equal length
+// are returned in no particular order. Expired memories are left out.
+func (s *PostgresStore) FindPaths(fromID, toID int64, opts PathOptions) (_ []Path, err error) {
+	defer s.observe("FindPaths")(&err)
+	if fromID == toID {
+		return nil, fmt.Errorf("a path needs two different memories")
+	}
+	traversal := TraversalOptions{Direction: DirectionBoth, Types: opts.Types}
+	if err := traversal.validate(); err != nil {
+		return nil, err
+	}
+
+	// No path runs through an expired memory, including the endpoints
+	live, err := s.liveMemoryIDs([]int64{fromID, toID})
+	if err != nil {
+		return nil, err
+	}
+	if !live[fromID] || !live[toID] {
+		return []Path{}, nil
+	}
+
+	// Breadth-first expansion from the source. Every edge on a path of length
+	// <= MaxDepth touches a node closer than MaxDepth, so this loads all of them.
+	adjacency := make(map[int64][]Relationship)
+	seenEdges := make(map[string]bool)
+	visited := map[int64]bool{fromID: true}
+	expired := make(map[int64]bool)
+	frontier := []int64{fromID}
+	for depth := 0; depth < opts.MaxDepth && len(frontier) > 0 && len(visited) < maxTraversalNodes; depth++ {
+		edges, err := s.edgesAround(frontier, traversal)
+		if err != nil {
+			return nil, err
+		}
+
+		var reached []int64
+		for _, edge := range edges {
+			for _, id := range []int64{edge.FromID, edge.ToID} {
+				if !visited[id] && !expired[id] {
+					reached = append(reached, id)
+				}
+			}
+		}
+		live, err := s.liveMemoryIDs(reached)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range reached {
+			if !live[id] {
+				expired[id] = true
+			}
+		}
+
+		var next []int64
+		for _, edge := range edges {
+			if expired[edge.FromID] || expired[edge.ToID] {
+				continue
+			}
+			key := edgeKey(edge)
+			if seenEdges[key] {
+				continue
+			}
+			seenEdges[key] = true
+			adjacency[edge.FromID] = append(adjacency[edge.FromID], edge)
+			adjacency[edge.ToID] = append(adjacency[edge.ToID], edge)
+
+			for _, id := range []int64{edge.FromID, edge.ToID} {
+				if !visited[id] {
+					visited[id] = true
+					next = append(next, id)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return enumeratePaths(adjacency, fromID, toID, opts), nil
+}
+
+// liveMemoryIDs returns which of ids name stored memories that have not expired
+func (s *PostgresStore) liveMemoryIDs(ids []int64) (map[int64]bool, error) {
+	live := make(map[int64]bool)
+	if len(ids) == 0 {
+		return live, nil
+	}
+
+	rows, err := s.db.Query("SELECT id FROM memories WHERE id = ANY($1) AND (expires_at IS NULL OR expires_at > now())", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check memories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan memory id: %w", err)
+		}
+		live[id] = true
+	}
+	return live, rows.Err()
+}
+
+// enumeratePaths lists simple paths through adjacency one length at a time,
+// so shorter paths come first
+func enumeratePaths(adjacency map[int64][]Relationship, fromID, toID int64, opts PathOptions) []Path {
+	// Distances to the target prune the enumeration below
+	distance := map[int64]int{toID: 0}
+	queue := []int64{toID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, edge := range adjacency[id] {
+			neighbour := otherEnd(edge, id)
+			if _, ok := distance[neighbour]; !ok {
+				distance[neighbour] = distance[id] + 1
+				queue = append(queue, neighbour)
+			}
+		}
+	}
+	shortest, ok := distance[fromID]
+	if !ok {
+		return []Path{}
+	}
+
+	paths := []Path{}
+	onPath := map[int64]bool{fromID: true}
+	var ids []int64
+	var edges []Relationship
+	var walk func(id int64, remaining int)
+	walk = func(id int64, remaining int) {
+		if len(paths) >= opts.Limit {
+			return
+		}
+		if id == toID {
+			if remaining == 0 {
+				paths = append(paths, Path{
+					MemoryIDs: append([]int64{fromID}, ids...),
+					Edges:     append([]Relationship{}, edges...),
+				})
+			}
+			return
+		}
+		for _, edge := range adjacency[id] {
+			neighbour := otherEnd(edge, id)
+			if dist, ok := distance[neighbour]; !ok || dist > remaining-1 || onPath[neighbour] {
+				continue
+			}
+			onPath[neighbour] = true
+			ids = append(ids, neighbour)
+			edges = append(edges, edge)
+			walk(neighbour, remaining-1)
+			onPath[neighbour] = false
+			ids = ids[:len(ids)-1]
+			edges = edges[:len(edges)-1]
+		}
+	}
+	for length := shortest; length <= opts.MaxDepth && len(paths) < opts.Limit; length++ {
+		walk(fromID, length)
+	}
+
+	return paths
+}
+
+// Traverse walks the graph breadth-first from startID and returns the
+// reached memories with their depth plus every edge followed on the way.
+// The start memory is included at depth 0; expired memories are left out.
+func (s *PostgresStore) Traverse(startID int64, opts TraversalOptions) (_ *Subgraph, err error) {
+	defer s.observe("Traverse")(&err)
+	if opts.Direction == "" {
+		opts.Direction = DirectionBoth
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// Nothing is reached through an expired memory, including the start
+	live, err := s.liveMemoryIDs([]int64{startID})
+	if err != nil {
+		return nil, err
+	}
+	depths := map[int64]int{startID: 0}
+	ids := []int64{startID}
+	seenEdges := make(map[string]bool)
+	var edges []SubgraphEdge
+	var frontier []int64
+	if live[startID] {
+		frontier = []int64{startID}
+	}
+	for depth := 1; depth <= opts.MaxDepth && len(frontier) > 0 && len(depths) < maxTraversalNodes; depth++ {
+		rels, err := s.edgesAround(frontier, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []int64
+		for _, rel := range rels {
+			key := edgeKey(rel)
+			if seenEdges[key] {
+				continue
+			}
+			seenEdges[key] = true
+			edges = append(edges, SubgraphEdge{
+				ID:         rel.ID,
+				FromID:     rel.FromID,
+				ToID:       rel.ToID,
+				Type:       rel.Type,
+				Properties: rel.Properties,
+				Depth:      depth,
+			})
+
+			// Only the end the traversal moves towards is reached
+			var reached []int64
+			switch opts.Direction {
+			case DirectionOut:
+				reached = []int64{rel.ToID}
+			case DirectionIn:
+				reached = []int64{rel.FromID}
+			default:
+				reached = []int64{rel.FromID, rel.ToID}
+			}
+			for _, id := range reached {
+				if _, ok := depths[id]; !ok {
+					depths[id] = depth
+					next = append(next, id)
+				}
+			}
+		}
+
+		// Expired memories keep their depth so they are not checked again,
+		// but are neither returned nor expanded
+		live, err := s.liveMemoryIDs(next)
+		if err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, id := range next {
+			if live[id] {
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+
+	memories, err := s.getMemoriesByIDs(ids, SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch connected memories: %w", err)
+	}
+
+	subgraph := &Subgraph{Nodes: []SubgraphNode{}, Edges: []SubgraphEdge{}}
+	found := make(map[int64]bool)
+	var connectedIDs []int64
+	for _, memory := range memories {
+		found[memory.ID] = true
+		subgraph.Nodes = append(subgraph.Nodes, SubgraphNode{Memory: memory, Depth: depths[memory.ID]})
+		if memory.ID != startID {
+			connectedIDs = append(connectedIDs, memory.ID)
+		}
+	}
+	sort.Slice(subgraph.Nodes, func(i, j int) bool {
+		a, b := subgraph.Nodes[i], subgraph.Nodes[j]
+		return a.Depth < b.Depth || (a.Depth == b.Depth && a.Memory.ID < b.Memory.ID)
+	})
+
+	// Drop edges to memories that have expired or were never stored
+	for _, edge := range edges {
+		if found[edge.FromID] && found[edge.ToID] {
+			subgraph.Edges = append(subgraph.Edges, edge)
+		}
+	}
+
+	s.access.record(connectedIDs)
+	return subgraph, nil
+}
+
+// validate checks the direction and relationship type names before they are spliced into Cypher
+func (o TraversalOptions) validate() error {
+	switch o.Direction {
+	case DirectionOut, DirectionIn, DirectionBoth:
+	default:
+		return fmt.Errorf("direction must be %q, %q or %q, got %q", DirectionOut, DirectionIn, DirectionBoth, o.Direction)
+	}
+	for _, relType := range append(append([]string{}, o.Types...), o.ExcludeTypes...) {
+		if !ontology.ValidName(relType) {
+			return fmt.Errorf("invalid relationship type %q", relType)
+		}
+	}
+	return nil
+}
+
+// edgesAround returns the edges leaving (out), entering (in) or touching (both)
+// the memories in ids that pass the type and confidence filters in opts
+func (s *PostgresStore) edgesAround(ids []int64, opts TraversalOptions) ([]Relationship, error) {
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	idList := make([]string, len(ids))
+	for i, id := range ids {
+		idList[i] = strconv.FormatInt(id, 10)
+	}
+	var where string
+	switch opts.Direction {
+	case DirectionOut:
+		where = fmt.Sprintf("a.id IN [%s]", strings.Join(idList, ", "))
+	case DirectionIn:
+		where = fmt.Sprintf("b.id IN [%s]", strings.Join(idList, ", "))
+	default:
+		where = fmt.Sprintf("(a.id IN [%[1]s] OR b.id IN [%[1]s])", strings.Join(idList, ", "))
+	}
+	if len(opts.Types) > 0 {
+		where += fmt.Sprintf(" AND type(r) IN ['%s']", strings.Join(opts.Types, "', '"))
+	}
+	if len(opts.ExcludeTypes) > 0 {
+		where += fmt.Sprintf(" AND NOT type(r) IN ['%s']", strings.Join(opts.ExcludeTypes, "', '"))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]->(b:Memory)
+			WHERE %s
+			RETURN id(r), a.id, b.id, type(r), properties(r)
+		$$) as (edge_id agtype, from_id agtype, to_id agtype, rel_type agtype, properties agtype);
+	`, where)
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var relationships []Relationship
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return nil, err
+		}
+		// Confidence is compared here since it may be stored as a number or a string
+		if opts.MinConfidence > 0 && EdgeConfidence(rel) < opts.MinConfidence {
+			continue
+		}
+		relationships = append(relationships, rel)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// graphNeighbor is a memory reached from a search seed by graph expansion
+type graphNeighbor struct {
+	ID       int64
+	Hops     int
+	SeedID   int64        // The search result the expansion started from
+	ParentID int64        // The memory one hop closer to the seed
+	Edge     Relationship // The edge the neighbour was reached through
+}
+
+// expandNeighbors finds up to limit memories within maxDepth hops of seeds,
+// following edges in either direction, with one batched edge query per hop.
+// Seeds are expanded in order, and each seed's edges by descending
+// confidence, so the cap keeps the neighbours of the best results.
+func (s *PostgresStore) expandNeighbors(seeds []int64, maxDepth, limit int) ([]graphNeighbor, error) {
+	visited := make(map[int64]bool, len(seeds))
+	seedOf := make(map[int64]int64, len(seeds))
+	for _, id := range seeds {
+		visited[id] = true
+		seedOf[id] = id
+	}
+
+	var neighbors []graphNeighbor
+	frontier := seeds
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		edges, err := s.edgesAround(frontier, TraversalOptions{Direction: DirectionBoth})
+		if err != nil {
+			return nil, err
+		}
+
+		adjacency := make(map[int64][]Relationship)
+		for _, edge := range edges {
+			adjacency[edge.FromID] = append(adjacency[edge.FromID], edge)
+			if edge.ToID != edge.FromID {
+				adjacency[edge.ToID] = append(adjacency[edge.ToID], edge)
+			}
+		}
+
+		var next []int64
+		for _, id := range frontier {
+			around := adjacency[id]
+			sort.SliceStable(around, func(i, j int) bool {
+				ci, cj := EdgeConfidence(around[i]), EdgeConfidence(around[j])
+				if ci != cj {
+					return ci > cj
+				}
+				return around[i].ID < around[j].ID
+			})
+
+			for _, edge := range around {
+				other := otherEnd(edge, id)
+				if visited[other] {
+					continue
+				}
+				if len(neighbors) == limit {
+					return neighbors, nil
+				}
+				visited[other] = true
+				seedOf[other] = seedOf[id]
+				neighbors = append(neighbors, graphNeighbor{ID: other, Hops: depth, SeedID: seedOf[id], ParentID: id, Edge: edge})
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+	return neighbors, nil
+}
+
+// EdgeConfidence returns an edge's confidence property. Edges without one
+// were asserted directly and count as certain.
+func EdgeConfidence(rel Relationship) float64 {
+	switch v := rel.Properties["confidence"].(type) {
+	case float64:
+		return v
+	case string:
+		if confidence, err := strconv.ParseFloat(v, 64); err == nil {
+			return confidence
+		}
+		return 0
+	case nil:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// edgeKey identifies an edge for de-duplication during traversals
+func edgeKey(rel Relationship) string {
+	return strconv.FormatInt(rel.ID, 10)
+}
+
+// otherEnd returns the endpoint of edge that isn't id
+func otherEnd(edge Relationship, id int64) int64 {
+	if edge.FromID == id {
+		return edge.ToID
+	}
+	return edge.FromID
+}
+
+// ListRelationships returns edges matching filter, ordered by ID
+func (s *PostgresStore) ListRelationships(filter RelationshipFilter) (_ []Relationship, err error) {
+	defer s.observe("ListRelationships")(&err)
+	var conditions []string
+	if filter.MemoryID != 0 {
+		conditions = append(conditions, fmt.Sprintf("(a.id = %[1]d OR b.id = %[1]d)", filter.MemoryID))
+	}
+	if filter.Type != "" {
+		if !ontology.ValidName(filter.Type) {
+			return nil, fmt.Errorf("invalid relationship type %q", filter.Type)
+		}
+		conditions = append(conditions, fmt.Sprintf("type(r) = '%s'", filter.Type))
+	}
+	if filter.AutoDetected != nil {
+		if *filter.AutoDetected {
+			conditions = append(conditions, "r.auto_detected = true")
+		} else {
+			conditions = append(conditions, "(r.auto_detected IS NULL OR r.auto_detected = false)")
+		}
+	}
+	where, limit := "", ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.Limit > 0 {
+		limit = fmt.Sprintf("LIMIT %d", filter.Limit)
+	}
+
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]->(b:Memory)
+			%s
+			RETURN id(r), a.id, b.id, type(r), properties(r)
+			ORDER BY id(r)
+			%s
+		$$) as (edge_id agtype, from_id agtype, to_id agtype, rel_type agtype, properties agtype);
+	`, where, limit)
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	defer rows.Close()
+
+	relationships := []Relationship{}
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, rel)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// GetRelationship retrieves a single edge by its ID
+func (s *PostgresStore) GetRelationship(id int64) (_ *Relationship, err error) {
+	defer s.observe("GetRelationship")(&err)
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+	return getRelationship(s.db, id)
+}
+
+// UpdateRelationship changes an edge's properties and optionally its type,
+// returning the updated edge. AGE can't relabel an edge, so a type change
+// replaces it with a new edge (and ID) carrying the merged properties.
+func (s *PostgresStore) UpdateRelationship(id int64, update RelationshipUpdate) (_ *Relationship, err error) {
+	defer s.observe("UpdateRelationship")(&err)
+	if update.Type != "" && !ontology.ValidName(update.Type) {
+		return nil, fmt.Errorf("invalid relationship type %q", update.Type)
+	}
+	setClause, err := cypherSetClause("r", update.Set)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range update.Remove {
+		if !ontology.ValidName(key) {
+			return nil, fmt.Errorf("invalid property name %q", key)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A transaction stays on one connection, so AGE only needs loading once
+	if _, err := tx.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	current, err := getRelationship(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Type != "" && update.Type != current.Type {
+		properties := make(map[string]interface{})
+		for key, value := range current.Properties {
+			properties[key] = value
+		}
+		for key, value := range update.Set {
+			properties[key] = value
+		}
+		for _, key := range update.Remove {
+			delete(properties, key)
+		}
+		if setClause, err = cypherSetClause("r", properties); err != nil {
+			return nil, err
+		}
+
+		var idJSON string
+		if err := tx.QueryRow(fmt.Sprintf(`
+			SELECT * FROM cypher('memory_graph', $$
+				MATCH (a:Memory {id: %d}), (b:Memory {id: %d})
+				MERGE (a)-[r:%s]->(b)
+				%s
+				RETURN id(r)
+			$$) as (edge_id agtype);
+		`, current.FromID, current.ToID, update.Type, setClause)).Scan(&idJSON); err != nil {
+			return nil, fmt.Errorf("failed to create replacement relationship: %w", err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`
+			SELECT * FROM cypher('memory_graph', $$
+				MATCH ()-[r]->() WHERE id(r) = %d
+				DELETE r
+			$$) as (result agtype);
+		`, id)); err != nil {
+			return nil, fmt.Errorf("failed to delete replaced relationship: %w", err)
+		}
+		if err := json.Unmarshal([]byte(idJSON), &id); err != nil {
+			return nil, fmt.Errorf("failed to parse relationship id %s: %w", idJSON, err)
+		}
+	} else if setClause != "" || len(update.Remove) > 0 {
+		removeClause := ""
+		if len(update.Remove) > 0 {
+			items := make([]string, len(update.Remove))
+			for i, key := range update.Remove {
+				items[i] = "r." + key
+			}
+			removeClause = "REMOVE " + strings.Join(items, ", ")
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`
+			SELECT * FROM cypher('memory_graph', $$
+				MATCH ()-[r]->() WHERE id(r) = %d
+				%s
+				%s
+			$$) as (result agtype);
+		`, id, setClause, removeClause)); err != nil {
+			return nil, fmt.Errorf("failed to update relationship: %w", err)
+		}
+	}
+
+	updated, err := getRelationship(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit relationship update: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteRelationship removes an edge, reporting whether it existed
+func (s *PostgresStore) DeleteRelationship(id int64) (_ bool, err error) {
+	defer s.observe("DeleteRelationship")(&err)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return false, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	if _, err := getRelationship(tx, id); errors.Is(err, ErrRelationshipNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH ()-[r]->() WHERE id(r) = %d
+			DELETE r
+		$$) as (result agtype);
+	`, id)); err != nil {
+		return false, fmt.Errorf("failed to delete relationship: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit relationship deletion: %w", err)
+	}
+	return true, nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getRelationship reads one edge; AGE must already be loaded on the connection
+func getRelationship(q queryRower, id int64) (*Relationship, error) {
+	query := fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (a:Memory)-[r]->(b:Memory) WHERE id(r) = %d
+			RETURN id(r), a.id, b.id, type(r), properties(r)
+		$$) as (edge_id agtype, from_id agtype, to_id agtype, rel_type agtype, properties agtype);
+	`, id)
+
+	rel, err := scanRelationship(q.QueryRow(query))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrRelationshipNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// cypherSetClause builds "SET v.key = value, ..." for properties, or "" when
+// there are none. Keys are validated since they can't be parameterized.
+func cypherSetClause(variable string, properties map[string]interface{}) (string, error) {
+	if len(properties) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		if !ontology.ValidName(key) {
+			return "", fmt.Errorf("invalid property name %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	assignments := make([]string, len(keys))
+	for i, key := range keys {
+		assignments[i] = fmt.Sprintf("%s.%s = %s", variable, key, cypherValue(properties[key]))
+	}
+	return "SET " + strings.Join(assignments, ", "), nil
+}
+
+// cypherValue formats a property value as a Cypher literal. Values other
+// than numbers and booleans are stored as strings.
+func cypherValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64, float32, int, int64:
+		return fmt.Sprintf("%v", v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return cypherString(v)
+	default:
+		return cypherString(fmt.Sprintf("%v", v))
+	}
+}
+
+// cypherString quotes s as a Cypher string literal
+func cypherString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "'", `\'`)
+	return "'" + s + "'"
+}
+
+// scanRelationship reads an (edge_id, from_id, to_id, rel_type, properties) agtype row
+func scanRelationship(row rowScanner) (Relationship, error) {
+	var idJSON, fromJSON, toJSON, typeJSON, propsJSON string
+	if err := row.Scan(&idJSON, &fromJSON, &toJSON, &typeJSON, &propsJSON); err != nil {
+		return Relationship{}, fmt.Errorf("failed to scan relationship: %w", err)
+	}
+
+	// agtype scalars and maps are JSON-compatible
+	var rel Relationship
+	if err := json.Unmarshal([]byte(idJSON), &rel.ID); err != nil {
+		return Relationship{}, fmt.Errorf("failed to parse relationship id %s: %w", idJSON, err)
+	}
+	if err := json.Unmarshal([]byte(fromJSON), &rel.FromID); err != nil {
+		return Relationship{}, fmt.Errorf("failed to parse relationship source %s: %w", fromJSON, err)
+	}
+	if err := json.Unmarshal([]byte(toJSON), &rel.ToID); err != nil {
+		return Relationship{}, fmt.Errorf("failed to parse relationship target %s: %w", toJSON, err)
+	}
+	if err := json.Unmarshal([]byte(typeJSON), &rel.Type); err != nil {
+		return Relationship{}, fmt.Errorf("failed to parse relationship type %s: %w", typeJSON, err)
+	}
+	if err := json.Unmarshal([]byte(propsJSON), &rel.Properties); err != nil {
+		return Relationship{}, fmt.Errorf("failed to parse relationship properties %s: %w", propsJSON, err)
+	}
+	if len(rel.Properties) == 0 {
+		rel.Properties = nil
+	}
+	return rel, nil
+}
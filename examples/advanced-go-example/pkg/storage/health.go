@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Stats returns the connection pool statistics
+func (s *PostgresStore) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// Ping checks that Postgres accepts connections
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// CheckGraph checks that the AGE extension is installed and the memory graph exists
+func (s *PostgresStore) CheckGraph(ctx context.Context) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM ag_catalog.ag_graph WHERE name = 'memory_graph')").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query AGE catalog: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("graph memory_graph does not exist")
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrJobNotFound is returned when a job ID does not exist
+var ErrJobNotFound = errors.New("job not found")
+
+// Job statuses. A failed attempt returns the job to pending until
+// MaxAttempts is reached, after which it stays failed.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is a unit of background work in the jobs table
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	MemoryID    int64           `json:"memory_id,omitzero"`
+	Payload     json.RawMessage `json:"payload,omitzero"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitzero"`
+	Progress    json.RawMessage `json:"progress,omitzero"` // Checkpoint saved by the handler while running
+	Result      json.RawMessage `json:"result,omitzero"`
+	RunAfter    time.Time       `json:"run_after"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	StartedAt   time.Time       `json:"started_at,omitzero"`
+	FinishedAt  time.Time       `json:"finished_at,omitzero"`
+	LockedBy    string          `json:"locked_by,omitzero"`    // Worker holding the lease on a running job
+	LockedUntil time.Time       `json:"locked_until,omitzero"` // When the lease runs out unless renewed
+}
+
+const jobColumns = "id, kind, memory_id, payload, status, attempts, max_attempts, last_error, progress, result, run_after, created_at, updated_at, started_at, finished_at, locked_by, locked_until"
+
+// leaseEnd is the SQL for the end of a lease starting now, given its length in seconds
+func leaseEnd(placeholder string) string {
+	return "now() + make_interval(secs => " + placeholder + ")"
+}
+
+// EnqueueJob adds a pending job. memoryID 0 means the job is not about a
+// single memory; maxAttempts below 1 runs the job once.
+func (s *PostgresStore) EnqueueJob(kind string, memoryID int64, payload interface{}, maxAttempts int) (_ int64, err error) {
+	defer s.observe("EnqueueJob")(&err)
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s job payload: %w", kind, err)
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var memory sql.NullInt64
+	if memoryID != 0 {
+		memory = sql.NullInt64{Int64: memoryID, Valid: true}
+	}
+
+	var id int64
+	err = s.db.QueryRow(
+		"INSERT INTO jobs (kind, memory_id, payload, max_attempts) VALUES ($1, $2, $3, $4) RETURNING id",
+		kind, memory, payloadJSON, maxAttempts,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue %s job: %w", kind, err)
+	}
+	return id, nil
+}
+
+// StartJob adds a job that is already running, for work done in the current
+// process rather than by the queue's workers. owner holds its lease for lease.
+func (s *PostgresStore) StartJob(kind string, memoryID int64, payload interface{}, maxAttempts int, owner string, lease time.Duration) (_ *Job, err error) {
+	defer s.observe("StartJob")(&err)
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s job payload: %w", kind, err)
+	}
+
+	var memory sql.NullInt64
+	if memoryID != 0 {
+		memory = sql.NullInt64{Int64: memoryID, Valid: true}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO jobs (kind, memory_id, payload, max_attempts, status, attempts, started_at, locked_by, locked_until)
+		VALUES ($1, $2, $3, $4, 'running', 1, now(), $5, %s)
+		RETURNING %s
+	`, leaseEnd("$6"), jobColumns)
+
+	job, err := scanJob(s.db.QueryRow(query, kind, memory, payloadJSON, max(maxAttempts, 1), owner, lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s job: %w", kind, err)
+	}
+	return &job, nil
+}
+
+// ClaimJobByID marks a specific pending or failed job as running, e.g. to
+// resume it in the current process. owner holds its lease for lease.
+func (s *PostgresStore) ClaimJobByID(id int64, owner string, lease time.Duration) (_ *Job, err error) {
+	defer s.observe("ClaimJobByID")(&err)
+	query := fmt.Sprintf(`
+		UPDATE jobs SET
+			status = 'running',
+			attempts = CASE WHEN status = 'failed' THEN 1 ELSE attempts + 1 END,
+			finished_at = NULL,
+			started_at = now(),
+			updated_at = now(),
+			locked_by = $2,
+			locked_until = %s
+		WHERE id = $1 AND status IN ('pending', 'failed')
+		RETURNING %s
+	`, leaseEnd("$3"), jobColumns)
+
+	job, err := scanJob(s.db.QueryRow(query, id, owner, lease.Seconds()))
+	if err == sql.ErrNoRows {
+		existing, err := s.GetJob(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("job %d is %s, so it cannot be claimed", id, existing.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// ClaimJob marks the oldest runnable job of one of kinds as running, with
+// a lease held by owner, and returns it, or nil when there is nothing to do.
+// Concurrent claimers skip each other's locked rows instead of waiting on them.
+func (s *PostgresStore) ClaimJob(kinds []string, owner string, lease time.Duration) (_ *Job, err error) {
+	defer s.observe("ClaimJob")(&err)
+	query := fmt.Sprintf(`
+		UPDATE jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			started_at = now(),
+			updated_at = now(),
+			locked_by = $2,
+			locked_until = %s
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_after <= now() AND kind = ANY($1)
+			ORDER BY run_after, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s
+	`, leaseEnd("$3"), jobColumns)
+
+	job, err := scanJob(s.db.QueryRow(query, pq.Array(kinds), owner, lease.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return &job, nil
+}
+
+// CompleteJob marks a running job as succeeded and stores its result
+func (s *PostgresStore) CompleteJob(id int64, result interface{}) (err error) {
+	defer s.observe("CompleteJob")(&err)
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result of job %d: %w", id, err)
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE jobs SET
+			status = 'succeeded', result = $2, last_error = NULL, finished_at = now(), updated_at = now(),
+			locked_by = NULL, locked_until = NULL
+		WHERE id = $1
+	`, id, resultJSON)
+	if err != nil {
+		return fmt.Errorf("failed to complete job %d: %w", id, err)
+	}
+	return nil
+}
+
+// RenewJobLease extends the lease owner holds on a running job. It reports
+// false when the job is no longer running under owner's lease, e.g. because
+// the lease ran out and another worker took the job over.
+func (s *PostgresStore) RenewJobLease(id int64, owner string, lease time.Duration) (_ bool, err error) {
+	defer s.observe("RenewJobLease")(&err)
+	result, err := s.db.Exec(fmt.Sprintf(`
+		UPDATE jobs SET locked_until = %s
+		WHERE id = $1 AND status = 'running' AND locked_by = $2
+	`, leaseEnd("$3")), id, owner, lease.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease on job %d: %w", id, err)
+	}
+	renewed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease on job %d: %w", id, err)
+	}
+	return renewed > 0, nil
+}
+
+// SetJobProgress saves a running job's checkpoint
+func (s *PostgresStore) SetJobProgress(id int64, progress interface{}) (err error) {
+	defer s.observe("SetJobProgress")(&err)
+	progressJSON, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress of job %d: %w", id, err)
+	}
+
+	if _, err := s.db.Exec("UPDATE jobs SET progress = $2, updated_at = now() WHERE id = $1", id, progressJSON); err != nil {
+		return fmt.Errorf("failed to save progress of job %d: %w", id, err)
+	}
+	return nil
+}
+
+// ReleaseJob returns a running job to pending without counting the attempt,
+// for work interrupted by shutdown rather than by an error
+func (s *PostgresStore) ReleaseJob(id int64) (err error) {
+	defer s.observe("ReleaseJob")(&err)
+	_, err = s.db.Exec(`
+		UPDATE jobs SET
+			status = 'pending', attempts = greatest(attempts - 1, 0), run_after = now(), updated_at = now(),
+			locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND status = 'running'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to release job %d: %w", id, err)
+	}
+	return nil
+}
+
+// RetryJob puts a failed job back in the queue with a fresh set of attempts.
+// Its progress is kept, so checkpointed jobs resume where they stopped.
+func (s *PostgresStore) RetryJob(id int64) (_ *Job, err error) {
+	defer s.observe("RetryJob")(&err)
+	query := fmt.Sprintf(`
+		UPDATE jobs SET status = 'pending', attempts = 0, run_after = now(), finished_at = NULL, updated_at = now()
+		WHERE id = $1 AND status = 'failed'
+		RETURNING %s
+	`, jobColumns)
+
+	job, err := scanJob(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		if _, err := s.GetJob(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("job %d has not failed, so it cannot be retried", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// FailJob records a failed attempt. The job is retried after retryAfter
+// unless it has used up its attempts; the returned status says which.
+func (s *PostgresStore) FailJob(id int64, jobErr error, retryAfter time.Duration) (_ string, err error) {
+	defer s.observe("FailJob")(&err)
+	var status string
+	err = s.db.QueryRow(`
+		UPDATE jobs SET
+			status = CASE WHEN attempts < max_attempts THEN 'pending' ELSE 'failed' END,
+			run_after = CASE WHEN attempts < max_attempts THEN now() + make_interval(secs => $3) ELSE run_after END,
+			finished_at = CASE WHEN attempts < max_attempts THEN NULL ELSE now() END,
+			last_error = $2,
+			updated_at = now(),
+			locked_by = NULL,
+			locked_until = NULL
+		WHERE id = $1
+		RETURNING status
+	`, id, jobErr.Error(), retryAfter.Seconds()).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record failure of job %d: %w", id, err)
+	}
+	return status, nil
+}
+
+// RequeueExpiredJobs returns running jobs whose lease has run out to
+// pending: their worker stopped or lost the database without releasing
+// them. Jobs under a live lease, possibly held by another server, are left
+// alone. The interrupted attempt still counts towards MaxAttempts.
+func (s *PostgresStore) RequeueExpiredJobs() (_ int64, err error) {
+	defer s.observe("RequeueExpiredJobs")(&err)
+	result, err := s.db.Exec(`
+		UPDATE jobs SET
+			status = CASE WHEN attempts < max_attempts THEN 'pending' ELSE 'failed' END,
+			finished_at = CASE WHEN attempts < max_attempts THEN NULL ELSE now() END,
+			last_error = 'interrupted: the worker stopped renewing its lease',
+			updated_at = now(),
+			locked_by = NULL,
+			locked_until = NULL
+		WHERE status = 'running' AND (locked_until IS NULL OR locked_until < now())
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue expired jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetJob retrieves a job by its ID
+func (s *PostgresStore) GetJob(id int64) (_ *Job, err error) {
+	defer s.observe("GetJob")(&err)
+	query := fmt.Sprintf("SELECT %s FROM jobs WHERE id = $1", jobColumns)
+
+	job, err := scanJob(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// JobsForMemory returns the newest jobs about a memory, newest first
+func (s *PostgresStore) JobsForMemory(memoryID int64, limit int) (_ []Job, err error) {
+	defer s.observe("JobsForMemory")(&err)
+	query := fmt.Sprintf("SELECT %s FROM jobs WHERE memory_id = $1 ORDER BY id DESC LIMIT $2", jobColumns)
+
+	rows, err := s.db.Query(query, memoryID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs for memory %d: %w", memoryID, err)
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// scanJob reads a row selected with jobColumns
+func scanJob(row rowScanner) (Job, error) {
+	var job Job
+	var memoryID sql.NullInt64
+	var lastError, lockedBy sql.NullString
+	var payload, progress, result []byte
+	var startedAt, finishedAt, lockedUntil sql.NullTime
+
+	err := row.Scan(
+		&job.ID, &job.Kind, &memoryID, &payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&lastError, &progress, &result, &job.RunAfter, &job.CreatedAt, &job.UpdatedAt, &startedAt, &finishedAt,
+		&lockedBy, &lockedUntil,
+	)
+	if err != nil {
+		return Job{}, err
+	}
+
+	job.MemoryID = memoryID.Int64
+	job.LastError = lastError.String
+	job.StartedAt = startedAt.Time
+	job.FinishedAt = finishedAt.Time
+	job.LockedBy = lockedBy.String
+	job.LockedUntil = lockedUntil.Time
+	if len(payload) > 0 {
+		job.Payload = json.RawMessage(payload)
+	}
+	if len(progress) > 0 {
+		job.Progress = json.RawMessage(progress)
+	}
+	if len(result) > 0 {
+		job.Result = json.RawMessage(result)
+	}
+	return job, nil
+}
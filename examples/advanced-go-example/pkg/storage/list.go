@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListSortFields are the columns ListMemories can order by
+var ListSortFields = []string{"created_at", "updated_at", "importance", "id"}
+
+// ListOptions controls ordering and paging for ListMemories
+type ListOptions struct {
+	GroupID   string
+	ClusterID int64  // Only members of this graph community (0 for all)
+	SortBy    string // One of ListSortFields (default: created_at)
+	Ascending bool   // Oldest/least important first instead of newest/most important
+	Limit     int
+	Cursor    string // NextCursor of the previous page, or "" for the first page
+}
+
+// listCursor is the decoded form of the opaque pagination cursor: the sort key
+// and ID of the last memory on the previous page
+type listCursor struct {
+	SortBy    string `json:"s"`
+	Ascending bool   `json:"a,omitzero"`
+	Value     string `json:"v,omitzero"`
+	ID        int64  `json:"i"`
+}
+
+// ListMemories returns unexpired memories ordered by opts.SortBy, with ties
+// broken by ID. It pages by keyset rather than offset, so pages stay stable
+// while memories are added or removed. The returned cursor is "" on the last page.
+func (s *PostgresStore) ListMemories(opts ListOptions) (_ []Memory, _ string, err error) {
+	defer s.observe("ListMemories")(&err)
+	if opts.SortBy == "" {
+		opts.SortBy = "created_at"
+	}
+	// Cast the cursor value to the column type: importance is REAL, and
+	// comparing it against a float8 parameter would never match exactly
+	var cast string
+	switch opts.SortBy {
+	case "created_at", "updated_at":
+		cast = "::timestamptz"
+	case "importance":
+		cast = "::real"
+	case "id":
+	default:
+		return nil, "", fmt.Errorf("sort_by must be one of %s, got %q", strings.Join(ListSortFields, ", "), opts.SortBy)
+	}
+	if opts.Limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive, got %d", opts.Limit)
+	}
+
+	args := queryArgs{}
+	conditions, err := metadataConditions(SearchOptions{GroupID: opts.GroupID}, &args)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.ClusterID != 0 {
+		conditions = append(conditions, "cluster_id = "+args.add(opts.ClusterID))
+	}
+
+	direction, comparison := "DESC", "<"
+	if opts.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.SortBy != opts.SortBy || cursor.Ascending != opts.Ascending {
+			return nil, "", fmt.Errorf("cursor was issued for a different sort order; start again without a cursor")
+		}
+		if opts.SortBy == "id" {
+			conditions = append(conditions, fmt.Sprintf("id %s %s", comparison, args.add(cursor.ID)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s%s, %s)",
+				opts.SortBy, comparison, args.add(cursor.Value), cast, args.add(cursor.ID)))
+		}
+	}
+
+	order := "id " + direction
+	if opts.SortBy != "id" {
+		order = fmt.Sprintf("%s %s, id %s", opts.SortBy, direction, direction)
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	query := fmt.Sprintf("SELECT %s FROM memories WHERE %s ORDER BY %s LIMIT %s",
+		memoryColumns, strings.Join(conditions, " AND "), order, args.add(opts.Limit+1))
+	memories, err := s.queryMemories(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(memories) <= opts.Limit {
+		return memories, "", nil
+	}
+
+	memories = memories[:opts.Limit]
+	next, err := encodeListCursor(opts, memories[len(memories)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return memories, next, nil
+}
+
+// GroupCount is a group and how many unexpired memories it has
+type GroupCount struct {
+	GroupID string `json:"group_id"` // "" for memories without a group
+	Count   int    `json:"count"`
+}
+
+// ListGroups returns every group with unexpired memories, by name
+func (s *PostgresStore) ListGroups() (_ []GroupCount, err error) {
+	defer s.observe("ListGroups")(&err)
+	rows, err := s.db.Query(`
+		SELECT COALESCE(group_id, '') AS g, count(*) FROM memories
+		WHERE expires_at IS NULL OR expires_at > now()
+		GROUP BY g
+		ORDER BY g
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []GroupCount{}
+	for rows.Next() {
+		var group GroupCount
+		if err := rows.Scan(&group.GroupID, &group.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating groups: %w", err)
+	}
+	return groups, nil
+}
+
+func encodeListCursor(opts ListOptions, last Memory) (string, error) {
+	cursor := listCursor{SortBy: opts.SortBy, Ascending: opts.Ascending, ID: last.ID}
+	switch opts.SortBy {
+	case "created_at":
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case "importance":
+		cursor.Value = strconv.FormatFloat(last.Importance, 'g', -1, 64)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeListCursor(encoded string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err == nil {
+		err = cursor.validate()
+	}
+	if err != nil {
+		return listCursor{}, fmt.Errorf("invalid cursor %q", encoded)
+	}
+	return cursor, nil
+}
+
+// validate checks the ID and that the value parses for the sort field, so an
+// edited cursor is rejected here instead of failing the cast in the query
+func (c listCursor) validate() error {
+	if c.ID <= 0 {
+		return fmt.Errorf("id must be positive, got %d", c.ID)
+	}
+	switch c.SortBy {
+	case "created_at", "updated_at":
+		_, err := time.Parse(time.RFC3339Nano, c.Value)
+		return err
+	case "importance":
+		_, err := strconv.ParseFloat(c.Value, 64)
+		return err
+	case "id":
+		if c.Value != "" {
+			return fmt.Errorf("id cursors have no value, got %q", c.Value)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown sort field %q", c.SortBy)
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListCursorRoundTrip(t *testing.T) {
+	nanos := time.Date(2025, 3, 1, 8, 30, 15, 123456789, time.UTC)
+	zoned := time.Date(2025, 3, 1, 10, 0, 0, 1, time.FixedZone("EET", 2*60*60))
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		last Memory
+	}{
+		{"created_at with nanoseconds", ListOptions{SortBy: "created_at"}, Memory{ID: 7, CreatedAt: nanos}},
+		{"created_at in another zone", ListOptions{SortBy: "created_at", Ascending: true}, Memory{ID: 8, CreatedAt: zoned}},
+		{"updated_at", ListOptions{SortBy: "updated_at"}, Memory{ID: 9, CreatedAt: zoned, UpdatedAt: nanos}},
+		{"importance", ListOptions{SortBy: "importance"}, Memory{ID: 10, Importance: 0.7}},
+		{"importance read back from REAL", ListOptions{SortBy: "importance", Ascending: true}, Memory{ID: 11, Importance: float64(float32(0.7))}},
+		{"zero importance", ListOptions{SortBy: "importance"}, Memory{ID: 12}},
+		{"id", ListOptions{SortBy: "id", Ascending: true}, Memory{ID: 1 << 40, CreatedAt: nanos}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeListCursor(tt.opts, tt.last)
+			if err != nil {
+				t.Fatalf("encodeListCursor() error = %v", err)
+			}
+			cursor, err := decodeListCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeListCursor() error = %v", err)
+			}
+			if cursor.SortBy != tt.opts.SortBy || cursor.Ascending != tt.opts.Ascending || cursor.ID != tt.last.ID {
+				t.Errorf("decoded %+v, want sort %s ascending %v and ID %d", cursor, tt.opts.SortBy, tt.opts.Ascending, tt.last.ID)
+			}
+
+			switch tt.opts.SortBy {
+			case "created_at", "updated_at":
+				want := tt.last.CreatedAt
+				if tt.opts.SortBy == "updated_at" {
+					want = tt.last.UpdatedAt
+				}
+				got, err := time.Parse(time.RFC3339Nano, cursor.Value)
+				if err != nil || !got.Equal(want) {
+					t.Errorf("value %q = %v, want %v", cursor.Value, got, want)
+				}
+			case "importance":
+				got, err := strconv.ParseFloat(cursor.Value, 64)
+				if err != nil || got != tt.last.Importance {
+					t.Errorf("value %q = %v, want %v", cursor.Value, got, tt.last.Importance)
+				}
+				// The query casts the value to REAL, where it must equal the stored column
+				if float32(got) != float32(tt.last.Importance) {
+					t.Errorf("value %q is %v as REAL, want %v", cursor.Value, float32(got), float32(tt.last.Importance))
+				}
+			case "id":
+				if cursor.Value != "" {
+					t.Errorf("value = %q, want none for id cursors", cursor.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeListCursorRejects(t *testing.T) {
+	valid, err := encodeListCursor(ListOptions{SortBy: "created_at"}, Memory{ID: 7, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("encodeListCursor() error = %v", err)
+	}
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"id","i":1}`))},
+		{"truncated", valid[:len(valid)-4]},
+		{"first byte changed", "X" + valid[1:]},
+		{"not an object", encode(`[1, 2]`)},
+		{"missing id", encode(`{"s":"id"}`)},
+		{"negative id", encode(`{"s":"id","i":-5}`)},
+		{"id as string", encode(`{"s":"id","i":"5"}`)},
+		{"unknown sort field", encode(`{"s":"text","v":"a","i":5}`)},
+		{"missing sort field", encode(`{"i":5}`)},
+		{"time that doesn't parse", encode(`{"s":"created_at","v":"yesterday","i":5}`)},
+		{"missing time", encode(`{"s":"updated_at","i":5}`)},
+		{"importance that doesn't parse", encode(`{"s":"importance","v":"0.7; DROP TABLE memories","i":5}`)},
+		{"value on an id cursor", encode(`{"s":"id","v":"1","i":5}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeListCursor(tt.cursor)
+			if err == nil {
+				t.Fatalf("decodeListCursor(%q) = %+v, want an error", tt.cursor, cursor)
+			}
+			if !strings.Contains(err.Error(), "invalid cursor") {
+				t.Errorf("decodeListCursor() error = %v, want it to contain %q", err, "invalid cursor")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"advanced-go-example/pkg/filter"
+
+	"github.com/lib/pq"
+)
+
+// normalizeTags trims whitespace and drops empty and duplicate tags.
+// It never returns nil so the result can be stored in a NOT NULL array column.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// marshalAttributes encodes attributes for the JSONB column ("{}" when empty)
+func marshalAttributes(attributes map[string]interface{}) ([]byte, error) {
+	if len(attributes) == 0 {
+		return []byte("{}"), nil
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attributes: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalAttributes decodes the JSONB column, returning nil for an empty object
+func unmarshalAttributes(data []byte) (map[string]interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	return attributes, nil
+}
+
+// metadataConditions translates the group, tag, attribute and structured filters in opts
+// into parameterized WHERE conditions, appending their values to args.
+// Memories past their expires_at are always excluded.
+func metadataConditions(opts SearchOptions, args *queryArgs) ([]string, error) {
+	// Expired memories stay hidden until the retention sweeper removes them
+	conditions := []string{"(expires_at IS NULL OR expires_at > now())"}
+
+	if opts.GroupID != "" {
+		conditions = append(conditions, "group_id = "+args.add(opts.GroupID))
+	}
+
+	if tags := normalizeTags(opts.Tags); len(tags) > 0 {
+		// @> requires every tag, && requires at least one; both can use the GIN index
+		operator := "&&"
+		if opts.MatchAllTags {
+			operator = "@>"
+		}
+		conditions = append(conditions, fmt.Sprintf("tags %s %s", operator, args.add(pq.Array(tags))))
+	}
+
+	if len(opts.Attributes) > 0 {
+		// JSONB containment matches every key/value pair in the filter
+		containment, err := marshalAttributes(opts.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("attributes @> %s::jsonb", args.add(string(containment))))
+	}
+
+	if opts.Filter != nil {
+		condition, compiledArgs, err := filter.Compile(opts.Filter, filter.Postgres, *args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile filter: %w", err)
+		}
+		*args = compiledArgs
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
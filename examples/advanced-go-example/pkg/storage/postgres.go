@@ -1,38 +1,98 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"advanced-go-example/pkg/filter"
+	"advanced-go-example/pkg/metrics"
+	"advanced-go-example/pkg/ontology"
+	"advanced-go-example/pkg/tracing"
+
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// PostgresConfig holds Postgres connection configuration
+// PostgresConfig holds Postgres connection configuration. Zero pool and
+// timeout settings use the defaults below.
 type PostgresConfig struct {
 	Host     string
 	Port     string
 	User     string
 	Password string
 	Database string
+
+	// TLS: sslmode is one of SSLModes; the files are optional PEM paths
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	ConnectTimeout  time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
 }
 
+// SSLModes are the sslmode values lib/pq supports
+var SSLModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+// Connection defaults
+const (
+	DefaultSSLMode         = "disable"
+	DefaultConnectTimeout  = 10 * time.Second
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 5 * time.Minute
+)
+
 // PostgresStore implements storage using PostgreSQL with pgvector and Apache AGE
 type PostgresStore struct {
-	db *sql.DB
+	db        *sql.DB
+	access    *accessTracker
+	listeners []func(MemoryChange)
+	ctx       context.Context // Parent of the spans methods start; see WithContext
 }
 
+// ErrMemoryNotFound is returned when a memory ID doesn't exist
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// EmbeddingDimensions is the size of the memories.embedding vector column
+const EmbeddingDimensions = 768
+
+// DefaultImportance is used when a memory is stored without an explicit importance
+const DefaultImportance = 0.5
+
 // Memory represents a stored memory with metadata
 type Memory struct {
-	ID        int64     `json:"id,omitzero"`
-	Text      string    `json:"text"`
-	Embedding []float64 `json:"embedding,omitzero"`
-	GroupID   string    `json:"group_id,omitzero"`
-	CreatedAt time.Time `json:"created_at,omitzero"`
-	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	ID         int64                  `json:"id,omitzero"`
+	ExternalID string                 `json:"external_id,omitzero"` // Stable across export and import
+	Text       string                 `json:"text"`
+	Embedding  []float64              `json:"embedding,omitzero"`
+	GroupID    string                 `json:"group_id,omitzero"`
+	Tags       []string               `json:"tags,omitzero"`
+	Source     string                 `json:"source,omitzero"`     // Where the memory came from (URI, file path, ...)
+	Importance float64                `json:"importance,omitzero"` // 0 (trivial) to 1 (critical)
+	Attributes map[string]interface{} `json:"attributes,omitzero"` // Free-form JSONB attributes
+	CreatedAt  time.Time              `json:"created_at,omitzero"`
+	UpdatedAt  time.Time              `json:"updated_at,omitzero"`
+
+	// Optional expiry enforced by the retention sweeper
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+
+	// Maintained asynchronously whenever a read returns the memory
+	LastAccessedAt time.Time `json:"last_accessed_at,omitzero"`
+	AccessCount    int64     `json:"access_count,omitzero"`
+
+	// Graph community saved by graph_stats (the smallest member ID)
+	ClusterID int64 `json:"cluster_id,omitzero"`
 }
 
 // SearchResult pairs a memory with its similarity score
@@ -41,16 +101,38 @@ type SearchResult struct {
 	Similarity       float64 `json:"similarity,omitzero"`
 	ViaRelationship  bool    `json:"via_relationship,omitzero"`  // True if found via graph traversal
 	RelationshipHops int     `json:"relationship_hops,omitzero"` // Number of hops from vector result
+	SeedID           int64   `json:"seed_id,omitzero"`           // Vector result the graph traversal started from
+	RelationshipID   int64   `json:"relationship_id,omitzero"`   // Edge that led to this memory
+	RelationshipType string  `json:"relationship_type,omitzero"` // Type of that edge
+	GraphScore       float64 `json:"graph_score,omitzero"`       // Seed similarity propagated along the edges, with graph ranking
+
+	// Set when a ranking profile other than pure similarity ordered the results
+	Score  float64         `json:"score,omitzero"`
+	Scores *ScoreBreakdown `json:"scores,omitzero"`
+}
+
+// SearchOptions controls ranking limits and metadata filters for SearchMemories
+type SearchOptions struct {
+	Limit         int
+	MinSimilarity float64
+	GroupID       string
+	Tags          []string               // Only return memories carrying these tags
+	MatchAllTags  bool                   // Require every tag instead of any of them
+	Attributes    map[string]interface{} // Attribute equality filters (JSONB containment)
+	Filter        *filter.Expr           // Optional structured filter, compiled to SQL
+	Ranking       *RankingProfile        // Optional re-ranking; nil orders by similarity only
+	MaxNeighbors  int                    // Cap on graph-discovered results: 0 uses DefaultMaxNeighbors, negative disables expansion
+	GraphRanking  *GraphRanking          // Optional; ranks graph-discovered results with the vector hits instead of appending them
 }
 
+// DefaultMaxNeighbors caps the graph-discovered results a search adds
+const DefaultMaxNeighbors = 20
+
 // NewPostgresStore creates a new PostgreSQL store with pgvector and Apache AGE
 func NewPostgresStore(config PostgresConfig) (*PostgresStore, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.Host, config.Port, config.User, config.Password, config.Database,
-	)
+	config = config.withDefaults()
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", config.connString())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -61,61 +143,139 @@ func NewPostgresStore(config PostgresConfig) (*PostgresStore, error) {
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
 
 	// Note: Apache AGE initialization (LOAD 'age' and SET search_path) is done
 	// per-query in methods that need it, since connection pool makes it unreliable
 	// to set globally
 
-	return &PostgresStore{db: db}, nil
+	return &PostgresStore{db: db, access: newAccessTracker(db), ctx: context.Background()}, nil
+}
+
+// withDefaults fills in zero-valued settings
+func (c PostgresConfig) withDefaults() PostgresConfig {
+	if c.SSLMode == "" {
+		c.SSLMode = DefaultSSLMode
+	}
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = DefaultConnectTimeout
+	}
+	if c.MaxOpenConns == 0 {
+		c.MaxOpenConns = DefaultMaxOpenConns
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if c.ConnMaxLifetime == 0 {
+		c.ConnMaxLifetime = DefaultConnMaxLifetime
+	}
+	return c
+}
+
+// connString builds a libpq key/value connection string, quoting values so
+// passwords and paths may contain spaces and quotes
+func (c PostgresConfig) connString() string {
+	params := [][2]string{
+		{"host", c.Host},
+		{"port", c.Port},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Database},
+		{"sslmode", c.SSLMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		// libpq counts whole seconds and treats 0 as no timeout
+		{"connect_timeout", strconv.Itoa(max(1, int(c.ConnectTimeout.Seconds())))},
+	}
+
+	var parts []string
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(param[1])
+		parts = append(parts, param[0]+"='"+value+"'")
+	}
+	return strings.Join(parts, " ")
 }
 
-// Close closes the database connection
+// WithContext returns a shallow copy of the store whose methods trace as
+// children of the span in ctx. Queries are not cancelled by ctx. Register
+// change listeners on the original store.
+func (s *PostgresStore) WithContext(ctx context.Context) *PostgresStore {
+	scoped := *s
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// Close flushes pending access tracking and closes the database connection
 func (s *PostgresStore) Close() error {
+	s.access.close()
 	return s.db.Close()
 }
 
-// StoreMemory stores a memory with its vector embedding
-func (s *PostgresStore) StoreMemory(text string, embedding []float64, groupID string) (int64, error) {
-	// Convert []float64 to []float32 for pgvector
-	embedding32 := make([]float32, len(embedding))
-	for i, v := range embedding {
-		embedding32[i] = float32(v)
+// StoreMemory stores a memory with its vector embedding and metadata
+func (s *PostgresStore) StoreMemory(memory Memory) (_ int64, err error) {
+	defer s.observe("StoreMemory")(&err)
+	attributes, err := marshalAttributes(memory.Attributes)
+	if err != nil {
+		return 0, err
 	}
 
 	var id int64
 	query := `
-		INSERT INTO memories (text, embedding, group_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO memories (text, embedding, group_id, tags, source, importance, attributes, expires_at, external_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, ''), gen_random_uuid()::text))
 		RETURNING id
 	`
 
-	err := s.db.QueryRow(query, text, pgvector.NewVector(embedding32), groupID).Scan(&id)
+	err = s.db.QueryRow(
+		query,
+		memory.Text,
+		toVector(memory.Embedding),
+		memory.GroupID,
+		pq.Array(normalizeTags(memory.Tags)),
+		memory.Source,
+		memory.Importance,
+		string(attributes),
+		sql.NullTime{Time: memory.ExpiresAt, Valid: !memory.ExpiresAt.IsZero()},
+		memory.ExternalID,
+	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to store memory: %w", err)
 	}
 
 	// Create corresponding node in Apache AGE graph
+	if err := s.upsertMemoryNode(id, memory.Text); err != nil {
+		return 0, err
+	}
+
+	s.notifyChange([]int64{id}, []string{memory.GroupID}, false)
+	return id, nil
+}
+
+// upsertMemoryNode creates the AGE node for a memory, or refreshes its text
+func (s *PostgresStore) upsertMemoryNode(id int64, text string) error {
 	// Initialize AGE for this connection
 	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
-		return 0, fmt.Errorf("failed to initialize Apache AGE for memory %d: %w", id, err)
+		return fmt.Errorf("failed to initialize Apache AGE for memory %d: %w", id, err)
 	}
 
 	cypherQuery := fmt.Sprintf(`
 		SELECT * FROM cypher('memory_graph', $$
-			CREATE (m:Memory {id: %d, text: '%s'})
+			MERGE (m:Memory {id: %d})
+			SET m.text = '%s'
 			RETURN m
 		$$) as (memory agtype);
 	`, id, escapeString(text))
 
-	_, err = s.db.Exec(cypherQuery)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create AGE node for memory %d: %w", id, err)
+	if _, err := s.db.Exec(cypherQuery); err != nil {
+		return fmt.Errorf("failed to create AGE node for memory %d: %w", id, err)
 	}
-
-	return id, nil
+	return nil
 }
 
 // escapeString escapes single quotes for Cypher queries
@@ -125,46 +285,38 @@ func escapeString(s string) string {
 }
 
 // SearchMemories performs vector similarity search using pgvector
-func (s *PostgresStore) SearchMemories(queryEmbedding []float64, limit int, minSimilarity float64, groupID string) ([]SearchResult, error) {
-	// Convert []float64 to []float32 for pgvector
-	embedding32 := make([]float32, len(queryEmbedding))
-	for i, v := range queryEmbedding {
-		embedding32[i] = float32(v)
+func (s *PostgresStore) SearchMemories(queryEmbedding []float64, opts SearchOptions) (_ []SearchResult, err error) {
+	defer s.observe("SearchMemories")(&err)
+	// Relationship filters query the AGE graph from inside the SQL statement
+	if opts.Filter.UsesRelationships() {
+		if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+			return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+		}
 	}
 
-	// Build query with optional group filter
-	query := `
-		SELECT
-			id, text, embedding::text, group_id, created_at, updated_at,
-			1 - (embedding <=> $1) as similarity
-		FROM memories
-	`
-	args := []interface{}{pgvector.NewVector(embedding32)}
-
-	if groupID != "" {
-		query += " WHERE group_id = $2"
-		args = append(args, groupID)
-		if minSimilarity > 0 {
-			query += " AND 1 - (embedding <=> $1) >= $3"
-			args = append(args, minSimilarity)
-			query += " ORDER BY embedding <=> $1 LIMIT $4"
-			args = append(args, limit)
-		} else {
-			query += " ORDER BY embedding <=> $1 LIMIT $3"
-			args = append(args, limit)
-		}
-	} else {
-		if minSimilarity > 0 {
-			query += " WHERE 1 - (embedding <=> $1) >= $2"
-			args = append(args, minSimilarity)
-			query += " ORDER BY embedding <=> $1 LIMIT $3"
-			args = append(args, limit)
-		} else {
-			query += " ORDER BY embedding <=> $1 LIMIT $2"
-			args = append(args, limit)
-		}
+	args := queryArgs{}
+	vector := args.add(toVector(queryEmbedding))
+
+	// Metadata filters are pushed down into the WHERE clause
+	conditions, err := metadataConditions(opts, &args)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MinSimilarity > 0 {
+		conditions = append(conditions, fmt.Sprintf("1 - (embedding <=> %s) >= %s", vector, args.add(opts.MinSimilarity)))
 	}
 
+	query := fmt.Sprintf("SELECT %s, 1 - (embedding <=> %s) as similarity FROM memories", memoryColumns, vector)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Re-ranking profiles need a larger pool of vector hits to choose from
+	ranking := RankingProfiles["similarity"]
+	if opts.Ranking != nil {
+		ranking = *opts.Ranking
+	}
+	query += fmt.Sprintf(" ORDER BY embedding <=> %s LIMIT %s", vector, args.add(ranking.poolSize(opts.Limit)))
+
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search memories: %w", err)
@@ -173,32 +325,14 @@ func (s *PostgresStore) SearchMemories(queryEmbedding []float64, limit int, minS
 
 	var results []SearchResult
 	for rows.Next() {
-		var memory Memory
-		var embeddingStr string
+		// Don't read the embedding for output - saves tokens and not needed by user
+		// The embedding is only used by pgvector for similarity calculation
 		var similarity float64
-		var groupIDPtr *string
-
-		err := rows.Scan(
-			&memory.ID,
-			&memory.Text,
-			&embeddingStr,
-			&groupIDPtr,
-			&memory.CreatedAt,
-			&memory.UpdatedAt,
-			&similarity,
-		)
+		memory, err := scanMemory(rows, &similarity)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan search result: %w", err)
 		}
 
-		// Set optional fields
-		if groupIDPtr != nil {
-			memory.GroupID = *groupIDPtr
-		}
-
-		// Don't parse embedding for output - saves tokens and not needed by user
-		// The embedding is only used by pgvector for similarity calculation
-
 		results = append(results, SearchResult{
 			Memory:     memory,
 			Similarity: similarity,
@@ -210,114 +344,94 @@ func (s *PostgresStore) SearchMemories(queryEmbedding []float64, limit int, minS
 		return nil, fmt.Errorf("error iterating search results: %w", err)
 	}
 
+	if !ranking.pureSimilarity() {
+		results = ranking.rerank(results, opts.Limit, time.Now())
+	}
+
 	// Graph-enhanced search: traverse relationships from top results
-	if len(results) > 0 {
-		// Extract IDs from vector search results
-		resultIDs := make([]int64, len(results))
-		resultMap := make(map[int64]bool) // Track which IDs we already have
+	maxNeighbors := opts.MaxNeighbors
+	if maxNeighbors == 0 {
+		maxNeighbors = DefaultMaxNeighbors
+	}
+	if len(results) > 0 && maxNeighbors > 0 {
+		hops := 1
+		if opts.GraphRanking != nil {
+			hops = opts.GraphRanking.hops()
+		}
+
+		seeds := make([]int64, len(results))
+		seedSimilarity := make(map[int64]float64, len(results))
 		for i, result := range results {
-			resultIDs[i] = result.Memory.ID
-			resultMap[result.Memory.ID] = true
+			seeds[i] = result.Memory.ID
+			seedSimilarity[result.Memory.ID] = result.Similarity
 		}
 
-		// Find connected memories (1 hop)
-		connectedIDs, err := s.getConnectedMemoryIDs(resultIDs, 1)
-		if err == nil && len(connectedIDs) > 0 {
-			// Fetch connected memories that aren't already in results
-			var newIDs []int64
-			for id := range connectedIDs {
-				if !resultMap[id] {
-					newIDs = append(newIDs, id)
-				}
-			}
+		neighbors, err := s.expandNeighbors(seeds, hops, maxNeighbors)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand search results through the graph: %w", err)
+		}
+
+		ids := make([]int64, len(neighbors))
+		for i, neighbor := range neighbors {
+			ids[i] = neighbor.ID
+		}
+		// Connected memories must satisfy the same metadata filters
+		connected, err := s.getSimilarities(ids, queryEmbedding, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connected memories: %w", err)
+		}
 
-			if len(newIDs) > 0 {
-				// Build query to fetch connected memories
-				placeholders := ""
-				args := make([]interface{}, len(newIDs))
-				for i, id := range newIDs {
-					if i > 0 {
-						placeholders += ","
-					}
-					placeholders += fmt.Sprintf("$%d", i+1)
-					args[i] = id
-				}
-
-				fetchQuery := fmt.Sprintf(`
-					SELECT id, text, group_id, created_at, updated_at
-					FROM memories
-					WHERE id IN (%s)
-				`, placeholders)
-
-				connectedRows, err := s.db.Query(fetchQuery, args...)
-				if err == nil {
-					defer connectedRows.Close()
-
-					for connectedRows.Next() {
-						var memory Memory
-						var groupIDPtr *string
-
-						if err := connectedRows.Scan(
-							&memory.ID,
-							&memory.Text,
-							&groupIDPtr,
-							&memory.CreatedAt,
-							&memory.UpdatedAt,
-						); err == nil {
-							if groupIDPtr != nil {
-								memory.GroupID = *groupIDPtr
-							}
-
-							// Add as graph-discovered result with lower similarity
-							results = append(results, SearchResult{
-								Memory:           memory,
-								Similarity:       0, // No vector similarity, found via graph
-								ViaRelationship:  true,
-								RelationshipHops: connectedIDs[memory.ID],
-							})
-						}
-					}
-				}
+		var graphScores map[int64]float64
+		if opts.GraphRanking != nil {
+			graphScores = opts.GraphRanking.propagate(seedSimilarity, neighbors)
+		}
+
+		for _, neighbor := range neighbors {
+			result, ok := connected[neighbor.ID]
+			if !ok {
+				continue
 			}
+			result.ViaRelationship = true
+			result.RelationshipHops = neighbor.Hops
+			result.SeedID = neighbor.SeedID
+			result.RelationshipID = neighbor.Edge.ID
+			result.RelationshipType = neighbor.Edge.Type
+			result.GraphScore = graphScores[neighbor.ID]
+			results = append(results, result)
 		}
 	}
 
+	// Merge graph-discovered results into one ranked list
+	if opts.GraphRanking != nil {
+		results = opts.GraphRanking.rank(results, ranking, opts.Limit, time.Now())
+	}
+
+	// Track access off the read path
+	returnedIDs := make([]int64, len(results))
+	for i, result := range results {
+		returnedIDs[i] = result.Memory.ID
+	}
+	s.access.record(returnedIDs)
+
 	return results, nil
 }
 
-// AddRelationship creates a graph edge between two memories using Apache AGE
-func (s *PostgresStore) AddRelationship(fromID, toID int64, relType string, properties map[string]interface{}) error {
-	// Initialize AGE for this connection
-	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
-		return fmt.Errorf("failed to initialize AGE: %w", err)
+// AddRelationship links two memories with an edge of relType and returns the
+// edge ID. There is at most one edge per direction and type between two
+// memories: adding it again updates the existing edge's properties.
+func (s *PostgresStore) AddRelationship(fromID, toID int64, relType string, properties map[string]interface{}) (_ int64, err error) {
+	defer s.observe("AddRelationship")(&err)
+	if !ontology.ValidName(relType) {
+		return 0, fmt.Errorf("invalid relationship type %q", relType)
+	}
+	setClause, err := cypherSetClause("r", properties)
+	if err != nil {
+		return 0, err
 	}
 
-	// Build Cypher properties string
-	propsStr := ""
-	if len(properties) > 0 {
-		propsStr = "{"
-		first := true
-		for key, value := range properties {
-			if !first {
-				propsStr += ", "
-			}
-			first = false
-			// Format value as string for Cypher
-			switch v := value.(type) {
-			case string:
-				// Escape single quotes for Cypher by replacing ' with \'
-				escaped := strings.Replace(v, "'", "\\'", -1)
-				propsStr += fmt.Sprintf("%s: '%s'", key, escaped)
-			case float64, int, int64:
-				propsStr += fmt.Sprintf("%s: %v", key, v)
-			case bool:
-				propsStr += fmt.Sprintf("%s: %v", key, v)
-			default:
-				escaped := strings.Replace(fmt.Sprintf("%v", v), "'", "\\'", -1)
-				propsStr += fmt.Sprintf("%s: '%s'", key, escaped)
-			}
-		}
-		propsStr += "}"
+	// Initialize AGE for this connection
+	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
+		return 0, fmt.Errorf("failed to initialize AGE: %w", err)
 	}
 
 	// Create relationship using Apache AGE Cypher
@@ -325,189 +439,293 @@ func (s *PostgresStore) AddRelationship(fromID, toID int64, relType string, prop
 		SELECT * FROM cypher('memory_graph', $$
 			MATCH (a:Memory {id: %d})
 			MATCH (b:Memory {id: %d})
-			MERGE (a)-[r:%s %s]->(b)
-			RETURN r
-		$$) as (relationship agtype);
-	`, fromID, toID, relType, propsStr)
-
-	_, err := s.db.Exec(query)
+			MERGE (a)-[r:%s]->(b)
+			%s
+			RETURN id(r)
+		$$) as (edge_id agtype);
+	`, fromID, toID, relType, setClause)
+
+	var idJSON string
+	err = s.db.QueryRow(query).Scan(&idJSON)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to create relationship: memory %d or %d not found in graph", fromID, toID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create relationship: %w", err)
+		return 0, fmt.Errorf("failed to create relationship: %w", err)
 	}
 
-	return nil
+	var id int64
+	if err := json.Unmarshal([]byte(idJSON), &id); err != nil {
+		return 0, fmt.Errorf("failed to parse relationship id %s: %w", idJSON, err)
+	}
+
+	autoDetected, _ := properties["auto_detected"].(bool)
+	metrics.EdgeCreated(relType, autoDetected)
+	return id, nil
 }
 
 // GetMemoryByID retrieves a single memory by its ID
-func (s *PostgresStore) GetMemoryByID(id int64) (*Memory, error) {
-	query := `SELECT id, text, group_id, created_at, updated_at FROM memories WHERE id = $1`
+func (s *PostgresStore) GetMemoryByID(id int64) (_ *Memory, err error) {
+	defer s.observe("GetMemoryByID")(&err)
+	memory, err := s.getMemoryByID(id)
+	if err != nil {
+		return nil, err
+	}
 
-	var memory Memory
-	var groupIDPtr *string
+	s.access.record([]int64{memory.ID})
+	return memory, nil
+}
 
-	err := s.db.QueryRow(query, id).Scan(
-		&memory.ID,
-		&memory.Text,
-		&groupIDPtr,
-		&memory.CreatedAt,
-		&memory.UpdatedAt,
-	)
+// LookupMemory is GetMemoryByID without recording an access, for the
+// server's own reads (detection, statistics) that must not look like use
+func (s *PostgresStore) LookupMemory(id int64) (_ *Memory, err error) {
+	defer s.observe("LookupMemory")(&err)
+	return s.getMemoryByID(id)
+}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("memory not found: %d", id)
+// GetMemoriesByIDs retrieves the unexpired memories among ids, in no particular order
+func (s *PostgresStore) GetMemoriesByIDs(ids []int64) (_ []Memory, err error) {
+	defer s.observe("GetMemoriesByIDs")(&err)
+	memories, err := s.getMemoriesByIDs(ids, SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memories: %w", err)
+	}
+
+	returnedIDs := make([]int64, len(memories))
+	for i, memory := range memories {
+		returnedIDs[i] = memory.ID
 	}
+	s.access.record(returnedIDs)
+	return memories, nil
+}
+
+// LookupMemories is GetMemoriesByIDs without recording an access
+func (s *PostgresStore) LookupMemories(ids []int64) (_ []Memory, err error) {
+	defer s.observe("LookupMemories")(&err)
+	memories, err := s.getMemoriesByIDs(ids, SearchOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory: %w", err)
+		return nil, fmt.Errorf("failed to get memories: %w", err)
+	}
+	return memories, nil
+}
+
+// ExploreConnections finds memories within maxDepth hops of memoryID, following
+// edges of every type in either direction. Use Traverse for filters and edges.
+func (s *PostgresStore) ExploreConnections(memoryID int64, maxDepth int) (_ []Memory, err error) {
+	defer s.observe("ExploreConnections")(&err)
+	subgraph, err := s.Traverse(memoryID, TraversalOptions{MaxDepth: maxDepth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to explore connections: %w", err)
 	}
 
-	if groupIDPtr != nil {
-		memory.GroupID = *groupIDPtr
+	memories := []Memory{}
+	for _, node := range subgraph.Nodes {
+		if node.Memory.ID != memoryID {
+			memories = append(memories, node.Memory)
+		}
 	}
+	return memories, nil
+}
+
+// getMemoryByID fetches the memory row for id, expired or not
+func (s *PostgresStore) getMemoryByID(id int64) (*Memory, error) {
+	query := fmt.Sprintf("SELECT %s FROM memories WHERE id = $1", memoryColumns)
 
+	memory, err := scanMemory(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrMemoryNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
 	return &memory, nil
 }
 
-// ExploreConnections finds related memories using Apache AGE graph traversal
-func (s *PostgresStore) ExploreConnections(memoryID int64, maxDepth int) ([]Memory, error) {
-	// Initialize AGE for this connection
-	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
-		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+// getSimilarities fetches the memories among ids that match the metadata
+// filters in opts, with their similarity to the query embedding
+func (s *PostgresStore) getSimilarities(ids []int64, queryEmbedding []float64, opts SearchOptions) (map[int64]SearchResult, error) {
+	results := make(map[int64]SearchResult, len(ids))
+	if len(ids) == 0 {
+		return results, nil
 	}
 
-	// Use Apache AGE to find connected memories
-	query := fmt.Sprintf(`
-		SELECT * FROM cypher('memory_graph', $$
-			MATCH path = (start:Memory {id: %d})-[*1..%d]-(connected:Memory)
-			RETURN DISTINCT connected.id
-		$$) as (connected_id agtype);
-	`, memoryID, maxDepth)
+	args := queryArgs{}
+	vector := args.add(toVector(queryEmbedding))
+	conditions, err := metadataConditions(opts, &args)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append([]string{"id = ANY(" + args.add(pq.Array(ids)) + ")"}, conditions...)
 
-	rows, err := s.db.Query(query)
+	query := fmt.Sprintf("SELECT %s, 1 - (embedding <=> %s) FROM memories WHERE %s",
+		memoryColumns, vector, strings.Join(conditions, " AND "))
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to explore connections: %w", err)
+		return nil, fmt.Errorf("failed to query memories: %w", err)
 	}
 	defer rows.Close()
 
-	var connectedIDs []int64
 	for rows.Next() {
-		var idJSON string
-		if err := rows.Scan(&idJSON); err != nil {
-			continue
-		}
-
-		// Parse agtype JSON
-		var id int64
-		if err := json.Unmarshal([]byte(idJSON), &id); err != nil {
-			continue
+		var similarity float64
+		memory, err := scanMemory(rows, &similarity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
 		}
+		results[memory.ID] = SearchResult{Memory: memory, Similarity: similarity}
+	}
 
-		connectedIDs = append(connectedIDs, id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
 	}
+	return results, nil
+}
 
-	// Fetch full memory objects
-	if len(connectedIDs) == 0 {
+// getMemoriesByIDs fetches full memory rows for the given IDs, applying the
+// metadata filters in opts (limit and similarity are ignored)
+func (s *PostgresStore) getMemoriesByIDs(ids []int64, opts SearchOptions) ([]Memory, error) {
+	if len(ids) == 0 {
 		return []Memory{}, nil
 	}
 
-	// Build query to fetch memories
-	placeholders := ""
-	args := make([]interface{}, len(connectedIDs))
-	for i, id := range connectedIDs {
-		if i > 0 {
-			placeholders += ","
-		}
-		placeholders += fmt.Sprintf("$%d", i+1)
-		args[i] = id
+	args := queryArgs{}
+	conditions, err := metadataConditions(opts, &args)
+	if err != nil {
+		return nil, err
 	}
+	conditions = append([]string{"id = ANY(" + args.add(pq.Array(ids)) + ")"}, conditions...)
 
-	fetchQuery := fmt.Sprintf(`
-		SELECT id, text, group_id, created_at, updated_at
-		FROM memories
-		WHERE id IN (%s)
-	`, placeholders)
+	query := fmt.Sprintf("SELECT %s FROM memories WHERE %s", memoryColumns, strings.Join(conditions, " AND "))
+	return s.queryMemories(query, args...)
+}
 
-	memoryRows, err := s.db.Query(fetchQuery, args...)
+// queryMemories runs a query selecting memoryColumns and scans every row
+func (s *PostgresStore) queryMemories(query string, args ...interface{}) ([]Memory, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch connected memories: %w", err)
-	}
-	defer memoryRows.Close()
-
-	var memories []Memory
-	for memoryRows.Next() {
-		var memory Memory
-		var groupIDPtr *string
-
-		err := memoryRows.Scan(
-			&memory.ID,
-			&memory.Text,
-			&groupIDPtr,
-			&memory.CreatedAt,
-			&memory.UpdatedAt,
-		)
-		if err != nil {
-			continue
-		}
+		return nil, fmt.Errorf("failed to fetch memories: %w", err)
+	}
+	defer rows.Close()
 
-		if groupIDPtr != nil {
-			memory.GroupID = *groupIDPtr
+	memories := []Memory{}
+	for rows.Next() {
+		memory, err := scanMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
 		}
-
 		memories = append(memories, memory)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
+	}
+
 	return memories, nil
 }
 
-// getConnectedMemoryIDs finds memories connected to the given IDs via graph relationships
-// Returns a map of connected memory ID -> number of hops from original results
-func (s *PostgresStore) getConnectedMemoryIDs(startIDs []int64, maxDepth int) (map[int64]int, error) {
-	if len(startIDs) == 0 {
-		return make(map[int64]int), nil
+// memoryColumns lists the memories columns read by scanMemory, in scan order
+const memoryColumns = "id, external_id, text, group_id, tags, source, importance, attributes, created_at, updated_at, expires_at, last_accessed_at, access_count, cluster_id"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMemory scans memoryColumns, followed by any extra columns, into a Memory
+func scanMemory(row rowScanner, extra ...interface{}) (Memory, error) {
+	var memory Memory
+	var groupIDPtr, sourcePtr *string
+	var tags pq.StringArray
+	var attributes []byte
+	var expiresAt, lastAccessedAt sql.NullTime
+	var clusterID sql.NullInt64
+
+	dest := append([]interface{}{
+		&memory.ID,
+		&memory.ExternalID,
+		&memory.Text,
+		&groupIDPtr,
+		&tags,
+		&sourcePtr,
+		&memory.Importance,
+		&attributes,
+		&memory.CreatedAt,
+		&memory.UpdatedAt,
+		&expiresAt,
+		&lastAccessedAt,
+		&memory.AccessCount,
+		&clusterID,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return Memory{}, err
 	}
 
-	// Initialize AGE
-	if _, err := s.db.Exec("LOAD 'age'; SET search_path = ag_catalog, '$user', public;"); err != nil {
-		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	// Set optional fields
+	if groupIDPtr != nil {
+		memory.GroupID = *groupIDPtr
+	}
+	if sourcePtr != nil {
+		memory.Source = *sourcePtr
+	}
+	if len(tags) > 0 {
+		memory.Tags = tags
 	}
+	if expiresAt.Valid {
+		memory.ExpiresAt = expiresAt.Time
+	}
+	if lastAccessedAt.Valid {
+		memory.LastAccessedAt = lastAccessedAt.Time
+	}
+	memory.ClusterID = clusterID.Int64
 
-	connectedIDs := make(map[int64]int) // memory ID -> hop distance
+	var err error
+	memory.Attributes, err = unmarshalAttributes(attributes)
+	if err != nil {
+		return Memory{}, fmt.Errorf("failed to decode attributes of memory %d: %w", memory.ID, err)
+	}
 
-	// For each starting ID, traverse the graph
-	for _, startID := range startIDs {
-		query := fmt.Sprintf(`
-			SELECT * FROM cypher('memory_graph', $$
-				MATCH path = (start:Memory {id: %d})-[*1..%d]-(connected:Memory)
-				RETURN DISTINCT connected.id, length(path) as hops
-			$$) as (connected_id agtype, hop_count agtype);
-		`, startID, maxDepth)
+	return memory, nil
+}
 
-		rows, err := s.db.Query(query)
-		if err != nil {
-			// Graph might not have this node, continue
-			continue
-		}
+// queryArgs accumulates positional arguments while a query is being built
+type queryArgs []interface{}
 
-		for rows.Next() {
-			var idJSON, hopsJSON string
-			if err := rows.Scan(&idJSON, &hopsJSON); err != nil {
-				continue
-			}
+// add appends a value and returns its $N placeholder
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
 
-			var id, hops int64
-			if err := json.Unmarshal([]byte(idJSON), &id); err != nil {
-				continue
-			}
-			if err := json.Unmarshal([]byte(hopsJSON), &hops); err != nil {
-				continue
-			}
+// toVector converts []float64 to the []float32 pgvector expects
+func toVector(embedding []float64) pgvector.Vector {
+	embedding32 := make([]float32, len(embedding))
+	for i, v := range embedding {
+		embedding32[i] = float32(v)
+	}
+	return pgvector.NewVector(embedding32)
+}
 
-			// Skip if we already found this ID from a shorter path
-			if existingHops, exists := connectedIDs[id]; !exists || int(hops) < existingHops {
-				connectedIDs[id] = int(hops)
-			}
-		}
-		rows.Close()
+// parseVector converts pgvector's text form back to []float64
+func parseVector(text string) ([]float64, error) {
+	var vector pgvector.Vector
+	if err := vector.Parse(text); err != nil {
+		return nil, err
 	}
+	embedding := make([]float64, len(vector.Slice()))
+	for i, v := range vector.Slice() {
+		embedding[i] = float64(v)
+	}
+	return embedding, nil
+}
 
-	return connectedIDs, nil
+// observe starts a span for a storage method and returns the function that
+// ends it and records its latency. Call it as
+// defer s.observe("Method")(&err) with a named error result.
+func (s *PostgresStore) observe(method string) func(err *error) {
+	start := time.Now()
+	_, span := tracing.StartChild(s.ctx, "storage."+method, attribute.String("db.system", "postgresql"))
+	return func(err *error) {
+		metrics.ObserveQuery(method, start, *err)
+		tracing.End(span, *err)
+	}
 }
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// RankingProfile mixes vector similarity with recency, importance and access frequency.
+// Weights are relative: the final score is the weighted average of the components.
+type RankingProfile struct {
+	Name             string
+	SimilarityWeight float64
+	RecencyWeight    float64
+	ImportanceWeight float64
+	AccessWeight     float64
+	HalfLife         time.Duration // Age at which the recency component drops to 0.5
+	DecayField       string        // created_at, updated_at or last_accessed_at
+}
+
+// ScoreBreakdown reports the components that produced a result's score
+type ScoreBreakdown struct {
+	Similarity float64 `json:"similarity"`
+	Recency    float64 `json:"recency"`
+	Importance float64 `json:"importance"`
+	Access     float64 `json:"access"`
+}
+
+// Built-in ranking profiles. "similarity" is the default and keeps the
+// original pure pgvector ordering.
+var RankingProfiles = map[string]RankingProfile{
+	"similarity": {Name: "similarity", SimilarityWeight: 1},
+	"balanced": {
+		Name:             "balanced",
+		SimilarityWeight: 0.6,
+		RecencyWeight:    0.25,
+		ImportanceWeight: 0.15,
+		HalfLife:         30 * 24 * time.Hour,
+		DecayField:       "created_at",
+	},
+	"recent": {
+		Name:             "recent",
+		SimilarityWeight: 0.5,
+		RecencyWeight:    0.5,
+		HalfLife:         7 * 24 * time.Hour,
+		DecayField:       "updated_at",
+	},
+	"important": {
+		Name:             "important",
+		SimilarityWeight: 0.6,
+		ImportanceWeight: 0.4,
+	},
+	"popular": {
+		Name:             "popular",
+		SimilarityWeight: 0.6,
+		RecencyWeight:    0.15,
+		AccessWeight:     0.25,
+		HalfLife:         14 * 24 * time.Hour,
+		DecayField:       "last_accessed_at",
+	},
+}
+
+// GraphRanking ranks graph-discovered search results alongside the vector
+// hits instead of appending them. A neighbour's graph score is its seed's
+// similarity, multiplied for every edge on the way by HopDecay and by the
+// edge's type weight and confidence. Its relevance is the larger of that
+// and its own similarity to the query.
+type GraphRanking struct {
+	HopDecay    float64            // Multiplier per hop, in (0, 1] (default: 0.5)
+	TypeWeights map[string]float64 // Multiplier per relationship type, in [0, 1]; unlisted types weigh 1
+	MaxHops     int                // How far to expand from the vector hits, 1-3 (default: 1)
+}
+
+// DefaultHopDecay is the per-hop multiplier of graph scores
+const DefaultHopDecay = 0.5
+
+// maxGraphRankingHops bounds GraphRanking.MaxHops
+const maxGraphRankingHops = 3
+
+// accessSaturation is the access count at which the access component reaches 0.5
+const accessSaturation = 10
+
+// rerankPoolFactor controls how many vector hits are re-ranked per requested result
+const rerankPoolFactor = 5
+
+// minRerankPool is the smallest candidate pool fetched when re-ranking
+const minRerankPool = 50
+
+// Validate checks the profile's weights, half-life and decay field
+func (p RankingProfile) Validate() error {
+	weights := []float64{p.SimilarityWeight, p.RecencyWeight, p.ImportanceWeight, p.AccessWeight}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return fmt.Errorf("ranking weights must not be negative")
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one ranking weight must be positive")
+	}
+	if p.RecencyWeight > 0 && p.HalfLife <= 0 {
+		return fmt.Errorf("recency weighting requires a positive half-life")
+	}
+	switch p.DecayField {
+	case "", "created_at", "updated_at", "last_accessed_at":
+	default:
+		return fmt.Errorf("decay field must be created_at, updated_at or last_accessed_at, got %q", p.DecayField)
+	}
+	return nil
+}
+
+// Validate checks the decay, type weights and depth
+func (g GraphRanking) Validate() error {
+	if g.HopDecay < 0 || g.HopDecay > 1 {
+		return fmt.Errorf("hop decay must be between 0 and 1, got %v", g.HopDecay)
+	}
+	for relType, weight := range g.TypeWeights {
+		if weight < 0 || weight > 1 {
+			return fmt.Errorf("weight of %s must be between 0 and 1, got %v", relType, weight)
+		}
+	}
+	if g.MaxHops < 0 || g.MaxHops > maxGraphRankingHops {
+		return fmt.Errorf("max hops must be between 1 and %d, got %d", maxGraphRankingHops, g.MaxHops)
+	}
+	return nil
+}
+
+// hops returns the expansion depth, applying the default
+func (g GraphRanking) hops() int {
+	if g.MaxHops == 0 {
+		return 1
+	}
+	return g.MaxHops
+}
+
+// propagate computes the graph score of every neighbour from the similarity
+// of the seeds. Neighbours come in expansion order, so a parent is always
+// scored before its children.
+func (g GraphRanking) propagate(seedSimilarity map[int64]float64, neighbors []graphNeighbor) map[int64]float64 {
+	decay := g.HopDecay
+	if decay == 0 {
+		decay = DefaultHopDecay
+	}
+
+	scores := make(map[int64]float64, len(seedSimilarity)+len(neighbors))
+	for id, similarity := range seedSimilarity {
+		scores[id] = similarity
+	}
+	for _, neighbor := range neighbors {
+		weight, ok := g.TypeWeights[neighbor.Edge.Type]
+		if !ok {
+			weight = 1
+		}
+		confidence := min(max(EdgeConfidence(neighbor.Edge), 0), 1)
+		scores[neighbor.ID] = scores[neighbor.ParentID] * decay * weight * confidence
+	}
+	return scores
+}
+
+// rank scores vector hits and graph-discovered results together, using
+// each result's relevance in place of its similarity, and truncates the
+// merged list to limit
+func (g GraphRanking) rank(results []SearchResult, profile RankingProfile, limit int, now time.Time) []SearchResult {
+	for i := range results {
+		relevance := max(results[i].Similarity, results[i].GraphScore)
+		if profile.pureSimilarity() {
+			results[i].Score = relevance
+			continue
+		}
+		score, scores := profile.score(results[i].Memory, relevance, now)
+		results[i].Score = score
+		results[i].Scores = &scores
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// pureSimilarity reports whether the profile orders results exactly like pgvector
+func (p RankingProfile) pureSimilarity() bool {
+	return p.RecencyWeight == 0 && p.ImportanceWeight == 0 && p.AccessWeight == 0
+}
+
+// poolSize returns how many vector hits to fetch before re-ranking down to limit
+func (p RankingProfile) poolSize(limit int) int {
+	if p.pureSimilarity() {
+		return limit
+	}
+	return max(limit*rerankPoolFactor, minRerankPool)
+}
+
+// score computes the weighted score and its components for a memory with the given similarity
+func (p RankingProfile) score(memory Memory, similarity float64, now time.Time) (float64, ScoreBreakdown) {
+	breakdown := ScoreBreakdown{
+		Similarity: similarity,
+		Importance: memory.Importance,
+	}
+
+	if p.RecencyWeight > 0 {
+		timestamp := memory.CreatedAt
+		switch p.DecayField {
+		case "updated_at":
+			timestamp = memory.UpdatedAt
+		case "last_accessed_at":
+			// Never-accessed memories decay from when they were stored
+			if !memory.LastAccessedAt.IsZero() {
+				timestamp = memory.LastAccessedAt
+			}
+		}
+		// Exponential decay: 1 for brand-new memories, 0.5 after one half-life
+		age := max(now.Sub(timestamp), 0)
+		breakdown.Recency = math.Pow(0.5, age.Hours()/p.HalfLife.Hours())
+	}
+
+	// Saturating curve: frequent use helps, but ten reads count for half the maximum
+	count := float64(memory.AccessCount)
+	breakdown.Access = count / (count + accessSaturation)
+
+	totalWeight := p.SimilarityWeight + p.RecencyWeight + p.ImportanceWeight + p.AccessWeight
+	total := (p.SimilarityWeight*breakdown.Similarity +
+		p.RecencyWeight*breakdown.Recency +
+		p.ImportanceWeight*breakdown.Importance +
+		p.AccessWeight*breakdown.Access) / totalWeight
+
+	return total, breakdown
+}
+
+// rerank scores the results with the profile, sorts them by total score and truncates to limit
+func (p RankingProfile) rerank(results []SearchResult, limit int, now time.Time) []SearchResult {
+	for i := range results {
+		score, scores := p.score(results[i].Memory, results[i].Similarity, now)
+		results[i].Score = score
+		results[i].Scores = &scores
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
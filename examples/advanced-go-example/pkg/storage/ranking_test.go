@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rankingNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRankingProfiles(t *testing.T) {
+	for name, profile := range RankingProfiles {
+		t.Run(name, func(t *testing.T) {
+			if profile.Name != name {
+				t.Errorf("Name = %q, want %q", profile.Name, name)
+			}
+			if err := profile.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+	if !RankingProfiles["similarity"].pureSimilarity() {
+		t.Errorf("the similarity profile must keep the pgvector ordering")
+	}
+}
+
+func TestRankingProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile RankingProfile
+		wantErr string // Empty when the profile is valid
+	}{
+		{name: "similarity only", profile: RankingProfile{SimilarityWeight: 1}},
+		{name: "importance only", profile: RankingProfile{ImportanceWeight: 2}},
+		{name: "recency", profile: RankingProfile{RecencyWeight: 1, HalfLife: time.Hour, DecayField: "updated_at"}},
+		{name: "access only", profile: RankingProfile{AccessWeight: 1}},
+		{name: "decay on last access", profile: RankingProfile{RecencyWeight: 1, HalfLife: time.Hour, DecayField: "last_accessed_at"}},
+		{name: "half-life unused without recency", profile: RankingProfile{SimilarityWeight: 1, HalfLife: -time.Hour}},
+		{name: "negative weight", profile: RankingProfile{SimilarityWeight: 1, ImportanceWeight: -0.1}, wantErr: "must not be negative"},
+		{name: "negative access weight", profile: RankingProfile{SimilarityWeight: 1, AccessWeight: -1}, wantErr: "must not be negative"},
+		{name: "all zero", profile: RankingProfile{}, wantErr: "at least one ranking weight"},
+		{name: "recency without half-life", profile: RankingProfile{RecencyWeight: 1}, wantErr: "positive half-life"},
+		{name: "unknown decay field", profile: RankingProfile{SimilarityWeight: 1, DecayField: "expires_at"}, wantErr: `got "expires_at"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRankingProfilePoolSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile RankingProfile
+		limit   int
+		want    int
+	}{
+		{"pure similarity fetches the limit", RankingProfiles["similarity"], 10, 10},
+		{"small limit uses the minimum pool", RankingProfiles["balanced"], 4, minRerankPool},
+		{"large limit scales", RankingProfiles["balanced"], 20, 20 * rerankPoolFactor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.poolSize(tt.limit); got != tt.want {
+				t.Errorf("poolSize(%d) = %d, want %d", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankingProfileScore(t *testing.T) {
+	day := 24 * time.Hour
+	recency := RankingProfile{RecencyWeight: 1, HalfLife: 10 * day, DecayField: "created_at"}
+
+	tests := []struct {
+		name       string
+		profile    RankingProfile
+		memory     Memory
+		similarity float64
+		want       float64
+		wantScores ScoreBreakdown
+	}{
+		{
+			name:       "similarity only",
+			profile:    RankingProfile{SimilarityWeight: 1},
+			memory:     Memory{Importance: 0.9, CreatedAt: rankingNow.Add(-100 * day)},
+			similarity: 0.8,
+			want:       0.8,
+			wantScores: ScoreBreakdown{Similarity: 0.8, Importance: 0.9},
+		},
+		{
+			name:       "brand new",
+			profile:    recency,
+			memory:     Memory{CreatedAt: rankingNow},
+			want:       1,
+			wantScores: ScoreBreakdown{Recency: 1},
+		},
+		{
+			name:       "one half-life",
+			profile:    recency,
+			memory:     Memory{CreatedAt: rankingNow.Add(-10 * day)},
+			want:       0.5,
+			wantScores: ScoreBreakdown{Recency: 0.5},
+		},
+		{
+			name:       "two half-lives",
+			profile:    recency,
+			memory:     Memory{CreatedAt: rankingNow.Add(-20 * day)},
+			want:       0.25,
+			wantScores: ScoreBreakdown{Recency: 0.25},
+		},
+		{
+			name:       "future timestamps count as new",
+			profile:    recency,
+			memory:     Memory{CreatedAt: rankingNow.Add(day)},
+			want:       1,
+			wantScores: ScoreBreakdown{Recency: 1},
+		},
+		{
+			name:       "decay on updated_at",
+			profile:    RankingProfile{RecencyWeight: 1, HalfLife: 10 * day, DecayField: "updated_at"},
+			memory:     Memory{CreatedAt: rankingNow.Add(-100 * day), UpdatedAt: rankingNow.Add(-10 * day)},
+			want:       0.5,
+			wantScores: ScoreBreakdown{Recency: 0.5},
+		},
+		{
+			name:       "decay on last access",
+			profile:    RankingProfile{RecencyWeight: 1, HalfLife: 10 * day, DecayField: "last_accessed_at"},
+			memory:     Memory{CreatedAt: rankingNow.Add(-100 * day), LastAccessedAt: rankingNow.Add(-10 * day)},
+			want:       0.5,
+			wantScores: ScoreBreakdown{Recency: 0.5},
+		},
+		{
+			name:       "never accessed decays from creation",
+			profile:    RankingProfile{RecencyWeight: 1, HalfLife: 10 * day, DecayField: "last_accessed_at"},
+			memory:     Memory{CreatedAt: rankingNow.Add(-20 * day)},
+			want:       0.25,
+			wantScores: ScoreBreakdown{Recency: 0.25},
+		},
+		{
+			name:       "access saturates",
+			profile:    RankingProfile{AccessWeight: 1},
+			memory:     Memory{AccessCount: accessSaturation},
+			want:       0.5,
+			wantScores: ScoreBreakdown{Access: 0.5},
+		},
+		{
+			name:       "frequent access",
+			profile:    RankingProfile{AccessWeight: 1},
+			memory:     Memory{AccessCount: 3 * accessSaturation},
+			want:       0.75,
+			wantScores: ScoreBreakdown{Access: 0.75},
+		},
+		{
+			name:       "access reported even when unweighted",
+			profile:    RankingProfile{SimilarityWeight: 1},
+			memory:     Memory{AccessCount: accessSaturation},
+			similarity: 0.3,
+			want:       0.3,
+			wantScores: ScoreBreakdown{Similarity: 0.3, Access: 0.5},
+		},
+		{
+			name:       "weights are relative",
+			profile:    RankingProfile{SimilarityWeight: 3, ImportanceWeight: 1},
+			memory:     Memory{Importance: 0.2},
+			similarity: 0.6,
+			want:       0.5,
+			wantScores: ScoreBreakdown{Similarity: 0.6, Importance: 0.2},
+		},
+		{
+			name:       "balanced",
+			profile:    RankingProfiles["balanced"],
+			memory:     Memory{Importance: 1, CreatedAt: rankingNow.Add(-30 * day)},
+			similarity: 0.5,
+			want:       0.6*0.5 + 0.25*0.5 + 0.15*1,
+			wantScores: ScoreBreakdown{Similarity: 0.5, Recency: 0.5, Importance: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, scores := tt.profile.score(tt.memory, tt.similarity, rankingNow)
+			if !approxEqual(got, tt.want) {
+				t.Errorf("score() = %v, want %v", got, tt.want)
+			}
+			if !approxEqual(scores.Similarity, tt.wantScores.Similarity) ||
+				!approxEqual(scores.Recency, tt.wantScores.Recency) ||
+				!approxEqual(scores.Importance, tt.wantScores.Importance) ||
+				!approxEqual(scores.Access, tt.wantScores.Access) {
+				t.Errorf("score() breakdown = %+v, want %+v", scores, tt.wantScores)
+			}
+		})
+	}
+}
+
+func TestRankingProfileRerank(t *testing.T) {
+	old := rankingNow.Add(-60 * 24 * time.Hour)
+	results := func() []SearchResult {
+		return []SearchResult{
+			{Memory: Memory{ID: 1, Importance: 0, CreatedAt: old, UpdatedAt: rankingNow}}, // Old but just edited
+			{Memory: Memory{ID: 2, Importance: 1, CreatedAt: old, UpdatedAt: old, AccessCount: 90, LastAccessedAt: rankingNow}},
+			{Memory: Memory{ID: 3, Importance: 0, CreatedAt: rankingNow, UpdatedAt: rankingNow}},
+			{Memory: Memory{ID: 4, Importance: 0.5, CreatedAt: old, UpdatedAt: old}}, // Ties with 1 on similarity
+		}
+	}
+	similarities := []float64{0.9, 0.8, 0.7, 0.9}
+
+	tests := []struct {
+		name    string
+		profile RankingProfile
+		limit   int
+		want    []int64
+	}{
+		{"similarity keeps ties in input order", RankingProfiles["similarity"], 10, []int64{1, 4, 2, 3}},
+		{"important", RankingProfiles["important"], 10, []int64{2, 4, 1, 3}},
+		{"recent decays on updated_at", RankingProfiles["recent"], 10, []int64{1, 3, 4, 2}},
+		{"balanced", RankingProfiles["balanced"], 10, []int64{2, 4, 3, 1}},
+		{"popular", RankingProfiles["popular"], 10, []int64{2, 3, 1, 4}},
+		{"truncated to limit", RankingProfiles["important"], 2, []int64{2, 4}},
+		{"limit 0", RankingProfiles["important"], 0, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := results()
+			for i := range in {
+				in[i].Similarity = similarities[i]
+			}
+			ranked := tt.profile.rerank(in, tt.limit, rankingNow)
+
+			ids := []int64{}
+			for i, result := range ranked {
+				ids = append(ids, result.Memory.ID)
+				if result.Scores == nil {
+					t.Fatalf("result %d has no score breakdown", i)
+				}
+				if i > 0 && result.Score > ranked[i-1].Score {
+					t.Errorf("result %d scores %v, above the previous %v", i, result.Score, ranked[i-1].Score)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("rerank() order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGraphRankingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranking GraphRanking
+		wantErr string // Empty when the ranking is valid
+	}{
+		{name: "defaults", ranking: GraphRanking{}},
+		{name: "full", ranking: GraphRanking{HopDecay: 1, TypeWeights: map[string]float64{"RELATES_TO": 0, "SOLVES": 1}, MaxHops: maxGraphRankingHops}},
+		{name: "negative decay", ranking: GraphRanking{HopDecay: -0.1}, wantErr: "hop decay must be between 0 and 1"},
+		{name: "decay above 1", ranking: GraphRanking{HopDecay: 1.5}, wantErr: "hop decay must be between 0 and 1"},
+		{name: "type weight above 1", ranking: GraphRanking{TypeWeights: map[string]float64{"SOLVES": 2}}, wantErr: "weight of SOLVES"},
+		{name: "negative type weight", ranking: GraphRanking{TypeWeights: map[string]float64{"SOLVES": -1}}, wantErr: "weight of SOLVES"},
+		{name: "negative hops", ranking: GraphRanking{MaxHops: -1}, wantErr: "max hops must be between 1 and 3"},
+		{name: "too many hops", ranking: GraphRanking{MaxHops: maxGraphRankingHops + 1}, wantErr: "max hops must be between 1 and 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ranking.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := (GraphRanking{}).hops(); got != 1 {
+		t.Errorf("hops() = %d, want a default of 1", got)
+	}
+	if got := (GraphRanking{MaxHops: 3}).hops(); got != 3 {
+		t.Errorf("hops() = %d, want 3", got)
+	}
+}
+
+func TestGraphRankingPropagate(t *testing.T) {
+	edge := func(relType string, confidence interface{}) Relationship {
+		rel := Relationship{Type: relType}
+		if confidence != nil {
+			rel.Properties = map[string]interface{}{"confidence": confidence}
+		}
+		return rel
+	}
+	seeds := map[int64]float64{1: 0.8, 2: 0.6}
+
+	tests := []struct {
+		name      string
+		ranking   GraphRanking
+		neighbors []graphNeighbor
+		want      map[int64]float64
+	}{
+		{name: "seeds only", want: map[int64]float64{1: 0.8, 2: 0.6}},
+		{
+			name:      "default decay",
+			neighbors: []graphNeighbor{{ID: 3, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", nil)}},
+			want:      map[int64]float64{1: 0.8, 2: 0.6, 3: 0.4},
+		},
+		{
+			name:    "type weights",
+			ranking: GraphRanking{HopDecay: 1, TypeWeights: map[string]float64{"RELATES_TO": 0.5}},
+			neighbors: []graphNeighbor{
+				{ID: 3, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", nil)},
+				{ID: 4, Hops: 1, SeedID: 2, ParentID: 2, Edge: edge("SOLVES", nil)},
+			},
+			want: map[int64]float64{1: 0.8, 2: 0.6, 3: 0.4, 4: 0.6},
+		},
+		{
+			name:    "confidence",
+			ranking: GraphRanking{HopDecay: 1},
+			neighbors: []graphNeighbor{
+				{ID: 3, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", 0.5)},
+				{ID: 4, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", "0.25")},
+				{ID: 5, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", 2.0)},
+				{ID: 6, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", -1.0)},
+			},
+			want: map[int64]float64{1: 0.8, 2: 0.6, 3: 0.4, 4: 0.2, 5: 0.8, 6: 0},
+		},
+		{
+			name: "decays per hop",
+			neighbors: []graphNeighbor{
+				{ID: 3, Hops: 1, SeedID: 1, ParentID: 1, Edge: edge("RELATES_TO", nil)},
+				{ID: 4, Hops: 2, SeedID: 1, ParentID: 3, Edge: edge("RELATES_TO", nil)},
+				{ID: 5, Hops: 3, SeedID: 1, ParentID: 4, Edge: edge("RELATES_TO", 0.5)},
+			},
+			want: map[int64]float64{1: 0.8, 2: 0.6, 3: 0.4, 4: 0.2, 5: 0.05},
+		},
+		{
+			name:      "unknown parent",
+			neighbors: []graphNeighbor{{ID: 3, Hops: 1, SeedID: 9, ParentID: 9, Edge: edge("RELATES_TO", nil)}},
+			want:      map[int64]float64{1: 0.8, 2: 0.6, 3: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.ranking.propagate(seeds, tt.neighbors)
+			if len(got) != len(tt.want) {
+				t.Fatalf("propagate() = %v, want %v", got, tt.want)
+			}
+			for id, want := range tt.want {
+				if score, ok := got[id]; !ok || !approxEqual(score, want) {
+					t.Errorf("score of %d = %v, want %v", id, score, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphRankingRank(t *testing.T) {
+	results := func() []SearchResult {
+		return []SearchResult{
+			{Memory: Memory{ID: 1, CreatedAt: rankingNow}, Similarity: 0.9},
+			{Memory: Memory{ID: 2, CreatedAt: rankingNow}, Similarity: 0.2, GraphScore: 0.95, ViaRelationship: true},
+			{Memory: Memory{ID: 3, Importance: 1, CreatedAt: rankingNow}, Similarity: 0.5, GraphScore: 0.1, ViaRelationship: true},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		profile    RankingProfile
+		limit      int
+		want       []int64
+		wantScores []float64
+	}{
+		{"similarity uses the relevance", RankingProfiles["similarity"], 10, []int64{2, 1, 3}, []float64{0.95, 0.9, 0.5}},
+		{"important", RankingProfiles["important"], 10, []int64{3, 2, 1}, []float64{0.7, 0.57, 0.54}},
+		{"truncated to limit", RankingProfiles["similarity"], 1, []int64{2}, []float64{0.95}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := GraphRanking{}.rank(results(), tt.profile, tt.limit, rankingNow)
+
+			ids := []int64{}
+			for i, result := range ranked {
+				ids = append(ids, result.Memory.ID)
+				if !approxEqual(result.Score, tt.wantScores[i]) {
+					t.Errorf("score of %d = %v, want %v", result.Memory.ID, result.Score, tt.wantScores[i])
+				}
+				if tt.profile.pureSimilarity() != (result.Scores == nil) {
+					t.Errorf("result %d breakdown = %+v, want one only for weighted profiles", result.Memory.ID, result.Scores)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("rank() order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
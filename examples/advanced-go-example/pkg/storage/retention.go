@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultRetentionGroup is the policy group applied to groups without a policy of their own
+const DefaultRetentionGroup = "*"
+
+// RetentionPolicy limits how long memories in a group are kept. Zero-valued
+// rules are not enforced. GroupID "" covers memories without a group.
+type RetentionPolicy struct {
+	GroupID       string
+	MaxAge        time.Duration // Purge memories older than this
+	MaxCount      int           // Keep only the newest MaxCount memories
+	MinImportance float64       // Purge memories less important than this
+	UpdatedAt     time.Time
+}
+
+// Purge reasons reported for each candidate, in order of precedence
+const (
+	PurgeReasonExpired       = "expired"
+	PurgeReasonMaxAge        = "max_age"
+	PurgeReasonMaxCount      = "max_count"
+	PurgeReasonMinImportance = "min_importance"
+)
+
+// PurgeCandidate is a memory that retention rules would remove
+type PurgeCandidate struct {
+	ID        int64     `json:"id"`
+	Text      string    `json:"text"`
+	GroupID   string    `json:"group_id,omitzero"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+}
+
+// RetentionRun summarizes one enforcement pass
+type RetentionRun struct {
+	AuditID    int64            `json:"audit_id"`
+	RunAt      time.Time        `json:"run_at"`
+	DryRun     bool             `json:"dry_run"`
+	Candidates []PurgeCandidate `json:"candidates"`
+	Reasons    map[string]int   `json:"reasons"` // Candidate count per reason
+	Purged     int              `json:"purged"`
+}
+
+// SetRetentionPolicy creates or replaces the policy for a group
+func (s *PostgresStore) SetRetentionPolicy(policy RetentionPolicy) (err error) {
+	defer s.observe("SetRetentionPolicy")(&err)
+	query := `
+		INSERT INTO retention_policies (group_id, max_age, max_count, min_importance, updated_at)
+		VALUES ($1, make_interval(secs => $2), $3, $4, now())
+		ON CONFLICT (group_id) DO UPDATE SET
+			max_age = EXCLUDED.max_age,
+			max_count = EXCLUDED.max_count,
+			min_importance = EXCLUDED.min_importance,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	var maxAge, minImportance sql.NullFloat64
+	var maxCount sql.NullInt64
+	if policy.MaxAge > 0 {
+		maxAge = sql.NullFloat64{Float64: policy.MaxAge.Seconds(), Valid: true}
+	}
+	if policy.MaxCount > 0 {
+		maxCount = sql.NullInt64{Int64: int64(policy.MaxCount), Valid: true}
+	}
+	if policy.MinImportance > 0 {
+		minImportance = sql.NullFloat64{Float64: policy.MinImportance, Valid: true}
+	}
+
+	if _, err := s.db.Exec(query, policy.GroupID, maxAge, maxCount, minImportance); err != nil {
+		return fmt.Errorf("failed to set retention policy for group %q: %w", policy.GroupID, err)
+	}
+	return nil
+}
+
+// DeleteRetentionPolicy removes a group's policy. It reports whether one existed.
+func (s *PostgresStore) DeleteRetentionPolicy(groupID string) (_ bool, err error) {
+	defer s.observe("DeleteRetentionPolicy")(&err)
+	result, err := s.db.Exec("DELETE FROM retention_policies WHERE group_id = $1", groupID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete retention policy for group %q: %w", groupID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete retention policy for group %q: %w", groupID, err)
+	}
+	return affected > 0, nil
+}
+
+// ListRetentionPolicies returns every configured policy, ordered by group
+func (s *PostgresStore) ListRetentionPolicies() (_ []RetentionPolicy, err error) {
+	defer s.observe("ListRetentionPolicies")(&err)
+	query := `
+		SELECT group_id, EXTRACT(EPOCH FROM max_age)::float8, max_count, min_importance, updated_at
+		FROM retention_policies
+		ORDER BY group_id
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []RetentionPolicy{}
+	for rows.Next() {
+		var policy RetentionPolicy
+		var maxAge, minImportance sql.NullFloat64
+		var maxCount sql.NullInt64
+
+		if err := rows.Scan(&policy.GroupID, &maxAge, &maxCount, &minImportance, &policy.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+
+		policy.MaxAge = time.Duration(maxAge.Float64 * float64(time.Second))
+		policy.MaxCount = int(maxCount.Int64)
+		policy.MinImportance = minImportance.Float64
+		policies = append(policies, policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// retentionCandidatesQuery finds memories that are expired or violate their
+// group's policy (falling back to the '*' default policy). Each memory is
+// reported once, with the first reason that applies.
+const retentionCandidatesQuery = `
+	WITH ranked AS (
+		SELECT
+			m.id, m.text, COALESCE(m.group_id, '') AS group_id, m.created_at, m.expires_at, m.importance,
+			COALESCE(p.max_age, d.max_age) AS max_age,
+			COALESCE(p.max_count, d.max_count) AS max_count,
+			COALESCE(p.min_importance, d.min_importance) AS min_importance,
+			row_number() OVER (PARTITION BY COALESCE(m.group_id, '') ORDER BY m.created_at DESC, m.id DESC) AS newest_rank
+		FROM memories m
+		LEFT JOIN retention_policies p ON p.group_id = COALESCE(m.group_id, '')
+		LEFT JOIN retention_policies d ON d.group_id = '*' AND p.group_id IS NULL
+	), reasoned AS (
+		SELECT id, text, group_id, created_at,
+			CASE
+				WHEN expires_at IS NOT NULL AND expires_at <= now() THEN 'expired'
+				WHEN max_age IS NOT NULL AND created_at < now() - max_age THEN 'max_age'
+				WHEN max_count IS NOT NULL AND newest_rank > max_count THEN 'max_count'
+				WHEN min_importance IS NOT NULL AND importance < min_importance THEN 'min_importance'
+			END AS reason
+		FROM ranked
+	)
+	SELECT id, text, group_id, created_at, reason
+	FROM reasoned
+	WHERE reason IS NOT NULL
+	ORDER BY id
+`
+
+// RunRetention enforces expiry and retention policies. Memory rows and their
+// AGE nodes (with all their edges) are removed in one transaction, and every
+// run, including a dry run that removes nothing, writes a retention_audit row.
+func (s *PostgresStore) RunRetention(dryRun bool) (_ *RetentionRun, err error) {
+	defer s.observe("RunRetention")(&err)
+	run := &RetentionRun{
+		DryRun:     dryRun,
+		Candidates: []PurgeCandidate{},
+		Reasons:    map[string]int{},
+	}
+
+	rows, err := s.db.Query(retentionCandidatesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find retention candidates: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var candidate PurgeCandidate
+		if err := rows.Scan(&candidate.ID, &candidate.Text, &candidate.GroupID, &candidate.CreatedAt, &candidate.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan retention candidate: %w", err)
+		}
+		run.Candidates = append(run.Candidates, candidate)
+		run.Reasons[candidate.Reason]++
+		ids = append(ids, candidate.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention candidates: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin retention transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var groupIDs []string
+	if !dryRun && len(ids) > 0 {
+		if groupIDs, err = deleteMemoriesTx(tx, ids); err != nil {
+			return nil, err
+		}
+		run.Purged = len(ids)
+	}
+
+	reasons, err := json.Marshal(run.Reasons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode retention reasons: %w", err)
+	}
+
+	auditQuery := `
+		INSERT INTO public.retention_audit (dry_run, purged_count, memory_ids, reasons)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, run_at
+	`
+	if err := tx.QueryRow(auditQuery, dryRun, run.Purged, pq.Array(ids), string(reasons)).Scan(&run.AuditID, &run.RunAt); err != nil {
+		return nil, fmt.Errorf("failed to write retention audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit retention run: %w", err)
+	}
+
+	if run.Purged > 0 {
+		s.notifyChange(ids, groupIDs, true)
+	}
+	return run, nil
+}
+
+// deleteMemoriesTx removes memory rows and their AGE nodes (DETACH DELETE also
+// drops their edges) inside tx, so the table and graph never disagree. It
+// returns the groups of the deleted memories.
+func deleteMemoriesTx(tx *sql.Tx, ids []int64) ([]string, error) {
+	// SET LOCAL keeps the AGE search_path from leaking into the pooled connection
+	if _, err := tx.Exec("LOAD 'age'; SET LOCAL search_path = ag_catalog, '$user', public;"); err != nil {
+		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
+	}
+
+	idList := make([]string, len(ids))
+	for i, id := range ids {
+		idList[i] = fmt.Sprintf("%d", id)
+	}
+
+	cypherQuery := fmt.Sprintf(`
+		SELECT * FROM cypher('memory_graph', $$
+			MATCH (m:Memory)
+			WHERE m.id IN [%s]
+			DETACH DELETE m
+		$$) as (deleted agtype);
+	`, strings.Join(idList, ", "))
+
+	if _, err := tx.Exec(cypherQuery); err != nil {
+		return nil, fmt.Errorf("failed to delete AGE nodes: %w", err)
+	}
+
+	rows, err := tx.Query("DELETE FROM public.memories WHERE id = ANY($1) RETURNING COALESCE(group_id, '')", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete memories: %w", err)
+	}
+	defer rows.Close()
+
+	var groupIDs []string
+	for rows.Next() {
+		var groupID string
+		if err := rows.Scan(&groupID); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted memory: %w", err)
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete memories: %w", err)
+	}
+
+	return groupIDs, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// UpdateMemory replaces the editable fields of memory.ID: text, group, tags,
+// source, importance and attributes. The embedding is replaced when
+// memory.Embedding is set, which callers must do whenever the text changes.
+// It returns the memory as stored.
+func (s *PostgresStore) UpdateMemory(memory Memory) (_ *Memory, err error) {
+	defer s.observe("UpdateMemory")(&err)
+	attributes, err := marshalAttributes(memory.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedding interface{}
+	if memory.Embedding != nil {
+		embedding = toVector(memory.Embedding)
+	}
+
+	// Read the old group in the same statement so both groups are notified
+	query := fmt.Sprintf(`
+		WITH old AS (
+			SELECT id AS old_id, COALESCE(group_id, '') AS old_group_id FROM memories WHERE id = $1 FOR UPDATE
+		)
+		UPDATE memories SET
+			text = $2,
+			embedding = COALESCE($3, embedding),
+			group_id = $4,
+			tags = $5,
+			source = $6,
+			importance = $7,
+			attributes = $8
+		FROM old
+		WHERE id = old.old_id
+		RETURNING %s, old.old_group_id
+	`, memoryColumns)
+
+	var oldGroupID string
+	updated, err := scanMemory(s.db.QueryRow(
+		query,
+		memory.ID,
+		memory.Text,
+		embedding,
+		memory.GroupID,
+		pq.Array(normalizeTags(memory.Tags)),
+		memory.Source,
+		memory.Importance,
+		string(attributes),
+	), &oldGroupID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrMemoryNotFound, memory.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+
+	// The node text follows the row, so the graph stays in sync
+	if err := s.upsertMemoryNode(updated.ID, updated.Text); err != nil {
+		return nil, err
+	}
+
+	s.notifyChange([]int64{updated.ID}, []string{oldGroupID, updated.GroupID}, false)
+	return &updated, nil
+}
@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"advanced-go-example/pkg/exchange"
+	"advanced-go-example/pkg/graphexport"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ExportMemoriesInput defines input for export_memories tool
+type ExportMemoriesInput struct {
+	Path              string `json:"path" jsonschema:"New file on the server to write the JSONL export to; an existing file is not overwritten"`
+	GroupID           string `json:"group_id,omitempty" jsonschema:"Only export this group"`
+	IncludeEmbeddings *bool  `json:"include_embeddings,omitempty" jsonschema:"Include embedding vectors so imports with the same model skip re-embedding (default: true)"`
+}
+
+// ExportMemoriesOutput defines output for export_memories tool
+type ExportMemoriesOutput struct {
+	Path     string `json:"path"`
+	Memories int    `json:"memories"`
+	Edges    int    `json:"edges"`
+}
+
+func (h *memoryHandler) handleExportMemories(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ExportMemoriesInput,
+) (*mcp.CallToolResult, ExportMemoriesOutput, error) {
+	if input.Path == "" {
+		return nil, ExportMemoriesOutput{}, fmt.Errorf("path cannot be empty")
+	}
+
+	// Set defaults
+	includeEmbeddings := true
+	if input.IncludeEmbeddings != nil {
+		includeEmbeddings = *input.IncludeEmbeddings
+	}
+
+	var stats *exchange.ExportStats
+	err := writeFile(input.Path, func(w *bufio.Writer) (err error) {
+		stats, err = exchange.Export(h.store, w, exchange.ExportOptions{
+			GroupID:           input.GroupID,
+			IncludeEmbeddings: includeEmbeddings,
+			EmbeddingModel:    h.embeddings.Model(),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, ExportMemoriesOutput{}, fmt.Errorf("failed to export memories: %w", err)
+	}
+
+	return nil, ExportMemoriesOutput{
+		Path:     input.Path,
+		Memories: stats.Memories,
+		Edges:    stats.Edges,
+	}, nil
+}
+
+// ExportGraphInput defines input for export_graph tool
+type ExportGraphInput struct {
+	Path     string `json:"path" jsonschema:"New file on the server to write the graph to; an existing file is not overwritten"`
+	Format   string `json:"format,omitempty" jsonschema:"dot (Graphviz), graphml or cytoscape (Cytoscape.js JSON); default: from the path's extension (.dot/.gv, .graphml, .json/.cyjs)"`
+	MemoryID int64  `json:"memory_id,omitempty" jsonschema:"Only export the memories around this one (default: the whole graph)"`
+	Depth    int    `json:"depth,omitempty" jsonschema:"Hops around memory_id, 1-5 (default: 2)"`
+	GroupID  string `json:"group_id,omitempty" jsonschema:"Only export memories in this group and the edges between them"`
+}
+
+// ExportGraphOutput defines output for export_graph tool
+type ExportGraphOutput struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Nodes  int    `json:"nodes"`
+	Edges  int    `json:"edges"`
+}
+
+func (h *memoryHandler) handleExportGraph(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ExportGraphInput,
+) (*mcp.CallToolResult, ExportGraphOutput, error) {
+	if input.Path == "" {
+		return nil, ExportGraphOutput{}, fmt.Errorf("path cannot be empty")
+	}
+
+	// Set defaults
+	if input.Format == "" {
+		if input.Format = graphexport.FormatForPath(input.Path); input.Format == "" {
+			return nil, ExportGraphOutput{}, fmt.Errorf("cannot tell the format from %q; set format to one of %s", input.Path, strings.Join(graphexport.Formats, ", "))
+		}
+	}
+	if input.MemoryID == 0 && input.Depth != 0 {
+		return nil, ExportGraphOutput{}, fmt.Errorf("depth requires memory_id")
+	}
+	if input.Depth < 0 || input.Depth > 5 {
+		return nil, ExportGraphOutput{}, fmt.Errorf("depth must be between 1 and 5, got %d", input.Depth)
+	}
+	if !slices.Contains(graphexport.Formats, input.Format) {
+		return nil, ExportGraphOutput{}, fmt.Errorf("format must be one of %s, got %q", strings.Join(graphexport.Formats, ", "), input.Format)
+	}
+
+	graph, err := graphexport.Load(h.store, graphexport.Options{
+		MemoryID: input.MemoryID,
+		Depth:    input.Depth,
+		GroupID:  input.GroupID,
+	})
+	if err != nil {
+		return nil, ExportGraphOutput{}, err
+	}
+
+	err = writeFile(input.Path, func(w *bufio.Writer) error {
+		return graphexport.Write(w, graph, input.Format)
+	})
+	if err != nil {
+		return nil, ExportGraphOutput{}, fmt.Errorf("failed to export graph: %w", err)
+	}
+
+	return nil, ExportGraphOutput{
+		Path:   input.Path,
+		Format: input.Format,
+		Nodes:  len(graph.Nodes),
+		Edges:  len(graph.Edges),
+	}, nil
+}
+
+// writeFile runs write against a new buffered file at path. Tool callers
+// choose the path, so an existing file is never overwritten and the only
+// file removed on failure is the one created here.
+func writeFile(path string, write func(w *bufio.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	w := bufio.NewWriter(file)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// ImportMemoriesInput defines input for import_memories tool
+type ImportMemoriesInput struct {
+	Path string `json:"path" jsonschema:"JSONL export file on the server to import"`
+}
+
+// ImportMemoriesOutput defines output for import_memories tool
+type ImportMemoriesOutput struct {
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Reembedded   int `json:"reembedded"`
+	Edges        int `json:"edges"`
+	SkippedEdges int `json:"skipped_edges"`
+}
+
+func (h *memoryHandler) handleImportMemories(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ImportMemoriesInput,
+) (*mcp.CallToolResult, ImportMemoriesOutput, error) {
+	if input.Path == "" {
+		return nil, ImportMemoriesOutput{}, fmt.Errorf("path cannot be empty")
+	}
+
+	file, err := os.Open(input.Path)
+	if err != nil {
+		return nil, ImportMemoriesOutput{}, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	stats, err := exchange.Import(h.store, h.embeddings, file)
+	if err != nil {
+		// Records before the failure stay imported; importing again is safe
+		return nil, ImportMemoriesOutput{}, fmt.Errorf("import stopped after %d created and %d updated memories: %w", stats.Created, stats.Updated, err)
+	}
+
+	return nil, ImportMemoriesOutput{
+		Created:      stats.Created,
+		Updated:      stats.Updated,
+		Reembedded:   stats.Reembedded,
+		Edges:        stats.Edges,
+		SkippedEdges: stats.SkippedEdges,
+	}, nil
+}
@@ -0,0 +1,561 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"advanced-go-example/pkg/analytics"
+	"advanced-go-example/pkg/ontology"
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// EdgeOutput is a graph edge with its reason and confidence pulled out of the properties
+type EdgeOutput struct {
+	ID         int64                  `json:"id"`
+	FromID     int64                  `json:"from_id"`
+	ToID       int64                  `json:"to_id"`
+	Type       string                 `json:"type"`
+	Reason     string                 `json:"reason,omitzero"`
+	Confidence float64                `json:"confidence,omitzero"`
+	Properties map[string]interface{} `json:"properties,omitzero"`
+	Depth      int                    `json:"depth,omitzero"` // Hop at which explore_connections followed the edge
+}
+
+func toEdgeOutput(rel storage.Relationship) EdgeOutput {
+	edge := EdgeOutput{
+		ID:         rel.ID,
+		FromID:     rel.FromID,
+		ToID:       rel.ToID,
+		Type:       rel.Type,
+		Properties: rel.Properties,
+	}
+	edge.Reason, _ = rel.Properties["reason"].(string)
+	if _, ok := rel.Properties["confidence"]; ok {
+		edge.Confidence = storage.EdgeConfidence(rel)
+	}
+	return edge
+}
+
+// addRelationship validates an edge against the ontology and stores it under
+// its canonical type and direction
+func (h *memoryHandler) addRelationship(fromID, toID int64, relType string, properties map[string]interface{}) (storage.Relationship, error) {
+	fromID, toID, relType, err := h.ontology.Normalize(fromID, toID, relType, properties)
+	if err != nil {
+		return storage.Relationship{}, err
+	}
+
+	id, err := h.store.AddRelationship(fromID, toID, relType, properties)
+	if err != nil {
+		return storage.Relationship{}, err
+	}
+	return storage.Relationship{ID: id, FromID: fromID, ToID: toID, Type: relType, Properties: properties}, nil
+}
+
+// ListRelationshipTypesInput defines input for list_relationship_types tool
+type ListRelationshipTypesInput struct{}
+
+// ListRelationshipTypesOutput defines output for list_relationship_types tool
+type ListRelationshipTypesOutput struct {
+	Types            []ontology.RelationshipType `json:"types"`
+	SystemProperties []string                    `json:"system_properties"` // Allowed on every type
+}
+
+func (h *memoryHandler) handleListRelationshipTypes(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ListRelationshipTypesInput,
+) (*mcp.CallToolResult, ListRelationshipTypesOutput, error) {
+	return nil, ListRelationshipTypesOutput{
+		Types:            h.ontology.Types(),
+		SystemProperties: ontology.SystemProperties,
+	}, nil
+}
+
+// FindPathInput defines input for find_path tool
+type FindPathInput struct {
+	FromID            int64    `json:"from_id" jsonschema:"Memory ID the path starts at"`
+	ToID              int64    `json:"to_id" jsonschema:"Memory ID the path ends at"`
+	MaxDepth          int      `json:"max_depth,omitempty" jsonschema:"Longest path to consider, in edges, 1-6 (default: 4)"`
+	Limit             int      `json:"limit,omitempty" jsonschema:"Number of shortest paths to return, 1-10 (default: 1)"`
+	RelationshipTypes []string `json:"relationship_types,omitempty" jsonschema:"Only follow these relationship types (default: all)"`
+}
+
+// PathOutput is one path: the memories in order and the edges joining them
+type PathOutput struct {
+	Length    int          `json:"length"`
+	MemoryIDs []int64      `json:"memory_ids"`
+	Edges     []EdgeOutput `json:"edges"`
+}
+
+// FindPathOutput defines output for find_path tool
+type FindPathOutput struct {
+	Paths    []PathOutput     `json:"paths"`
+	Memories []storage.Memory `json:"memories,omitzero"` // Every memory on the returned paths
+	Message  string           `json:"message,omitzero"`
+}
+
+func (h *memoryHandler) handleFindPath(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input FindPathInput,
+) (*mcp.CallToolResult, FindPathOutput, error) {
+	if input.FromID == 0 || input.ToID == 0 {
+		return nil, FindPathOutput{}, fmt.Errorf("from_id and to_id are required")
+	}
+
+	// Set defaults
+	if input.MaxDepth == 0 {
+		input.MaxDepth = 4
+	}
+	if input.Limit == 0 {
+		input.Limit = 1
+	}
+	if input.MaxDepth < 1 || input.MaxDepth > 6 {
+		return nil, FindPathOutput{}, fmt.Errorf("max_depth must be between 1 and 6, got %d", input.MaxDepth)
+	}
+	if input.Limit < 1 || input.Limit > 10 {
+		return nil, FindPathOutput{}, fmt.Errorf("limit must be between 1 and 10, got %d", input.Limit)
+	}
+
+	// Report missing or expired memories instead of an empty result
+	endpoints, err := h.store.LookupMemories([]int64{input.FromID, input.ToID})
+	if err != nil {
+		return nil, FindPathOutput{}, err
+	}
+	found := make(map[int64]bool, len(endpoints))
+	for _, memory := range endpoints {
+		found[memory.ID] = true
+	}
+	for _, id := range []int64{input.FromID, input.ToID} {
+		if !found[id] {
+			return nil, FindPathOutput{}, fmt.Errorf("%w: %d", storage.ErrMemoryNotFound, id)
+		}
+	}
+
+	paths, err := h.store.FindPaths(input.FromID, input.ToID, storage.PathOptions{
+		MaxDepth: input.MaxDepth,
+		Limit:    input.Limit,
+		Types:    input.RelationshipTypes,
+	})
+	if err != nil {
+		return nil, FindPathOutput{}, fmt.Errorf("failed to find paths: %w", err)
+	}
+
+	output := FindPathOutput{Paths: []PathOutput{}}
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, path := range paths {
+		pathOutput := PathOutput{Length: len(path.Edges), MemoryIDs: path.MemoryIDs}
+		for _, edge := range path.Edges {
+			pathOutput.Edges = append(pathOutput.Edges, toEdgeOutput(edge))
+		}
+		output.Paths = append(output.Paths, pathOutput)
+
+		for _, id := range path.MemoryIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		output.Message = fmt.Sprintf("No path of at most %d edges connects memories %d and %d", input.MaxDepth, input.FromID, input.ToID)
+		return nil, output, nil
+	}
+
+	if output.Memories, err = h.store.GetMemoriesByIDs(ids); err != nil {
+		return nil, FindPathOutput{}, err
+	}
+	output.Message = fmt.Sprintf("Found %d path(s); the shortest has %d edge(s)", len(paths), output.Paths[0].Length)
+	return nil, output, nil
+}
+
+// ListRelationshipsInput defines input for list_relationships tool
+type ListRelationshipsInput struct {
+	MemoryID     int64  `json:"memory_id,omitempty" jsonschema:"Only edges starting or ending at this memory"`
+	Type         string `json:"type,omitempty" jsonschema:"Only edges of this relationship type"`
+	AutoDetected *bool  `json:"auto_detected,omitempty" jsonschema:"Only edges created (true) or not created (false) by auto-detection"`
+	Limit        int    `json:"limit,omitempty" jsonschema:"Maximum edges, 1-500 (default: 100)"`
+}
+
+// ListRelationshipsOutput defines output for list_relationships tool
+type ListRelationshipsOutput struct {
+	Relationships []EdgeOutput `json:"relationships"`
+	Count         int          `json:"count"`
+}
+
+func (h *memoryHandler) handleListRelationships(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ListRelationshipsInput,
+) (*mcp.CallToolResult, ListRelationshipsOutput, error) {
+	// Set defaults
+	if input.Limit == 0 {
+		input.Limit = 100
+	}
+	if input.Limit < 1 || input.Limit > 500 {
+		return nil, ListRelationshipsOutput{}, fmt.Errorf("limit must be between 1 and 500, got %d", input.Limit)
+	}
+
+	relationships, err := h.store.ListRelationships(storage.RelationshipFilter{
+		MemoryID:     input.MemoryID,
+		Type:         input.Type,
+		AutoDetected: input.AutoDetected,
+		Limit:        input.Limit,
+	})
+	if err != nil {
+		return nil, ListRelationshipsOutput{}, fmt.Errorf("failed to list relationships: %w", err)
+	}
+
+	output := ListRelationshipsOutput{Relationships: []EdgeOutput{}}
+	for _, rel := range relationships {
+		output.Relationships = append(output.Relationships, toEdgeOutput(rel))
+	}
+	output.Count = len(output.Relationships)
+	return nil, output, nil
+}
+
+// UpdateRelationshipInput defines input for update_relationship tool
+type UpdateRelationshipInput struct {
+	ID               int64                  `json:"id" jsonschema:"Edge ID from list_relationships, add_relationship or explore_connections"`
+	Type             string                 `json:"type,omitempty" jsonschema:"New relationship type; the edge is replaced and gets a new ID"`
+	Properties       map[string]interface{} `json:"properties,omitempty" jsonschema:"Properties to add or overwrite, e.g. {\"confidence\": 0.4}"`
+	RemoveProperties []string               `json:"remove_properties,omitempty" jsonschema:"Property names to remove"`
+}
+
+// UpdateRelationshipOutput defines output for update_relationship tool
+type UpdateRelationshipOutput struct {
+	Relationship EdgeOutput `json:"relationship"`
+	Message      string     `json:"message,omitzero"`
+}
+
+func (h *memoryHandler) handleUpdateRelationship(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input UpdateRelationshipInput,
+) (*mcp.CallToolResult, UpdateRelationshipOutput, error) {
+	if input.ID == 0 {
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("id is required")
+	}
+	if input.Type == "" && len(input.Properties) == 0 && len(input.RemoveProperties) == 0 {
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("nothing to update: give type, properties or remove_properties")
+	}
+
+	// Check the new type, or the current one, against the ontology
+	current, err := h.store.GetRelationship(input.ID)
+	if err != nil {
+		return nil, UpdateRelationshipOutput{}, err
+	}
+	typeName := current.Type
+	if input.Type != "" {
+		typeName = input.Type
+	}
+	t, reversed, ok := h.ontology.Lookup(typeName)
+	switch {
+	case !ok && input.Type != "":
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("unknown relationship type %q (known: %s)", input.Type, strings.Join(h.ontology.Names(), ", "))
+	case reversed:
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("%s is the inverse of %s: delete this edge and add it again with add_relationship", typeName, t.Name)
+	case t.Internal && input.Type != "":
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("relationship type %s is managed by the server", t.Name)
+	case ok:
+		if err := t.CheckProperties(input.Properties); err != nil {
+			return nil, UpdateRelationshipOutput{}, err
+		}
+	}
+
+	rel, err := h.store.UpdateRelationship(input.ID, storage.RelationshipUpdate{
+		Type:   input.Type,
+		Set:    input.Properties,
+		Remove: input.RemoveProperties,
+	})
+	if err != nil {
+		return nil, UpdateRelationshipOutput{}, fmt.Errorf("failed to update relationship: %w", err)
+	}
+
+	message := fmt.Sprintf("Relationship %d updated", rel.ID)
+	if rel.ID != input.ID {
+		message = fmt.Sprintf("Relationship %d replaced by %s edge %d", input.ID, rel.Type, rel.ID)
+	}
+	return nil, UpdateRelationshipOutput{
+		Relationship: toEdgeOutput(*rel),
+		Message:      message,
+	}, nil
+}
+
+// DeleteRelationshipInput defines input for delete_relationship tool
+type DeleteRelationshipInput struct {
+	ID int64 `json:"id" jsonschema:"Edge ID to delete"`
+}
+
+// DeleteRelationshipOutput defines output for delete_relationship tool
+type DeleteRelationshipOutput struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitzero"`
+}
+
+func (h *memoryHandler) handleDeleteRelationship(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input DeleteRelationshipInput,
+) (*mcp.CallToolResult, DeleteRelationshipOutput, error) {
+	if input.ID == 0 {
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("id is required")
+	}
+
+	deleted, err := h.store.DeleteRelationship(input.ID)
+	if err != nil {
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("failed to delete relationship: %w", err)
+	}
+	if !deleted {
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("%w: %d", storage.ErrRelationshipNotFound, input.ID)
+	}
+
+	return nil, DeleteRelationshipOutput{
+		Success: true,
+		Message: fmt.Sprintf("Relationship %d deleted", input.ID),
+	}, nil
+}
+
+// GraphStatsInput defines input for graph_stats tool
+type GraphStatsInput struct {
+	GroupID      string `json:"group_id,omitempty" jsonschema:"Only analyze memories in this group and the edges between them (default: all groups)"`
+	Top          int    `json:"top,omitempty" jsonschema:"Memories listed by PageRank and by degree, and communities listed, 1-50 (default: 10)"`
+	OrphanLimit  int    `json:"orphan_limit,omitempty" jsonschema:"Orphan memories listed, 1-200 (default: 20); all are counted"`
+	SaveClusters bool   `json:"save_clusters,omitempty" jsonschema:"Save each memory's community as its cluster_id, for browsing with list_memories"`
+}
+
+// DegreeStats summarizes how many edges memories have
+type DegreeStats struct {
+	Min          int           `json:"min"`
+	Max          int           `json:"max"`
+	Mean         float64       `json:"mean"`
+	Median       float64       `json:"median"`
+	Distribution []DegreeCount `json:"distribution"` // Ascending by degree
+}
+
+// DegreeCount is the number of memories with a given degree
+type DegreeCount struct {
+	Degree int `json:"degree"`
+	Count  int `json:"count"`
+}
+
+// RankedMemory is a memory with its centrality in the graph
+type RankedMemory struct {
+	Memory    storage.Memory `json:"memory"`
+	Degree    int            `json:"degree"`
+	PageRank  float64        `json:"pagerank"`
+	ClusterID int64          `json:"cluster_id,omitzero"` // Community found by this run
+}
+
+// CommunityOutput is a community found by label propagation
+type CommunityOutput struct {
+	ClusterID  int64          `json:"cluster_id"` // Smallest member ID
+	Size       int            `json:"size"`
+	TopMembers []RankedMemory `json:"top_members"` // Up to 3, by PageRank
+}
+
+// GraphStatsOutput defines output for graph_stats tool
+type GraphStatsOutput struct {
+	NodeCount      int               `json:"node_count"`
+	EdgeCount      int               `json:"edge_count"`
+	EdgesByType    map[string]int    `json:"edges_by_type"`
+	Degree         DegreeStats       `json:"degree"`
+	OrphanCount    int               `json:"orphan_count"`
+	Orphans        []storage.Memory  `json:"orphans,omitzero"` // Memories without edges, oldest first
+	TopByPageRank  []RankedMemory    `json:"top_by_pagerank"`
+	TopByDegree    []RankedMemory    `json:"top_by_degree"`
+	CommunityCount int               `json:"community_count"`
+	Communities    []CommunityOutput `json:"communities"` // Largest first
+	ClustersSaved  int               `json:"clusters_saved,omitzero"`
+}
+
+// communityMembersShown bounds the members listed per community
+const communityMembersShown = 3
+
+func (h *memoryHandler) handleGraphStats(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input GraphStatsInput,
+) (*mcp.CallToolResult, GraphStatsOutput, error) {
+	// Set defaults
+	if input.Top == 0 {
+		input.Top = 10
+	}
+	if input.OrphanLimit == 0 {
+		input.OrphanLimit = 20
+	}
+	if input.Top < 1 || input.Top > 50 {
+		return nil, GraphStatsOutput{}, fmt.Errorf("top must be between 1 and 50, got %d", input.Top)
+	}
+	if input.OrphanLimit < 1 || input.OrphanLimit > 200 {
+		return nil, GraphStatsOutput{}, fmt.Errorf("orphan_limit must be between 1 and 200, got %d", input.OrphanLimit)
+	}
+
+	snapshot, err := h.store.GraphSnapshot(input.GroupID)
+	if err != nil {
+		return nil, GraphStatsOutput{}, fmt.Errorf("failed to export graph: %w", err)
+	}
+
+	output := GraphStatsOutput{
+		NodeCount:   len(snapshot.MemoryIDs),
+		EdgeCount:   len(snapshot.Edges),
+		EdgesByType: map[string]int{},
+	}
+	edges := make([]analytics.Edge, len(snapshot.Edges))
+	for i, rel := range snapshot.Edges {
+		output.EdgesByType[rel.Type]++
+		edges[i] = analytics.Edge{From: rel.FromID, To: rel.ToID, Weight: storage.EdgeConfidence(rel)}
+	}
+
+	graph := analytics.NewGraph(snapshot.MemoryIDs, edges)
+	degrees := graph.Degrees()
+	ranks := graph.PageRank(analytics.DefaultDamping)
+	communities := graph.Communities()
+
+	output.Degree = degreeStats(snapshot.MemoryIDs, degrees)
+
+	// Memory IDs are in ascending order, so orphans come oldest first
+	var orphans []int64
+	for _, id := range snapshot.MemoryIDs {
+		if degrees[id] == 0 {
+			orphans = append(orphans, id)
+		}
+	}
+	output.OrphanCount = len(orphans)
+	orphans = orphans[:min(len(orphans), input.OrphanLimit)]
+
+	byPageRank := topIDs(snapshot.MemoryIDs, input.Top, func(id int64) float64 { return ranks[id] })
+	byDegree := topIDs(snapshot.MemoryIDs, input.Top, func(id int64) float64 { return float64(degrees[id]) })
+
+	// Group members by community, largest community first
+	members := map[int64][]int64{}
+	for _, id := range snapshot.MemoryIDs {
+		if clusterID, ok := communities[id]; ok {
+			members[clusterID] = append(members[clusterID], id)
+		}
+	}
+	clusterIDs := make([]int64, 0, len(members))
+	for clusterID := range members {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	slices.SortFunc(clusterIDs, func(a, b int64) int {
+		if c := cmp.Compare(len(members[b]), len(members[a])); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	output.CommunityCount = len(clusterIDs)
+	clusterIDs = clusterIDs[:min(len(clusterIDs), input.Top)]
+
+	topMembers := map[int64][]int64{}
+	for _, clusterID := range clusterIDs {
+		topMembers[clusterID] = topIDs(members[clusterID], communityMembersShown, func(id int64) float64 { return ranks[id] })
+	}
+
+	// Fetch every memory the report shows in one query
+	shown := append(append(append([]int64(nil), orphans...), byPageRank...), byDegree...)
+	for _, ids := range topMembers {
+		shown = append(shown, ids...)
+	}
+	found, err := h.store.LookupMemories(shown)
+	if err != nil {
+		return nil, GraphStatsOutput{}, err
+	}
+	memories := make(map[int64]storage.Memory, len(found))
+	for _, memory := range found {
+		memories[memory.ID] = memory
+	}
+
+	ranked := func(ids []int64) []RankedMemory {
+		list := []RankedMemory{}
+		for _, id := range ids {
+			if memory, ok := memories[id]; ok {
+				list = append(list, RankedMemory{Memory: memory, Degree: degrees[id], PageRank: ranks[id], ClusterID: communities[id]})
+			}
+		}
+		return list
+	}
+	for _, id := range orphans {
+		if memory, ok := memories[id]; ok {
+			output.Orphans = append(output.Orphans, memory)
+		}
+	}
+	output.TopByPageRank = ranked(byPageRank)
+	output.TopByDegree = ranked(byDegree)
+	output.Communities = make([]CommunityOutput, len(clusterIDs))
+	for i, clusterID := range clusterIDs {
+		output.Communities[i] = CommunityOutput{
+			ClusterID:  clusterID,
+			Size:       len(members[clusterID]),
+			TopMembers: ranked(topMembers[clusterID]),
+		}
+	}
+
+	if input.SaveClusters {
+		if err := h.store.SetClusters(input.GroupID, communities); err != nil {
+			return nil, GraphStatsOutput{}, err
+		}
+		output.ClustersSaved = len(communities)
+	}
+
+	return nil, output, nil
+}
+
+// degreeStats summarizes the degrees of ids, which must be in ascending order
+func degreeStats(ids []int64, degrees map[int64]int) DegreeStats {
+	stats := DegreeStats{Distribution: []DegreeCount{}}
+	if len(ids) == 0 {
+		return stats
+	}
+
+	sorted := make([]int, len(ids))
+	total := 0
+	for i, id := range ids {
+		sorted[i] = degrees[id]
+		total += degrees[id]
+	}
+	slices.Sort(sorted)
+
+	stats.Min, stats.Max = sorted[0], sorted[len(sorted)-1]
+	stats.Mean = float64(total) / float64(len(sorted))
+	if middle := len(sorted) / 2; len(sorted)%2 == 1 {
+		stats.Median = float64(sorted[middle])
+	} else {
+		stats.Median = float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+
+	for _, degree := range sorted {
+		last := len(stats.Distribution) - 1
+		if last >= 0 && stats.Distribution[last].Degree == degree {
+			stats.Distribution[last].Count++
+		} else {
+			stats.Distribution = append(stats.Distribution, DegreeCount{Degree: degree, Count: 1})
+		}
+	}
+	return stats
+}
+
+// topIDs returns up to n of ids with the highest score, ties broken by ID.
+// Memories scoring zero are left out.
+func topIDs(ids []int64, n int, score func(int64) float64) []int64 {
+	top := []int64{}
+	for _, id := range ids {
+		if score(id) > 0 {
+			top = append(top, id)
+		}
+	}
+	slices.SortFunc(top, func(a, b int64) int {
+		if c := cmp.Compare(score(b), score(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return top[:min(len(top), n)]
+}
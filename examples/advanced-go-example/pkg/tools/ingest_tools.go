@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"advanced-go-example/pkg/ingest"
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// IngestDocumentInput defines input for ingest_document tool
+type IngestDocumentInput struct {
+	Path         string   `json:"path" jsonschema:"Markdown or text file, or a directory of .md/.txt files, on the server"`
+	GroupID      string   `json:"group_id,omitempty" jsonschema:"Optional group for the chunks"`
+	Tags         []string `json:"tags,omitempty" jsonschema:"Optional tags for every chunk"`
+	Importance   *float64 `json:"importance,omitempty" jsonschema:"Importance from 0 (trivial) to 1 (critical) (default: 0.5)"`
+	Format       string   `json:"format,omitempty" jsonschema:"auto, markdown or text (default: auto, by file extension)"`
+	ChunkSize    int      `json:"chunk_size,omitempty" jsonschema:"Maximum chunk size in bytes (default: 1000)"`
+	ChunkOverlap int      `json:"chunk_overlap,omitempty" jsonschema:"Bytes shared by consecutive chunks (default: 150, -1 disables)"`
+}
+
+// IngestDocumentOutput defines output for ingest_document tool
+type IngestDocumentOutput struct {
+	Documents []ingest.DocumentResult `json:"documents"`
+	Chunks    int                     `json:"chunks"`
+}
+
+func (h *memoryHandler) handleIngestDocument(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input IngestDocumentInput,
+) (*mcp.CallToolResult, IngestDocumentOutput, error) {
+	if input.Path == "" {
+		return nil, IngestDocumentOutput{}, fmt.Errorf("path cannot be empty")
+	}
+
+	// Set defaults
+	importance := storage.DefaultImportance
+	if input.Importance != nil {
+		importance = *input.Importance
+	}
+	if importance < 0 || importance > 1 {
+		return nil, IngestDocumentOutput{}, fmt.Errorf("importance must be between 0 and 1, got %v", importance)
+	}
+
+	ingester := ingest.NewIngester(h.store, h.embeddings)
+	results, err := ingester.IngestPath(input.Path, ingest.Options{
+		GroupID:    input.GroupID,
+		Tags:       input.Tags,
+		Importance: importance,
+		Format:     input.Format,
+		Chunking: ingest.ChunkOptions{
+			Size:    input.ChunkSize,
+			Overlap: input.ChunkOverlap,
+		},
+	})
+	if err != nil {
+		return nil, IngestDocumentOutput{}, fmt.Errorf("ingestion stopped after %d documents: %w", len(results), err)
+	}
+
+	output := IngestDocumentOutput{Documents: results}
+	for _, result := range results {
+		output.Chunks += result.Chunks
+	}
+
+	return nil, output, nil
+}
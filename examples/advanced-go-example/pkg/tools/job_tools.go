@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"advanced-go-example/pkg/detect"
+	"advanced-go-example/pkg/jobs"
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// JobOutput is a background job as reported by get_job_status
+type JobOutput struct {
+	ID          int64       `json:"id"`
+	Kind        string      `json:"kind"`
+	MemoryID    int64       `json:"memory_id,omitzero"`
+	Status      string      `json:"status"` // pending, running, succeeded or failed
+	Attempts    int         `json:"attempts"`
+	MaxAttempts int         `json:"max_attempts"`
+	LastError   string      `json:"last_error,omitzero"`
+	Progress    interface{} `json:"progress,omitzero"` // Checkpoint of a long job such as a backfill
+	Result      interface{} `json:"result,omitzero"`
+	RetryAt     time.Time   `json:"retry_at,omitzero"` // When a pending job that already failed is tried again
+	CreatedAt   time.Time   `json:"created_at"`
+	StartedAt   time.Time   `json:"started_at,omitzero"`
+	FinishedAt  time.Time   `json:"finished_at,omitzero"`
+}
+
+func toJobOutput(job storage.Job) JobOutput {
+	output := JobOutput{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		MemoryID:    job.MemoryID,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+	if job.Status == storage.JobPending && job.Attempts > 0 {
+		output.RetryAt = job.RunAfter
+	}
+	// Both were stored as JSON by the job queue
+	if len(job.Progress) > 0 && job.Status != storage.JobSucceeded {
+		_ = json.Unmarshal(job.Progress, &output.Progress)
+	}
+	if len(job.Result) > 0 {
+		_ = json.Unmarshal(job.Result, &output.Result)
+	}
+	return output
+}
+
+// GetJobStatusInput defines input for get_job_status tool
+type GetJobStatusInput struct {
+	JobID    int64 `json:"job_id,omitempty" jsonschema:"Job to report, e.g. the job_id returned by store_memory"`
+	MemoryID int64 `json:"memory_id,omitempty" jsonschema:"Report the most recent jobs for this memory instead"`
+	Limit    int   `json:"limit,omitempty" jsonschema:"Maximum jobs returned for memory_id, 1-50 (default: 5)"`
+}
+
+// GetJobStatusOutput defines output for get_job_status tool
+type GetJobStatusOutput struct {
+	Jobs  []JobOutput `json:"jobs"` // Newest first
+	Count int         `json:"count"`
+}
+
+func (h *memoryHandler) handleGetJobStatus(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input GetJobStatusInput,
+) (*mcp.CallToolResult, GetJobStatusOutput, error) {
+	if (input.JobID == 0) == (input.MemoryID == 0) {
+		return nil, GetJobStatusOutput{}, fmt.Errorf("exactly one of job_id or memory_id is required")
+	}
+
+	// Set defaults
+	if input.Limit == 0 {
+		input.Limit = 5
+	}
+	if input.Limit < 1 || input.Limit > 50 {
+		return nil, GetJobStatusOutput{}, fmt.Errorf("limit must be between 1 and 50, got %d", input.Limit)
+	}
+
+	var found []storage.Job
+	if input.JobID != 0 {
+		job, err := h.store.GetJob(input.JobID)
+		if err != nil {
+			return nil, GetJobStatusOutput{}, err
+		}
+		found = []storage.Job{*job}
+	} else {
+		var err error
+		if found, err = h.store.JobsForMemory(input.MemoryID, input.Limit); err != nil {
+			return nil, GetJobStatusOutput{}, err
+		}
+	}
+
+	output := GetJobStatusOutput{Jobs: make([]JobOutput, len(found)), Count: len(found)}
+	for i, job := range found {
+		output.Jobs[i] = toJobOutput(job)
+	}
+	return nil, output, nil
+}
+
+// BackfillRelationshipsInput defines input for backfill_relationships tool
+type BackfillRelationshipsInput struct {
+	GroupID       string  `json:"group_id,omitempty" jsonschema:"Only backfill memories in this group (default: all groups)"`
+	IncludeLinked bool    `json:"include_linked,omitempty" jsonschema:"Also analyze memories that already have auto-detected edges"`
+	MinSimilarity float64 `json:"min_similarity,omitempty" jsonschema:"Minimum similarity for candidates (default: 0.5)"`
+	MaxCandidates int     `json:"max_candidates,omitempty" jsonschema:"Maximum candidates per memory (default: 10)"`
+	MinConfidence float64 `json:"min_confidence,omitempty" jsonschema:"Minimum LLM confidence to create relationship (default: 0.7)"`
+	DryRun        bool    `json:"dry_run,omitempty" jsonschema:"If true, count the relationships that would be created without creating them"`
+	Concurrency   int     `json:"concurrency,omitempty" jsonschema:"LLM calls in flight, 1-16 (default: 2)"`
+	RateLimit     float64 `json:"rate_limit,omitempty" jsonschema:"LLM calls started per second, up to 50 (default: 1)"`
+	BatchSize     int     `json:"batch_size,omitempty" jsonschema:"Memories per candidate query and checkpoint, 1-500 (default: 50)"`
+	ResumeJobID   int64   `json:"resume_job_id,omitempty" jsonschema:"Requeue a failed backfill job; it continues from its last checkpoint with its original options"`
+}
+
+// BackfillRelationshipsOutput defines output for backfill_relationships tool
+type BackfillRelationshipsOutput struct {
+	JobID   int64  `json:"job_id"`
+	Message string `json:"message"`
+}
+
+func (h *memoryHandler) handleBackfillRelationships(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input BackfillRelationshipsInput,
+) (*mcp.CallToolResult, BackfillRelationshipsOutput, error) {
+	if input.ResumeJobID != 0 {
+		job, err := h.store.GetJob(input.ResumeJobID)
+		if err != nil {
+			return nil, BackfillRelationshipsOutput{}, err
+		}
+		if job.Kind != detect.BackfillJobKind {
+			return nil, BackfillRelationshipsOutput{}, fmt.Errorf("job %d is a %s job, not a backfill", job.ID, job.Kind)
+		}
+		if job, err = h.store.RetryJob(job.ID); err != nil {
+			return nil, BackfillRelationshipsOutput{}, err
+		}
+		return nil, BackfillRelationshipsOutput{
+			JobID:   job.ID,
+			Message: fmt.Sprintf("Backfill job %d requeued; it resumes from its last checkpoint", job.ID),
+		}, nil
+	}
+
+	if input.Concurrency < 0 || input.Concurrency > 16 {
+		return nil, BackfillRelationshipsOutput{}, fmt.Errorf("concurrency must be between 1 and 16, got %d", input.Concurrency)
+	}
+	if input.RateLimit < 0 || input.RateLimit > detect.MaxBackfillRateLimit {
+		return nil, BackfillRelationshipsOutput{}, fmt.Errorf("rate_limit must be greater than 0 and at most %v, got %v", detect.MaxBackfillRateLimit, input.RateLimit)
+	}
+	if input.BatchSize < 0 || input.BatchSize > 500 {
+		return nil, BackfillRelationshipsOutput{}, fmt.Errorf("batch_size must be between 1 and 500, got %d", input.BatchSize)
+	}
+
+	jobID, err := h.store.EnqueueJob(detect.BackfillJobKind, 0, detect.BackfillOptions{
+		Options: detect.Options{
+			MinSimilarity: input.MinSimilarity,
+			MaxCandidates: input.MaxCandidates,
+			MinConfidence: input.MinConfidence,
+			DryRun:        input.DryRun,
+		},
+		GroupID:       input.GroupID,
+		IncludeLinked: input.IncludeLinked,
+		Concurrency:   input.Concurrency,
+		RateLimit:     input.RateLimit,
+		BatchSize:     input.BatchSize,
+	}, jobs.DefaultMaxAttempts)
+	if err != nil {
+		return nil, BackfillRelationshipsOutput{}, err
+	}
+
+	return nil, BackfillRelationshipsOutput{
+		JobID:   jobID,
+		Message: fmt.Sprintf("Backfill queued as job %d; follow it with get_job_status", jobID),
+	}, nil
+}
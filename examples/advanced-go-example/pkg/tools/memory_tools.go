@@ -2,49 +2,143 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"advanced-go-example/pkg/config"
+	"advanced-go-example/pkg/detect"
 	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/filter"
+	"advanced-go-example/pkg/jobs"
 	"advanced-go-example/pkg/llm"
+	"advanced-go-example/pkg/ontology"
 	"advanced-go-example/pkg/storage"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// RegisterMemoryTools registers all memory-related MCP tools
-func RegisterMemoryTools(server *mcp.Server, store *storage.PostgresStore, embClient *embeddings.Client, llmClient *llm.Client) {
+// RegisterMemoryTools registers all memory-related MCP tools. Search and
+// detection defaults come from settings and follow its reloads.
+func RegisterMemoryTools(server *mcp.Server, store *storage.PostgresStore, embClient *embeddings.Client, llmClient *llm.Client, types *ontology.Ontology, settings *config.Live) {
 	// Wrap dependencies in a handler struct
 	h := &memoryHandler{
 		store:      store,
 		embeddings: embClient,
 		llm:        llmClient,
+		ontology:   types,
+		detector:   detect.NewDetector(store, llmClient, types),
+		settings:   settings,
 	}
+	settings.OnChange(func(s config.Settings) {
+		h.detector.SetDefaults(s.Detection)
+	})
 
 	// Register tools
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "store_memory",
 		Description: "Store a memory with vector embedding and optional metadata",
-	}, h.handleStoreMemory)
+	}, scoped(h, (*memoryHandler).handleStoreMemory))
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "search_memories",
 		Description: "Search for relevant memories using semantic similarity (pgvector)",
-	}, h.handleSearchMemories)
+	}, scoped(h, (*memoryHandler).handleSearchMemories))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_memories",
+		Description: "List stored memories in a stable order, optionally by group, paging with an opaque cursor",
+	}, scoped(h, (*memoryHandler).handleListMemories))
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "add_relationship",
-		Description: "Create a graph relationship between two memories (Apache AGE)",
-	}, h.handleAddRelationship)
+		Description: "Create a graph relationship between two memories (Apache AGE); re-adding the same type updates its properties",
+	}, scoped(h, (*memoryHandler).handleAddRelationship))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_relationship_types",
+		Description: "List the relationship types the graph uses, with their meaning, direction, inverse names and allowed properties",
+	}, scoped(h, (*memoryHandler).handleListRelationshipTypes))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_relationships",
+		Description: "List graph edges with their IDs, by memory, type or whether they were auto-detected",
+	}, scoped(h, (*memoryHandler).handleListRelationships))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "update_relationship",
+		Description: "Change an edge's properties or type by edge ID, e.g. to correct an auto-detected link",
+	}, scoped(h, (*memoryHandler).handleUpdateRelationship))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "delete_relationship",
+		Description: "Delete a graph edge by edge ID",
+	}, scoped(h, (*memoryHandler).handleDeleteRelationship))
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "explore_connections",
 		Description: "Find memories connected through graph relationships (Apache AGE)",
-	}, h.handleExploreConnections)
+	}, scoped(h, (*memoryHandler).handleExploreConnections))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "find_path",
+		Description: "Find the shortest path(s) between two memories in the graph, with each edge's type, reason and confidence",
+	}, scoped(h, (*memoryHandler).handleFindPath))
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "auto_detect_relationships",
 		Description: "Automatically detect and create relationships using LLM analysis of semantic similarity",
-	}, h.handleAutoDetectRelationships)
+	}, scoped(h, (*memoryHandler).handleAutoDetectRelationships))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "memory_access_report",
+		Description: "List the most frequently accessed (hot) and least recently accessed (cold) memories",
+	}, scoped(h, (*memoryHandler).handleMemoryAccessReport))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "set_retention_policy",
+		Description: "Set or delete a group's retention rules (max age, max count, minimum importance)",
+	}, scoped(h, (*memoryHandler).handleSetRetentionPolicy))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "retention_report",
+		Description: "Dry run of the retention sweeper: show policies and which memories would be purged",
+	}, scoped(h, (*memoryHandler).handleRetentionReport))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "export_memories",
+		Description: "Export memories, metadata, embeddings and graph edges to a portable JSONL file",
+	}, scoped(h, (*memoryHandler).handleExportMemories))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "import_memories",
+		Description: "Import a JSONL export; memories are matched by external ID, so re-importing is safe",
+	}, scoped(h, (*memoryHandler).handleImportMemories))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "export_graph",
+		Description: "Write the memory graph, or the part around a memory or in a group, to a file as Graphviz DOT, GraphML or Cytoscape.js JSON for graph viewers",
+	}, scoped(h, (*memoryHandler).handleExportGraph))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "ingest_document",
+		Description: "Split a Markdown/text file or directory into overlapping chunks, embed them in batches and link them with NEXT edges",
+	}, scoped(h, (*memoryHandler).handleIngestDocument))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_job_status",
+		Description: "Report the status, attempts, errors and result of background jobs such as relationship detection queued by store_memory",
+	}, scoped(h, (*memoryHandler).handleGetJobStatus))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "backfill_relationships",
+		Description: "Queue a background job that runs relationship detection for every memory (or one group) without auto-detected edges; it is checkpointed and resumable",
+	}, scoped(h, (*memoryHandler).handleBackfillRelationships))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "graph_stats",
+		Description: "Analyze the memory graph: node and edge counts by type, degree distribution, orphan memories, most central memories by PageRank and degree, and communities found by label propagation (optionally saved as cluster_id)",
+	}, scoped(h, (*memoryHandler).handleGraphStats))
 }
 
 // memoryHandler holds dependencies for tool handlers
@@ -52,21 +146,52 @@ type memoryHandler struct {
 	store      *storage.PostgresStore
 	embeddings *embeddings.Client
 	llm        *llm.Client
+	ontology   *ontology.Ontology
+	detector   *detect.Detector
+	settings   *config.Live
+}
+
+// withContext returns a copy of h whose dependencies trace as children of the span in ctx
+func (h *memoryHandler) withContext(ctx context.Context) *memoryHandler {
+	return &memoryHandler{
+		store:      h.store.WithContext(ctx),
+		embeddings: h.embeddings.WithContext(ctx),
+		llm:        h.llm.WithContext(ctx),
+		ontology:   h.ontology,
+		detector:   h.detector.WithContext(ctx),
+		settings:   h.settings,
+	}
+}
+
+// scoped adapts a handler method to mcp.AddTool, running each call on a copy
+// of h bound to the call's context, which carries the tool call's span
+func scoped[In, Out any](
+	h *memoryHandler,
+	handle func(*memoryHandler, context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, Out, error),
+) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, Out, error) {
+		return handle(h.withContext(ctx), ctx, req, input)
+	}
 }
 
 // StoreMemoryInput defines input for store_memory tool
 type StoreMemoryInput struct {
-	Text                   string `json:"text" jsonschema:"The text to remember"`
-	GroupID                string `json:"group_id,omitempty" jsonschema:"Optional group identifier"`
-	AutoDetectRelationships *bool  `json:"auto_detect_relationships,omitempty" jsonschema:"Automatically detect relationships using LLM (default: true)"`
+	Text                    string                 `json:"text" jsonschema:"The text to remember"`
+	GroupID                 string                 `json:"group_id,omitempty" jsonschema:"Optional group identifier"`
+	Tags                    []string               `json:"tags,omitempty" jsonschema:"Optional tags for categorizing and filtering"`
+	Source                  string                 `json:"source,omitempty" jsonschema:"Optional source of the memory (URI, file path, conversation)"`
+	Importance              *float64               `json:"importance,omitempty" jsonschema:"Importance from 0 (trivial) to 1 (critical) (default: 0.5)"`
+	Attributes              map[string]interface{} `json:"attributes,omitempty" jsonschema:"Optional free-form JSON attributes"`
+	ExpiresAt               string                 `json:"expires_at,omitempty" jsonschema:"Optional RFC 3339 time after which the memory is purged"`
+	AutoDetectRelationships *bool                  `json:"auto_detect_relationships,omitempty" jsonschema:"Queue a background job that detects relationships using the LLM (default: true)"`
 }
 
 // StoreMemoryOutput defines output for store_memory tool
 type StoreMemoryOutput struct {
-	Success              bool   `json:"success"`
-	Message              string `json:"message,omitzero"`
-	ID                   int64  `json:"id,omitzero"`
-	RelationshipsCreated int    `json:"relationships_created,omitzero"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitzero"`
+	ID      int64  `json:"id,omitzero"`
+	JobID   int64  `json:"job_id,omitzero"` // Relationship detection job; poll it with get_job_status
 }
 
 func (h *memoryHandler) handleStoreMemory(
@@ -78,6 +203,26 @@ func (h *memoryHandler) handleStoreMemory(
 		return nil, StoreMemoryOutput{}, fmt.Errorf("text cannot be empty")
 	}
 
+	// Default importance if not specified
+	importance := storage.DefaultImportance
+	if input.Importance != nil {
+		importance = *input.Importance
+	}
+	if importance < 0 || importance > 1 {
+		return nil, StoreMemoryOutput{}, fmt.Errorf("importance must be between 0 and 1, got %v", importance)
+	}
+
+	var expiresAt time.Time
+	if input.ExpiresAt != "" {
+		var err error
+		if expiresAt, err = time.Parse(time.RFC3339, input.ExpiresAt); err != nil {
+			return nil, StoreMemoryOutput{}, fmt.Errorf("expires_at must be an RFC 3339 timestamp: %w", err)
+		}
+		if !expiresAt.After(time.Now()) {
+			return nil, StoreMemoryOutput{}, fmt.Errorf("expires_at must be in the future")
+		}
+	}
+
 	// Default auto_detect_relationships to true if not specified
 	autoDetect := true
 	if input.AutoDetectRelationships != nil {
@@ -91,83 +236,118 @@ func (h *memoryHandler) handleStoreMemory(
 	}
 
 	// Store in database
-	id, err := h.store.StoreMemory(input.Text, embedding, input.GroupID)
+	id, err := h.store.StoreMemory(storage.Memory{
+		Text:       input.Text,
+		Embedding:  embedding,
+		GroupID:    input.GroupID,
+		Tags:       input.Tags,
+		Source:     input.Source,
+		Importance: importance,
+		Attributes: input.Attributes,
+		ExpiresAt:  expiresAt,
+	})
 	if err != nil {
 		return nil, StoreMemoryOutput{}, fmt.Errorf("failed to store memory: %w", err)
 	}
 
-	relationshipsCreated := 0
-
-	// Auto-detect relationships if enabled
-	if autoDetect {
-		// Find similar memories as candidates
-		searchResults, err := h.store.SearchMemories(embedding, 10, 0.5, "")
-		if err == nil {
-			// Filter out the newly stored memory itself and convert to candidates
-			var candidates []llm.CandidateMemory
-			for _, result := range searchResults {
-				if result.Memory.ID != id {
-					candidates = append(candidates, llm.CandidateMemory{
-						ID:         result.Memory.ID,
-						Text:       result.Memory.Text,
-						Similarity: result.Similarity,
-					})
-				}
-			}
-
-			// Use LLM to analyze relationships if we have candidates
-			if len(candidates) > 0 {
-				llmSuggestions, err := h.llm.AnalyzeRelationships(input.Text, id, candidates)
-				if err != nil {
-					// Don't fail the store operation if LLM analysis fails
-					// The memory is already stored successfully
-					return nil, StoreMemoryOutput{
-						Success: true,
-						Message: fmt.Sprintf("Memory stored with ID %d (relationship auto-detection failed: %v)", id, err),
-						ID:      id,
-					}, nil
-				}
-
-				// Create high-confidence relationships
-				for _, suggestion := range llmSuggestions {
-					if suggestion.Confidence >= 0.7 {
-						props := map[string]interface{}{
-							"reason":        suggestion.Reason,
-							"confidence":    suggestion.Confidence,
-							"auto_detected": true,
-						}
-
-						err := h.store.AddRelationship(id, suggestion.TargetID, suggestion.Type, props)
-						if err != nil {
-							// Return error if relationship creation fails
-							return nil, StoreMemoryOutput{}, fmt.Errorf("failed to create auto-detected relationship %s from %d to %d: %w", suggestion.Type, id, suggestion.TargetID, err)
-						}
-						relationshipsCreated++
-					}
-				}
-			}
-		}
+	if !autoDetect {
+		return nil, StoreMemoryOutput{
+			Success: true,
+			Message: fmt.Sprintf("Memory stored successfully with ID %d", id),
+			ID:      id,
+		}, nil
 	}
 
-	message := fmt.Sprintf("Memory stored successfully with ID %d", id)
-	if relationshipsCreated > 0 {
-		message = fmt.Sprintf("Memory stored with ID %d and %d relationships auto-created", id, relationshipsCreated)
+	// Relationship detection waits on the LLM, so it runs in the background
+	jobID, err := h.store.EnqueueJob(detect.JobKind, id, detect.Options{}, jobs.DefaultMaxAttempts)
+	if err != nil {
+		// Don't fail the store operation; the memory is already stored
+		return nil, StoreMemoryOutput{
+			Success: true,
+			Message: fmt.Sprintf("Memory stored with ID %d (queueing relationship detection failed: %v)", id, err),
+			ID:      id,
+		}, nil
 	}
 
 	return nil, StoreMemoryOutput{
-		Success:              true,
-		Message:              message,
-		ID:                   id,
-		RelationshipsCreated: relationshipsCreated,
+		Success: true,
+		Message: fmt.Sprintf("Memory stored with ID %d; relationship detection queued as job %d", id, jobID),
+		ID:      id,
+		JobID:   jobID,
 	}, nil
 }
 
 // SearchMemoriesInput defines input for search_memories tool
 type SearchMemoriesInput struct {
-	Query         string  `json:"query" jsonschema:"The search query"`
-	Limit         int     `json:"limit,omitempty" jsonschema:"Maximum results (default: 5)"`
-	MinSimilarity float64 `json:"min_similarity,omitempty" jsonschema:"Minimum similarity 0-1 (default: 0.0)"`
-	GroupID       string  `json:"group_id,omitempty" jsonschema:"Optional group filter"`
+	Query         string                 `json:"query" jsonschema:"The search query"`
+	Limit         int                    `json:"limit,omitempty" jsonschema:"Maximum results (default: search.limit in the server config, normally 5)"`
+	MinSimilarity float64                `json:"min_similarity,omitempty" jsonschema:"Minimum similarity 0-1 (default: search.min_similarity in the server config, normally 0.0)"`
+	GroupID       string                 `json:"group_id,omitempty" jsonschema:"Optional group filter"`
+	Tags          []string               `json:"tags,omitempty" jsonschema:"Optional tag filter"`
+	TagMode       string                 `json:"tag_mode,omitempty" jsonschema:"How tags are matched: any or all (default: any)"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty" jsonschema:"Optional attribute equality filter, e.g. {\"env\": \"prod\"}"`
+	Ranking       *RankingInput          `json:"ranking,omitempty" jsonschema:"Optional ranking profile mixing similarity with recency and importance"`
+	Filter        map[string]interface{} `json:"filter,omitempty" jsonschema:"Optional structured filter: {\"and\"|\"or\": [...]}, {\"not\": {...}} or {\"field\", \"op\", \"value\"} over group_id, source, importance, created_at, updated_at, tags, attributes.<key> and relationship (see README)"`
+	MaxNeighbors  *int                   `json:"max_neighbors,omitempty" jsonschema:"Maximum memories added through graph relationships of the results, 0-100; 0 disables graph expansion (default: search.max_neighbors in the server config, normally 20)"`
+	GraphRanking  *GraphRankingInput     `json:"graph_ranking,omitempty" jsonschema:"Optional; rank graph-discovered memories together with the vector hits by a score propagated from their seed, and truncate to limit"`
+}
+
+// RankingInput selects a ranking profile and optionally overrides its parameters
+type RankingInput struct {
+	Profile          string   `json:"profile,omitempty" jsonschema:"Built-in profile: similarity, balanced, recent, important or popular (default: similarity)"`
+	SimilarityWeight *float64 `json:"similarity_weight,omitempty" jsonschema:"Override the weight of vector similarity"`
+	RecencyWeight    *float64 `json:"recency_weight,omitempty" jsonschema:"Override the weight of recency (exponential decay)"`
+	ImportanceWeight *float64 `json:"importance_weight,omitempty" jsonschema:"Override the weight of importance"`
+	AccessWeight     *float64 `json:"access_weight,omitempty" jsonschema:"Override the weight of access frequency"`
+	HalfLifeDays     float64  `json:"half_life_days,omitempty" jsonschema:"Override the recency half-life in days"`
+	DecayField       string   `json:"decay_field,omitempty" jsonschema:"Timestamp used for recency: created_at, updated_at or last_accessed_at"`
+}
+
+// GraphRankingInput configures propagated scores for graph-discovered results
+type GraphRankingInput struct {
+	HopDecay    float64            `json:"hop_decay,omitempty" jsonschema:"Score multiplier per hop, 0-1 (default: 0.5)"`
+	TypeWeights map[string]float64 `json:"type_weights,omitempty" jsonschema:"Score multiplier per relationship type, 0-1, e.g. {\"CONTRADICTS\": 0.2}; unlisted types weigh 1"`
+	MaxHops     int                `json:"max_hops,omitempty" jsonschema:"How far to expand from the vector hits, 1-3 (default: 1)"`
+}
+
+// profile resolves the named profile and applies the overrides
+func (r *RankingInput) profile() (*storage.RankingProfile, error) {
+	name := r.Profile
+	if name == "" {
+		name = "similarity"
+	}
+	profile, ok := storage.RankingProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ranking profile %q", name)
+	}
+
+	if r.SimilarityWeight != nil {
+		profile.SimilarityWeight = *r.SimilarityWeight
+	}
+	if r.RecencyWeight != nil {
+		profile.RecencyWeight = *r.RecencyWeight
+	}
+	if r.ImportanceWeight != nil {
+		profile.ImportanceWeight = *r.ImportanceWeight
+	}
+	if r.AccessWeight != nil {
+		profile.AccessWeight = *r.AccessWeight
+	}
+	if r.HalfLifeDays > 0 {
+		profile.HalfLife = time.Duration(r.HalfLifeDays * float64(24*time.Hour))
+	}
+	if r.DecayField != "" {
+		profile.DecayField = r.DecayField
+	}
+	// Recency weighting without a half-life of its own falls back to 30 days
+	if profile.RecencyWeight > 0 && profile.HalfLife == 0 {
+		profile.HalfLife = 30 * 24 * time.Hour
+	}
+
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ranking: %w", err)
+	}
+	return &profile, nil
 }
 
 // SearchMemoriesOutput defines output for search_memories tool
@@ -186,8 +366,65 @@ func (h *memoryHandler) handleSearchMemories(
 	}
 
 	// Set defaults
+	defaults := h.settings.Get().Search
 	if input.Limit == 0 {
-		input.Limit = 5
+		input.Limit = defaults.Limit
+	}
+	if input.MinSimilarity == 0 {
+		input.MinSimilarity = defaults.MinSimilarity
+	}
+
+	var matchAllTags bool
+	switch input.TagMode {
+	case "", "any":
+	case "all":
+		matchAllTags = true
+	default:
+		return nil, SearchMemoriesOutput{}, fmt.Errorf("tag_mode must be \"any\" or \"all\", got %q", input.TagMode)
+	}
+
+	// Parse the structured filter before spending time on the embedding
+	var expr *filter.Expr
+	if len(input.Filter) > 0 {
+		data, err := json.Marshal(input.Filter)
+		if err != nil {
+			return nil, SearchMemoriesOutput{}, fmt.Errorf("failed to encode filter: %w", err)
+		}
+		if expr, err = filter.Parse(data); err != nil {
+			return nil, SearchMemoriesOutput{}, err
+		}
+	}
+
+	// Graph expansion follows the configured default; an explicit 0 turns it off
+	maxNeighbors := defaults.MaxNeighbors
+	if input.MaxNeighbors != nil {
+		if *input.MaxNeighbors < 0 || *input.MaxNeighbors > 100 {
+			return nil, SearchMemoriesOutput{}, fmt.Errorf("max_neighbors must be between 0 and 100, got %d", *input.MaxNeighbors)
+		}
+		maxNeighbors = *input.MaxNeighbors
+	}
+	if maxNeighbors == 0 {
+		maxNeighbors = -1
+	}
+
+	var graphRanking *storage.GraphRanking
+	if input.GraphRanking != nil {
+		graphRanking = &storage.GraphRanking{
+			HopDecay:    input.GraphRanking.HopDecay,
+			TypeWeights: input.GraphRanking.TypeWeights,
+			MaxHops:     input.GraphRanking.MaxHops,
+		}
+		if err := graphRanking.Validate(); err != nil {
+			return nil, SearchMemoriesOutput{}, fmt.Errorf("invalid graph_ranking: %w", err)
+		}
+	}
+
+	var ranking *storage.RankingProfile
+	if input.Ranking != nil {
+		var err error
+		if ranking, err = input.Ranking.profile(); err != nil {
+			return nil, SearchMemoriesOutput{}, err
+		}
 	}
 
 	// Generate query embedding
@@ -197,7 +434,18 @@ func (h *memoryHandler) handleSearchMemories(
 	}
 
 	// Search using pgvector
-	results, err := h.store.SearchMemories(queryEmbedding, input.Limit, input.MinSimilarity, input.GroupID)
+	results, err := h.store.SearchMemories(queryEmbedding, storage.SearchOptions{
+		Limit:         input.Limit,
+		MinSimilarity: input.MinSimilarity,
+		GroupID:       input.GroupID,
+		Tags:          input.Tags,
+		MatchAllTags:  matchAllTags,
+		Attributes:    input.Attributes,
+		Filter:        expr,
+		Ranking:       ranking,
+		MaxNeighbors:  maxNeighbors,
+		GraphRanking:  graphRanking,
+	})
 	if err != nil {
 		return nil, SearchMemoriesOutput{}, fmt.Errorf("failed to search memories: %w", err)
 	}
@@ -213,17 +461,81 @@ func (h *memoryHandler) handleSearchMemories(
 	}, nil
 }
 
+// ListMemoriesInput defines input for list_memories tool
+type ListMemoriesInput struct {
+	GroupID   string `json:"group_id,omitempty" jsonschema:"Optional group filter"`
+	ClusterID int64  `json:"cluster_id,omitempty" jsonschema:"Only memories in this graph community, as saved by graph_stats"`
+	SortBy    string `json:"sort_by,omitempty" jsonschema:"Sort field: created_at, updated_at, importance or id (default: created_at)"`
+	Order     string `json:"order,omitempty" jsonschema:"Sort direction: desc or asc (default: desc)"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Memories per page, 1-100 (default: 20)"`
+	Cursor    string `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page; keep sort_by and order unchanged"`
+}
+
+// ListMemoriesOutput defines output for list_memories tool
+type ListMemoriesOutput struct {
+	Memories   []storage.Memory `json:"memories"`
+	Count      int              `json:"count"`
+	NextCursor string           `json:"next_cursor,omitzero"` // Empty on the last page
+}
+
+func (h *memoryHandler) handleListMemories(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ListMemoriesInput,
+) (*mcp.CallToolResult, ListMemoriesOutput, error) {
+	// Set defaults
+	if input.Limit == 0 {
+		input.Limit = 20
+	}
+	if input.Limit < 1 || input.Limit > 100 {
+		return nil, ListMemoriesOutput{}, fmt.Errorf("limit must be between 1 and 100, got %d", input.Limit)
+	}
+
+	var ascending bool
+	switch input.Order {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		return nil, ListMemoriesOutput{}, fmt.Errorf("order must be \"asc\" or \"desc\", got %q", input.Order)
+	}
+
+	memories, next, err := h.store.ListMemories(storage.ListOptions{
+		GroupID:   input.GroupID,
+		ClusterID: input.ClusterID,
+		SortBy:    input.SortBy,
+		Ascending: ascending,
+		Limit:     input.Limit,
+		Cursor:    input.Cursor,
+	})
+	if err != nil {
+		return nil, ListMemoriesOutput{}, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	// Ensure memories is never nil (empty array instead)
+	if memories == nil {
+		memories = []storage.Memory{}
+	}
+
+	return nil, ListMemoriesOutput{
+		Memories:   memories,
+		Count:      len(memories),
+		NextCursor: next,
+	}, nil
+}
+
 // AddRelationshipInput defines input for add_relationship tool
 type AddRelationshipInput struct {
 	FromID     int64                  `json:"from_id" jsonschema:"Source memory ID"`
 	ToID       int64                  `json:"to_id" jsonschema:"Target memory ID"`
-	Type       string                 `json:"type" jsonschema:"Relationship type (e.g., RELATES_TO, SIMILAR_TO)"`
+	Type       string                 `json:"type" jsonschema:"Relationship type or inverse name from list_relationship_types (e.g., RELATES_TO, DEPENDS_ON)"`
 	Properties map[string]interface{} `json:"properties,omitempty" jsonschema:"Optional relationship properties"`
 }
 
 // AddRelationshipOutput defines output for add_relationship tool
 type AddRelationshipOutput struct {
 	Success bool   `json:"success"`
+	ID      int64  `json:"id,omitzero"` // Edge ID for update_relationship and delete_relationship
 	Message string `json:"message,omitzero"`
 }
 
@@ -240,27 +552,34 @@ func (h *memoryHandler) handleAddRelationship(
 	}
 
 	// Create relationship using Apache AGE
-	err := h.store.AddRelationship(input.FromID, input.ToID, input.Type, input.Properties)
+	rel, err := h.addRelationship(input.FromID, input.ToID, input.Type, input.Properties)
 	if err != nil {
 		return nil, AddRelationshipOutput{}, fmt.Errorf("failed to create relationship: %w", err)
 	}
 
+	// Inverse names and symmetric types may be stored the other way round
 	return nil, AddRelationshipOutput{
 		Success: true,
-		Message: fmt.Sprintf("Relationship %s created between memories %d and %d", input.Type, input.FromID, input.ToID),
+		ID:      rel.ID,
+		Message: fmt.Sprintf("Relationship saved as %d -%s-> %d (edge %d)", rel.FromID, rel.Type, rel.ToID, rel.ID),
 	}, nil
 }
 
 // ExploreConnectionsInput defines input for explore_connections tool
 type ExploreConnectionsInput struct {
-	MemoryID int64 `json:"memory_id" jsonschema:"Starting memory ID"`
-	MaxDepth int   `json:"max_depth,omitempty" jsonschema:"Maximum traversal depth (default: 2)"`
+	MemoryID          int64    `json:"memory_id" jsonschema:"Starting memory ID"`
+	MaxDepth          int      `json:"max_depth,omitempty" jsonschema:"Maximum traversal depth, 1-6 (default: 2)"`
+	Direction         string   `json:"direction,omitempty" jsonschema:"Follow edges out of the memory (what it depends on), in (what builds on it) or both (default: both)"`
+	RelationshipTypes []string `json:"relationship_types,omitempty" jsonschema:"Only follow these relationship types (default: all)"`
+	ExcludeTypes      []string `json:"exclude_types,omitempty" jsonschema:"Never follow these relationship types"`
+	MinConfidence     float64  `json:"min_confidence,omitempty" jsonschema:"Skip edges whose confidence is below this (0-1); edges without a confidence always pass"`
 }
 
 // ExploreConnectionsOutput defines output for explore_connections tool
 type ExploreConnectionsOutput struct {
-	Memories []storage.Memory `json:"memories,omitzero"`
-	Count    int              `json:"count,omitzero"`
+	Nodes []storage.SubgraphNode `json:"nodes"` // The start memory at depth 0, then everything reached
+	Edges []EdgeOutput           `json:"edges"`
+	Count int                    `json:"count"` // Connected memories, excluding the start
 }
 
 func (h *memoryHandler) handleExploreConnections(
@@ -272,37 +591,69 @@ func (h *memoryHandler) handleExploreConnections(
 		return nil, ExploreConnectionsOutput{}, fmt.Errorf("memory_id is required")
 	}
 
-	// Set default depth
+	// Set defaults
 	if input.MaxDepth == 0 {
 		input.MaxDepth = 2
 	}
+	if input.Direction == "" {
+		input.Direction = storage.DirectionBoth
+	}
+	if input.MaxDepth < 1 || input.MaxDepth > 6 {
+		return nil, ExploreConnectionsOutput{}, fmt.Errorf("max_depth must be between 1 and 6, got %d", input.MaxDepth)
+	}
+	if input.MinConfidence < 0 || input.MinConfidence > 1 {
+		return nil, ExploreConnectionsOutput{}, fmt.Errorf("min_confidence must be between 0 and 1, got %v", input.MinConfidence)
+	}
 
 	// Explore using Apache AGE graph traversal
-	memories, err := h.store.ExploreConnections(input.MemoryID, input.MaxDepth)
+	subgraph, err := h.store.Traverse(input.MemoryID, storage.TraversalOptions{
+		MaxDepth:      input.MaxDepth,
+		Direction:     input.Direction,
+		Types:         input.RelationshipTypes,
+		ExcludeTypes:  input.ExcludeTypes,
+		MinConfidence: input.MinConfidence,
+	})
 	if err != nil {
 		return nil, ExploreConnectionsOutput{}, fmt.Errorf("failed to explore connections: %w", err)
 	}
+	if len(subgraph.Nodes) == 0 || subgraph.Nodes[0].Depth != 0 {
+		return nil, ExploreConnectionsOutput{}, fmt.Errorf("%w: %d", storage.ErrMemoryNotFound, input.MemoryID)
+	}
 
-	return nil, ExploreConnectionsOutput{
-		Memories: memories,
-		Count:    len(memories),
-	}, nil
+	output := ExploreConnectionsOutput{
+		Nodes: subgraph.Nodes,
+		Edges: []EdgeOutput{},
+		Count: len(subgraph.Nodes) - 1,
+	}
+	for _, edge := range subgraph.Edges {
+		edgeOutput := toEdgeOutput(storage.Relationship{
+			ID:         edge.ID,
+			FromID:     edge.FromID,
+			ToID:       edge.ToID,
+			Type:       edge.Type,
+			Properties: edge.Properties,
+		})
+		edgeOutput.Depth = edge.Depth
+		output.Edges = append(output.Edges, edgeOutput)
+	}
+
+	return nil, output, nil
 }
 
 // AutoDetectRelationshipsInput defines input for auto_detect_relationships tool
 type AutoDetectRelationshipsInput struct {
-	MemoryID        int64   `json:"memory_id" jsonschema:"Memory ID to analyze for relationships"`
-	MinSimilarity   float64 `json:"min_similarity,omitempty" jsonschema:"Minimum similarity for candidates (default: 0.5)"`
-	MaxCandidates   int     `json:"max_candidates,omitempty" jsonschema:"Maximum candidates to analyze (default: 10)"`
-	MinConfidence   float64 `json:"min_confidence,omitempty" jsonschema:"Minimum LLM confidence to create relationship (default: 0.7)"`
-	DryRun          bool    `json:"dry_run,omitempty" jsonschema:"If true, return suggestions without creating relationships"`
+	MemoryID      int64   `json:"memory_id" jsonschema:"Memory ID to analyze for relationships"`
+	MinSimilarity float64 `json:"min_similarity,omitempty" jsonschema:"Minimum similarity for candidates (default: 0.5)"`
+	MaxCandidates int     `json:"max_candidates,omitempty" jsonschema:"Maximum candidates to analyze (default: 10)"`
+	MinConfidence float64 `json:"min_confidence,omitempty" jsonschema:"Minimum LLM confidence to create relationship (default: 0.7)"`
+	DryRun        bool    `json:"dry_run,omitempty" jsonschema:"If true, return suggestions without creating relationships"`
 }
 
 // AutoDetectRelationshipsOutput defines output for auto_detect_relationships tool
 type AutoDetectRelationshipsOutput struct {
-	Suggestions       []RelationshipSuggestion `json:"suggestions"`
-	RelationshipsCreated int                   `json:"relationships_created"`
-	Message           string                   `json:"message"`
+	Suggestions          []RelationshipSuggestion `json:"suggestions"`
+	RelationshipsCreated int                      `json:"relationships_created"`
+	Message              string                   `json:"message"`
 }
 
 // RelationshipSuggestion represents a detected relationship
@@ -322,62 +673,27 @@ func (h *memoryHandler) handleAutoDetectRelationships(
 		return nil, AutoDetectRelationshipsOutput{}, fmt.Errorf("memory_id is required")
 	}
 
-	// Set defaults
-	if input.MinSimilarity == 0 {
-		input.MinSimilarity = 0.5
-	}
-	if input.MaxCandidates == 0 {
-		input.MaxCandidates = 10
-	}
-	if input.MinConfidence == 0 {
-		input.MinConfidence = 0.7
-	}
-
-	// Get the source memory
-	sourceMemory, err := h.store.GetMemoryByID(input.MemoryID)
-	if err != nil {
-		return nil, AutoDetectRelationshipsOutput{}, fmt.Errorf("failed to get source memory: %w", err)
-	}
-
-	// Generate embedding for the source memory
-	sourceEmbedding, err := h.embeddings.Generate(sourceMemory.Text)
-	if err != nil {
-		return nil, AutoDetectRelationshipsOutput{}, fmt.Errorf("failed to generate embedding: %w", err)
+	result, err := h.detector.Detect(input.MemoryID, detect.Options{
+		MinSimilarity: input.MinSimilarity,
+		MaxCandidates: input.MaxCandidates,
+		MinConfidence: input.MinConfidence,
+		DryRun:        input.DryRun,
+	})
+	// Edges that failed to store still return a result; they are reported in the message
+	if result == nil {
+		return nil, AutoDetectRelationshipsOutput{}, err
 	}
 
-	// Find similar memories as candidates
-	searchResults, err := h.store.SearchMemories(sourceEmbedding, input.MaxCandidates+1, input.MinSimilarity, "")
-	if err != nil {
-		return nil, AutoDetectRelationshipsOutput{}, fmt.Errorf("failed to search for candidates: %w", err)
-	}
-
-	// Filter out the source memory itself and convert to candidates
-	var candidates []llm.CandidateMemory
-	for _, result := range searchResults {
-		if result.Memory.ID != input.MemoryID {
-			candidates = append(candidates, llm.CandidateMemory{
-				ID:         result.Memory.ID,
-				Text:       result.Memory.Text,
-				Similarity: result.Similarity,
-			})
-		}
-	}
-
-	if len(candidates) == 0 {
+	if result.Candidates == 0 {
 		return nil, AutoDetectRelationshipsOutput{
-			Message: "No similar memories found for relationship analysis",
+			Suggestions: []RelationshipSuggestion{},
+			Message:     "No similar memories found for relationship analysis",
 		}, nil
 	}
 
-	// Use LLM to analyze relationships
-	llmSuggestions, err := h.llm.AnalyzeRelationships(sourceMemory.Text, sourceMemory.ID, candidates)
-	if err != nil {
-		return nil, AutoDetectRelationshipsOutput{}, fmt.Errorf("LLM analysis failed: %w", err)
-	}
-
 	// Convert to output format - ensure we always have an array (not nil)
 	suggestions := make([]RelationshipSuggestion, 0)
-	for _, s := range llmSuggestions {
+	for _, s := range result.Suggestions {
 		suggestions = append(suggestions, RelationshipSuggestion{
 			TargetID:   s.TargetID,
 			Type:       s.Type,
@@ -386,33 +702,54 @@ func (h *memoryHandler) handleAutoDetectRelationships(
 		})
 	}
 
-	// Create relationships if not a dry run
-	created := 0
-	if !input.DryRun {
-		for _, suggestion := range llmSuggestions {
-			if suggestion.Confidence >= input.MinConfidence {
-				props := map[string]interface{}{
-					"reason":     suggestion.Reason,
-					"confidence": suggestion.Confidence,
-					"auto_detected": true,
-				}
-
-				err := h.store.AddRelationship(input.MemoryID, suggestion.TargetID, suggestion.Type, props)
-				if err == nil {
-					created++
-				}
-			}
-		}
-	}
-
 	message := fmt.Sprintf("Found %d relationship suggestions", len(suggestions))
 	if !input.DryRun {
-		message = fmt.Sprintf("Created %d relationships from %d suggestions", created, len(suggestions))
+		message = fmt.Sprintf("Created %d relationships from %d suggestions", len(result.Created), len(suggestions))
+	}
+	if err != nil {
+		message += fmt.Sprintf(" (some failed: %v)", err)
 	}
 
 	return nil, AutoDetectRelationshipsOutput{
 		Suggestions:          suggestions,
-		RelationshipsCreated: created,
+		RelationshipsCreated: len(result.Created),
 		Message:              message,
 	}, nil
 }
+
+// MemoryAccessReportInput defines input for memory_access_report tool
+type MemoryAccessReportInput struct {
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum memories per list (default: 10)"`
+	ColdAfterDays int    `json:"cold_after_days,omitempty" jsonschema:"Memories not accessed for this many days are cold (default: 30)"`
+	GroupID       string `json:"group_id,omitempty" jsonschema:"Optional group filter"`
+}
+
+// MemoryAccessReportOutput defines output for memory_access_report tool
+type MemoryAccessReportOutput struct {
+	Hot  []storage.Memory `json:"hot"`
+	Cold []storage.Memory `json:"cold"`
+}
+
+func (h *memoryHandler) handleMemoryAccessReport(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input MemoryAccessReportInput,
+) (*mcp.CallToolResult, MemoryAccessReportOutput, error) {
+	// Set defaults
+	if input.Limit == 0 {
+		input.Limit = 10
+	}
+	if input.ColdAfterDays == 0 {
+		input.ColdAfterDays = 30
+	}
+
+	report, err := h.store.GetAccessReport(input.Limit, time.Duration(input.ColdAfterDays)*24*time.Hour, input.GroupID)
+	if err != nil {
+		return nil, MemoryAccessReportOutput{}, fmt.Errorf("failed to build access report: %w", err)
+	}
+
+	return nil, MemoryAccessReportOutput{
+		Hot:  report.Hot,
+		Cold: report.Cold,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"advanced-go-example/pkg/storage"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RetentionPolicyOutput is a retention policy as reported by the tools
+type RetentionPolicyOutput struct {
+	GroupID       string    `json:"group_id"`
+	MaxAgeDays    float64   `json:"max_age_days,omitzero"`
+	MaxCount      int       `json:"max_count,omitzero"`
+	MinImportance float64   `json:"min_importance,omitzero"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+}
+
+func toRetentionPolicyOutput(policy storage.RetentionPolicy) RetentionPolicyOutput {
+	return RetentionPolicyOutput{
+		GroupID:       policy.GroupID,
+		MaxAgeDays:    policy.MaxAge.Hours() / 24,
+		MaxCount:      policy.MaxCount,
+		MinImportance: policy.MinImportance,
+		UpdatedAt:     policy.UpdatedAt,
+	}
+}
+
+// SetRetentionPolicyInput defines input for set_retention_policy tool
+type SetRetentionPolicyInput struct {
+	GroupID       string  `json:"group_id" jsonschema:"Group the policy applies to; \"*\" is the default for groups without a policy, \"\" means memories without a group"`
+	MaxAgeDays    float64 `json:"max_age_days,omitempty" jsonschema:"Purge memories older than this many days"`
+	MaxCount      int     `json:"max_count,omitempty" jsonschema:"Keep only the newest N memories in the group"`
+	MinImportance float64 `json:"min_importance,omitempty" jsonschema:"Purge memories with importance below this (0-1)"`
+	Delete        bool    `json:"delete,omitempty" jsonschema:"Remove the group's policy instead of setting it"`
+}
+
+// SetRetentionPolicyOutput defines output for set_retention_policy tool
+type SetRetentionPolicyOutput struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitzero"`
+}
+
+func (h *memoryHandler) handleSetRetentionPolicy(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input SetRetentionPolicyInput,
+) (*mcp.CallToolResult, SetRetentionPolicyOutput, error) {
+	if input.Delete {
+		deleted, err := h.store.DeleteRetentionPolicy(input.GroupID)
+		if err != nil {
+			return nil, SetRetentionPolicyOutput{}, err
+		}
+		if !deleted {
+			return nil, SetRetentionPolicyOutput{}, fmt.Errorf("no retention policy for group %q", input.GroupID)
+		}
+		return nil, SetRetentionPolicyOutput{
+			Success: true,
+			Message: fmt.Sprintf("Retention policy for group %q deleted", input.GroupID),
+		}, nil
+	}
+
+	if input.MaxAgeDays < 0 || input.MaxCount < 0 {
+		return nil, SetRetentionPolicyOutput{}, fmt.Errorf("max_age_days and max_count must not be negative")
+	}
+	if input.MinImportance < 0 || input.MinImportance > 1 {
+		return nil, SetRetentionPolicyOutput{}, fmt.Errorf("min_importance must be between 0 and 1, got %v", input.MinImportance)
+	}
+	if input.MaxAgeDays == 0 && input.MaxCount == 0 && input.MinImportance == 0 {
+		return nil, SetRetentionPolicyOutput{}, fmt.Errorf("at least one of max_age_days, max_count or min_importance is required")
+	}
+
+	err := h.store.SetRetentionPolicy(storage.RetentionPolicy{
+		GroupID:       input.GroupID,
+		MaxAge:        time.Duration(input.MaxAgeDays * float64(24*time.Hour)),
+		MaxCount:      input.MaxCount,
+		MinImportance: input.MinImportance,
+	})
+	if err != nil {
+		return nil, SetRetentionPolicyOutput{}, err
+	}
+
+	return nil, SetRetentionPolicyOutput{
+		Success: true,
+		Message: fmt.Sprintf("Retention policy for group %q saved; it is enforced by the next sweep", input.GroupID),
+	}, nil
+}
+
+// RetentionReportInput defines input for retention_report tool
+type RetentionReportInput struct {
+	MaxCandidates int `json:"max_candidates,omitempty" jsonschema:"Maximum purge candidates to list (default: 50)"`
+}
+
+// RetentionReportOutput defines output for retention_report tool
+type RetentionReportOutput struct {
+	Policies       []RetentionPolicyOutput  `json:"policies"`
+	Candidates     []storage.PurgeCandidate `json:"candidates"`
+	CandidateCount int                      `json:"candidate_count"`
+	Reasons        map[string]int           `json:"reasons"`
+	AuditID        int64                    `json:"audit_id"`
+}
+
+func (h *memoryHandler) handleRetentionReport(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input RetentionReportInput,
+) (*mcp.CallToolResult, RetentionReportOutput, error) {
+	// Set defaults
+	if input.MaxCandidates == 0 {
+		input.MaxCandidates = 50
+	}
+
+	policies, err := h.store.ListRetentionPolicies()
+	if err != nil {
+		return nil, RetentionReportOutput{}, err
+	}
+
+	// Dry run: nothing is deleted, but the run is still audited
+	run, err := h.store.RunRetention(true)
+	if err != nil {
+		return nil, RetentionReportOutput{}, fmt.Errorf("failed to evaluate retention: %w", err)
+	}
+
+	output := RetentionReportOutput{
+		Policies:       make([]RetentionPolicyOutput, len(policies)),
+		Candidates:     run.Candidates,
+		CandidateCount: len(run.Candidates),
+		Reasons:        run.Reasons,
+		AuditID:        run.AuditID,
+	}
+	for i, policy := range policies {
+		output.Policies[i] = toRetentionPolicyOutput(policy)
+	}
+	if len(output.Candidates) > input.MaxCandidates {
+		output.Candidates = output.Candidates[:input.MaxCandidates]
+	}
+
+	return nil, output, nil
+}
@@ -0,0 +1,170 @@
+// Package tracing sets up OpenTelemetry tracing and starts the server's
+// spans. Until Setup installs an exporter, spans go to OTel's no-op
+// provider and cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer that creates the server's spans
+const instrumentationName = "advanced-go-example"
+
+// Exporters Config.Exporter can name
+const (
+	ExporterNone = ""     // Tracing disabled
+	ExporterOTLP = "otlp" // OTLP over HTTP; endpoint and headers from the standard OTEL_EXPORTER_OTLP_* variables
+	ExporterFile = "file" // One JSON span per line in Config.File, for offline use
+)
+
+// shutdownTimeout bounds flushing buffered spans on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Config selects where spans are exported
+type Config struct {
+	Exporter    string
+	File        string  // Output path for ExporterFile
+	SampleRatio float64 // Fraction of traces kept, from 0 to 1
+	ServiceName string  // service.name, unless OTEL_SERVICE_NAME overrides it
+}
+
+// Setup installs the global tracer provider for cfg. The returned function
+// flushes buffered spans and must be called before exit; it is a no-op when
+// tracing is disabled.
+func Setup(ctx context.Context, cfg Config) (func(), error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", cfg.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func() {}, nil
+	case ExporterOTLP:
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlp
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("the file exporter needs an output path")
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", cfg.File, err)
+		}
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		exporter = fileExporter{SpanExporter: stdout, file: file}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (expected %s or %s)", cfg.Exporter, ExporterOTLP, ExporterFile)
+	}
+
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			otel.Handle(err)
+		}
+	}, nil
+}
+
+// fileExporter closes the output file when the exporter shuts down
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Start starts a span named name as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartChild starts a span like Start, but only inside an existing trace.
+// Without a span in ctx it returns a no-op span, so background work such as
+// job polling doesn't produce a stream of single-span traces.
+func StartChild(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return Start(ctx, name, attrs...)
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ToolMiddleware wraps every tools/call request in a span. Handlers receive
+// the span's context, so the spans they start become its children.
+func ToolMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+
+		ctx, span := Start(ctx, "tools/call "+call.Params.Name,
+			attribute.String("mcp.method.name", method),
+			attribute.String("mcp.tool.name", call.Params.Name),
+		)
+		result, err := next(ctx, method, req)
+		if toolResult, ok := result.(*mcp.CallToolResult); ok && toolResult.IsError {
+			span.SetStatus(codes.Error, "tool returned an error")
+		}
+		End(span, err)
+		return result, err
+	}
+}
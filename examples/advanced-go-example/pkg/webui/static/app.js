@@ -0,0 +1,373 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+const state = {
+  cursor: "",
+  selected: 0,
+};
+
+// api calls the JSON API and throws the server's error message on failure
+async function api(path, options = {}) {
+  const response = await fetch(path, options);
+  const body = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function el(tag, props = {}, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, props);
+  node.append(...children);
+  return node;
+}
+
+function badge(text, kind = "") {
+  return el("span", { className: "badge " + kind, textContent: text });
+}
+
+function describe(memory) {
+  const parts = [];
+  if (memory.group_id) parts.push("group " + memory.group_id);
+  if (memory.created_at) parts.push("created " + new Date(memory.created_at).toLocaleString());
+  return parts.join(" · ");
+}
+
+// Results
+
+async function loadGroups() {
+  const { groups } = await api("/api/groups");
+  // Memories without a group only show under "All groups"
+  for (const group of groups.filter((group) => group.group_id)) {
+    $("group").append(el("option", { value: group.group_id, textContent: `${group.group_id} (${group.count})` }));
+  }
+}
+
+function setStatus(text) {
+  $("results-status").textContent = text;
+}
+
+function resultItem(memory, badges) {
+  const item = el("li", {},
+    el("div", {}, badge("#" + memory.id), ...badges),
+    el("div", { className: "result-text", textContent: memory.text }),
+    el("div", { className: "muted", textContent: describe(memory) }),
+  );
+  item.dataset.id = memory.id;
+  item.classList.toggle("selected", memory.id === state.selected);
+  item.addEventListener("click", () => openMemory(memory.id));
+  return item;
+}
+
+async function browse(append = false) {
+  if (!append) {
+    state.cursor = "";
+    $("result-list").replaceChildren();
+  }
+  const params = new URLSearchParams({ group_id: $("group").value, cursor: state.cursor });
+  try {
+    const page = await api("/api/memories?" + params);
+    for (const memory of page.memories) {
+      const tags = (memory.tags || []).map((tag) => badge(tag));
+      $("result-list").append(resultItem(memory, tags));
+    }
+    state.cursor = page.next_cursor || "";
+    $("more").hidden = !state.cursor;
+    setStatus($("result-list").children.length ? "Newest first" : "No memories yet");
+  } catch (err) {
+    setStatus(err.message);
+  }
+}
+
+async function search(query) {
+  $("more").hidden = true;
+  setStatus("Searching…");
+  const params = new URLSearchParams({ q: query, group_id: $("group").value });
+  try {
+    const { results } = await api("/api/search?" + params);
+    $("result-list").replaceChildren(...results.map((result) => {
+      const badges = [];
+      if (result.via_relationship) {
+        badges.push(badge(`via ${result.relationship_type || "graph"} from #${result.seed_id}`, "graph"));
+      }
+      if (result.similarity) {
+        badges.push(badge("similarity " + result.similarity.toFixed(3), "score"));
+      }
+      return resultItem(result.memory, badges);
+    }));
+    setStatus(`${results.length} results`);
+  } catch (err) {
+    setStatus(err.message);
+  }
+}
+
+// Detail and editing
+
+async function openMemory(id) {
+  state.selected = id;
+  for (const item of $("result-list").children) {
+    item.classList.toggle("selected", Number(item.dataset.id) === id);
+  }
+
+  let detail;
+  try {
+    detail = await api("/api/memories/" + id);
+  } catch (err) {
+    setStatus(err.message);
+    return;
+  }
+  const memory = detail.memory;
+
+  $("detail").hidden = false;
+  $("detail-id").textContent = "#" + memory.id;
+  $("detail-meta").textContent = [
+    describe(memory),
+    memory.updated_at && "updated " + new Date(memory.updated_at).toLocaleString(),
+    memory.access_count && `read ${memory.access_count} times`,
+    memory.cluster_id && "cluster " + memory.cluster_id,
+  ].filter(Boolean).join(" · ");
+  $("edit-text").value = memory.text;
+  $("edit-group").value = memory.group_id || "";
+  $("edit-source").value = memory.source || "";
+  $("edit-importance").value = memory.importance ?? 0;
+  $("edit-tags").value = (memory.tags || []).join(",");
+  $("edit-attributes").value = memory.attributes ? JSON.stringify(memory.attributes, null, 2) : "";
+  $("edit-status").textContent = "";
+
+  $("edges").replaceChildren(...detail.edges.map((edge) => {
+    const outgoing = edge.from_id === memory.id;
+    const other = outgoing ? edge.to_id : edge.from_id;
+    const link = el("a", { textContent: "#" + other });
+    link.addEventListener("click", () => openMemory(other));
+    const confidence = edge.properties && edge.properties.confidence;
+    return el("li", {},
+      outgoing ? `${edge.type} → ` : `← ${edge.type} from `, link,
+      confidence !== undefined ? ` (confidence ${confidence})` : "",
+    );
+  }));
+  if (!detail.edges.length) {
+    $("edges").append(el("li", { className: "muted", textContent: "No relationships" }));
+  }
+
+  loadGraph(id);
+}
+
+async function saveMemory(event) {
+  event.preventDefault();
+  let attributes = {};
+  const rawAttributes = $("edit-attributes").value.trim();
+  try {
+    if (rawAttributes) attributes = JSON.parse(rawAttributes);
+  } catch (err) {
+    $("edit-status").textContent = "Attributes must be a JSON object";
+    return;
+  }
+
+  $("edit-status").textContent = "Saving…";
+  try {
+    const memory = await api("/api/memories/" + state.selected, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        text: $("edit-text").value,
+        group_id: $("edit-group").value.trim(),
+        source: $("edit-source").value.trim(),
+        importance: Number($("edit-importance").value),
+        tags: $("edit-tags").value.split(",").map((tag) => tag.trim()).filter(Boolean),
+        attributes,
+      }),
+    });
+    $("edit-status").textContent = "Saved";
+    const item = $("result-list").querySelector(`li[data-id="${memory.id}"] .result-text`);
+    if (item) item.textContent = memory.text;
+  } catch (err) {
+    $("edit-status").textContent = err.message;
+  }
+}
+
+async function deleteMemory() {
+  const id = state.selected;
+  if (!confirm(`Delete memory #${id} and its relationships?`)) return;
+  try {
+    await api("/api/memories/" + id, { method: "DELETE" });
+  } catch (err) {
+    $("edit-status").textContent = err.message;
+    return;
+  }
+  $("detail").hidden = true;
+  const item = $("result-list").querySelector(`li[data-id="${id}"]`);
+  if (item) item.remove();
+  state.selected = 0;
+}
+
+// Graph view: a small force-directed layout of the explore_connections subgraph
+
+const SVG = "http://www.w3.org/2000/svg";
+let simulation = null;
+
+function svg(tag, attrs = {}) {
+  const node = document.createElementNS(SVG, tag);
+  for (const [key, value] of Object.entries(attrs)) node.setAttribute(key, value);
+  return node;
+}
+
+async function loadGraph(id) {
+  const graph = $("graph");
+  let subgraph;
+  try {
+    subgraph = await api(`/api/memories/${id}/graph?depth=${$("depth").value}`);
+  } catch (err) {
+    graph.replaceChildren(svg("text", { x: 20, y: 30 }));
+    graph.firstChild.textContent = err.message;
+    return;
+  }
+  drawGraph(subgraph, id);
+}
+
+function drawGraph(subgraph, startID) {
+  const graph = $("graph");
+  const width = 800, height = 500;
+  if (simulation) cancelAnimationFrame(simulation);
+
+  const nodes = subgraph.nodes.map((node, i) => {
+    const angle = (2 * Math.PI * i) / subgraph.nodes.length;
+    const radius = node.depth * 90;
+    return {
+      memory: node.memory,
+      x: width / 2 + radius * Math.cos(angle),
+      y: height / 2 + radius * Math.sin(angle),
+      vx: 0, vy: 0,
+      fixed: false,
+    };
+  });
+  const byID = new Map(nodes.map((node) => [node.memory.id, node]));
+  const links = subgraph.edges
+    .filter((edge) => byID.has(edge.from_id) && byID.has(edge.to_id))
+    .map((edge) => ({ edge, source: byID.get(edge.from_id), target: byID.get(edge.to_id) }));
+
+  const defs = svg("defs");
+  const marker = svg("marker", { id: "arrow", viewBox: "0 0 10 10", refX: 20, refY: 5, markerWidth: 6, markerHeight: 6, orient: "auto" });
+  marker.append(svg("path", { d: "M0,0 L10,5 L0,10 z", fill: "#8c959f" }));
+  defs.append(marker);
+  graph.replaceChildren(defs);
+
+  for (const link of links) {
+    link.line = svg("line", { "marker-end": "url(#arrow)" });
+    link.label = svg("text", { class: "edge-label", "text-anchor": "middle" });
+    link.label.textContent = link.edge.type;
+    graph.append(link.line, link.label);
+  }
+  for (const node of nodes) {
+    node.circle = svg("circle", { r: 9, class: node.memory.id === startID ? "start" : "" });
+    const title = svg("title");
+    title.textContent = `#${node.memory.id}: ${node.memory.text}`;
+    node.circle.append(title);
+    node.label = svg("text", { class: "node-label", dx: 12, dy: 4 });
+    const text = node.memory.text;
+    node.label.textContent = `#${node.memory.id} ${text.length > 30 ? text.slice(0, 29) + "…" : text}`;
+    graph.append(node.circle, node.label);
+    enableDrag(node, startID, () => render(nodes, links));
+  }
+
+  let ticks = 0;
+  const step = () => {
+    tick(nodes, links, width, height);
+    render(nodes, links);
+    if (++ticks < 300) simulation = requestAnimationFrame(step);
+  };
+  step();
+}
+
+// tick moves nodes one step: every pair repels, edges pull like springs and
+// a weak force keeps everything near the centre
+function tick(nodes, links, width, height) {
+  for (let i = 0; i < nodes.length; i++) {
+    for (let j = i + 1; j < nodes.length; j++) {
+      const a = nodes[i], b = nodes[j];
+      let dx = b.x - a.x, dy = b.y - a.y;
+      const distance2 = Math.max(dx * dx + dy * dy, 1);
+      const force = 2000 / distance2;
+      const distance = Math.sqrt(distance2);
+      dx = (dx / distance) * force;
+      dy = (dy / distance) * force;
+      a.vx -= dx; a.vy -= dy;
+      b.vx += dx; b.vy += dy;
+    }
+  }
+  for (const { source, target } of links) {
+    const dx = target.x - source.x, dy = target.y - source.y;
+    const distance = Math.max(Math.sqrt(dx * dx + dy * dy), 1);
+    const force = (distance - 110) * 0.02;
+    source.vx += (dx / distance) * force; source.vy += (dy / distance) * force;
+    target.vx -= (dx / distance) * force; target.vy -= (dy / distance) * force;
+  }
+  for (const node of nodes) {
+    if (node.fixed) { node.vx = node.vy = 0; continue; }
+    node.vx += (width / 2 - node.x) * 0.005;
+    node.vy += (height / 2 - node.y) * 0.005;
+    node.vx *= 0.6; node.vy *= 0.6;
+    node.x = Math.min(width - 20, Math.max(20, node.x + node.vx));
+    node.y = Math.min(height - 20, Math.max(20, node.y + node.vy));
+  }
+}
+
+function render(nodes, links) {
+  for (const { line, label, source, target } of links) {
+    line.setAttribute("x1", source.x); line.setAttribute("y1", source.y);
+    line.setAttribute("x2", target.x); line.setAttribute("y2", target.y);
+    label.setAttribute("x", (source.x + target.x) / 2);
+    label.setAttribute("y", (source.y + target.y) / 2 - 3);
+  }
+  for (const node of nodes) {
+    node.circle.setAttribute("cx", node.x); node.circle.setAttribute("cy", node.y);
+    node.label.setAttribute("x", node.x); node.label.setAttribute("y", node.y);
+  }
+}
+
+// enableDrag lets a node be dragged; a press without movement opens the memory
+function enableDrag(node, startID, redraw) {
+  const graph = $("graph");
+  node.circle.addEventListener("pointerdown", (event) => {
+    event.preventDefault();
+    node.circle.setPointerCapture(event.pointerId);
+    const matrix = graph.getScreenCTM().inverse();
+    let moved = false;
+    node.fixed = true;
+
+    const move = (e) => {
+      const point = new DOMPoint(e.clientX, e.clientY).matrixTransform(matrix);
+      node.x = point.x; node.y = point.y;
+      moved = true;
+      redraw();
+    };
+    const up = () => {
+      node.circle.removeEventListener("pointermove", move);
+      node.circle.removeEventListener("pointerup", up);
+      if (!moved && node.memory.id !== startID) openMemory(node.memory.id);
+    };
+    node.circle.addEventListener("pointermove", move);
+    node.circle.addEventListener("pointerup", up);
+  });
+}
+
+// Wiring
+
+$("search-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const query = $("query").value.trim();
+  query ? search(query) : browse();
+});
+$("browse").addEventListener("click", () => { $("query").value = ""; browse(); });
+$("group").addEventListener("change", () => {
+  const query = $("query").value.trim();
+  query ? search(query) : browse();
+});
+$("more").addEventListener("click", () => browse(true));
+$("edit-form").addEventListener("submit", saveMemory);
+$("delete").addEventListener("click", deleteMemory);
+$("depth").addEventListener("change", () => state.selected && loadGraph(state.selected));
+
+loadGroups().catch((err) => setStatus(err.message));
+browse();
@@ -0,0 +1,304 @@
+package webui
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"advanced-go-example/pkg/embeddings"
+	"advanced-go-example/pkg/storage"
+)
+
+//go:embed static
+var static embed.FS
+
+// maxGraphDepth bounds the graph view; deeper neighbourhoods rarely fit on screen
+const maxGraphDepth = 3
+
+// maxRequestBody bounds the JSON body of an edit
+const maxRequestBody = 1 << 20
+
+// handler serves the dashboard and the JSON API behind it. It calls the
+// same storage methods as the MCP tools.
+type handler struct {
+	store      *storage.PostgresStore
+	embeddings *embeddings.Client
+}
+
+// NewHandler returns the web UI: static pages at / and a JSON API under
+// /api/. Edits use PUT and DELETE, which browsers never send cross-origin
+// without a CORS preflight, and the API answers none, so other sites cannot
+// change memories through a user's browser.
+func NewHandler(store *storage.PostgresStore, embeddingClient *embeddings.Client) http.Handler {
+	h := &handler{store: store, embeddings: embeddingClient}
+
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(assets))
+	mux.HandleFunc("GET /api/groups", h.groups)
+	mux.HandleFunc("GET /api/search", h.search)
+	mux.HandleFunc("GET /api/memories", h.list)
+	mux.HandleFunc("GET /api/memories/{id}", h.get)
+	mux.HandleFunc("PUT /api/memories/{id}", h.update)
+	mux.HandleFunc("DELETE /api/memories/{id}", h.delete)
+	mux.HandleFunc("GET /api/memories/{id}/graph", h.graph)
+	return mux
+}
+
+// badRequest marks errors caused by the request rather than the server
+type badRequest struct{ error }
+
+func badRequestf(format string, args ...interface{}) error {
+	return badRequest{fmt.Errorf(format, args...)}
+}
+
+// writeJSON sends value, or err as {"error": "..."} with a matching status
+func writeJSON(w http.ResponseWriter, value interface{}, err error) {
+	status := http.StatusOK
+	if err != nil {
+		var bad badRequest
+		switch {
+		case errors.As(err, &bad):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrMemoryNotFound):
+			status = http.StatusNotFound
+		default:
+			status = http.StatusInternalServerError
+			log.Printf("Web UI: %v", err)
+		}
+		value = map[string]string{"error": err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// intParam parses an optional integer query parameter within [min, max]
+func intParam(r *http.Request, name string, defaultValue, min, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, badRequestf("%s must be an integer between %d and %d, got %q", name, min, max, value)
+	}
+	return n, nil
+}
+
+// memoryID parses the {id} path segment
+func memoryID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequestf("invalid memory id %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func (h *handler) groups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.store.ListGroups()
+	writeJSON(w, map[string]interface{}{"groups": groups}, err)
+}
+
+func (h *handler) search(w http.ResponseWriter, r *http.Request) {
+	results, err := h.searchResults(r)
+	writeJSON(w, map[string]interface{}{"results": results}, err)
+}
+
+func (h *handler) searchResults(r *http.Request) ([]storage.SearchResult, error) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		return nil, badRequestf("q cannot be empty")
+	}
+	limit, err := intParam(r, "limit", 20, 1, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding, err := h.embeddings.Generate(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	results, err := h.store.SearchMemories(embedding, storage.SearchOptions{
+		Limit:   limit,
+		GroupID: r.URL.Query().Get("group_id"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+	if results == nil {
+		results = []storage.SearchResult{}
+	}
+	return results, nil
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	memories, next, err := h.listPage(r)
+	writeJSON(w, map[string]interface{}{"memories": memories, "next_cursor": next}, err)
+}
+
+func (h *handler) listPage(r *http.Request) ([]storage.Memory, string, error) {
+	params := r.URL.Query()
+	limit, err := intParam(r, "limit", 50, 1, 100)
+	if err != nil {
+		return nil, "", err
+	}
+	sortBy := params.Get("sort_by")
+	if sortBy != "" && !slices.Contains(storage.ListSortFields, sortBy) {
+		return nil, "", badRequestf("sort_by must be one of %s, got %q", strings.Join(storage.ListSortFields, ", "), sortBy)
+	}
+
+	memories, next, err := h.store.ListMemories(storage.ListOptions{
+		GroupID:   params.Get("group_id"),
+		SortBy:    sortBy,
+		Ascending: params.Get("order") == "asc",
+		Limit:     limit,
+		Cursor:    params.Get("cursor"),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list memories: %w", err)
+	}
+	if memories == nil {
+		memories = []storage.Memory{}
+	}
+	return memories, next, nil
+}
+
+// memoryDetail is a memory with the edges at either end of it
+type memoryDetail struct {
+	Memory storage.Memory         `json:"memory"`
+	Edges  []storage.Relationship `json:"edges"`
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.detail(r)
+	writeJSON(w, detail, err)
+}
+
+func (h *handler) detail(r *http.Request) (*memoryDetail, error) {
+	id, err := memoryID(r)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := h.store.GetMemoryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := h.store.ListRelationships(storage.RelationshipFilter{MemoryID: id})
+	if err != nil {
+		return nil, err
+	}
+	if edges == nil {
+		edges = []storage.Relationship{}
+	}
+	return &memoryDetail{Memory: *memory, Edges: edges}, nil
+}
+
+// memoryEdit is the editable part of a memory
+type memoryEdit struct {
+	Text       string                 `json:"text"`
+	GroupID    string                 `json:"group_id"`
+	Tags       []string               `json:"tags"`
+	Source     string                 `json:"source"`
+	Importance float64                `json:"importance"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	memory, err := h.edit(r)
+	writeJSON(w, memory, err)
+}
+
+func (h *handler) edit(r *http.Request) (*storage.Memory, error) {
+	id, err := memoryID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var edit memoryEdit
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&edit); err != nil {
+		return nil, badRequestf("invalid memory: %v", err)
+	}
+	if strings.TrimSpace(edit.Text) == "" {
+		return nil, badRequestf("text cannot be empty")
+	}
+	if edit.Importance < 0 || edit.Importance > 1 {
+		return nil, badRequestf("importance must be between 0 and 1, got %v", edit.Importance)
+	}
+
+	current, err := h.store.GetMemoryByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := storage.Memory{
+		ID:         id,
+		Text:       edit.Text,
+		GroupID:    edit.GroupID,
+		Tags:       edit.Tags,
+		Source:     edit.Source,
+		Importance: edit.Importance,
+		Attributes: edit.Attributes,
+	}
+	// Changed text needs a new embedding, or searches would match the old text
+	if edit.Text != current.Text {
+		if memory.Embedding, err = h.embeddings.Generate(edit.Text); err != nil {
+			return nil, fmt.Errorf("failed to generate embedding: %w", err)
+		}
+	}
+	return h.store.UpdateMemory(memory)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := memoryID(r)
+	if err == nil {
+		// Report unknown IDs instead of silently deleting nothing
+		_, err = h.store.GetMemoryByID(id)
+	}
+	if err == nil {
+		err = h.store.DeleteMemories([]int64{id})
+	}
+	writeJSON(w, map[string]bool{"deleted": err == nil}, err)
+}
+
+func (h *handler) graph(w http.ResponseWriter, r *http.Request) {
+	subgraph, err := h.subgraph(r)
+	writeJSON(w, subgraph, err)
+}
+
+func (h *handler) subgraph(r *http.Request) (*storage.Subgraph, error) {
+	id, err := memoryID(r)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := intParam(r, "depth", 2, 1, maxGraphDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// The traversal behind explore_connections, which also returns the edges
+	subgraph, err := h.store.Traverse(id, storage.TraversalOptions{MaxDepth: depth})
+	if err != nil {
+		return nil, err
+	}
+	if len(subgraph.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: %d", storage.ErrMemoryNotFound, id)
+	}
+	return subgraph, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds application configuration. It comes from the defaults, the
+// optional YAML file named by CONFIG_FILE and then environment variables.
+type Config struct {
+	DatabasePath     string
+	EmbeddingBaseURL string
+	EmbeddingModel   string
+	EmbeddingAPIKey  string
+	EmbeddingTimeout time.Duration
+
+	// How often the sweeper enforces Settings.Retention (0 disables it)
+	RetentionInterval time.Duration
+
+	// Settings is the part SIGHUP reloads; use currentSettings() to read it
+	Settings Settings
+}
+
+// Settings are the settings that are safe to change while the server runs
+type Settings struct {
+	LogLevel            slog.Level
+	SearchLimit         int     // Default search_memory limit
+	SearchMinSimilarity float64 // Default search_memory min_similarity
+	Retention           RetentionPolicy
+}
+
+// settings holds the current Settings, replaced on reload
+var settings atomic.Pointer[Settings]
+
+// logLevel is the level of the default slog handler
+var logLevel slog.LevelVar
+
+// currentSettings returns the settings in effect
+func currentSettings() Settings {
+	return *settings.Load()
+}
+
+// applySettings makes s the settings in effect
+func applySettings(s Settings) {
+	settings.Store(&s)
+	logLevel.Set(s.LogLevel)
+}
+
+// defaultConfig returns the built-in configuration
+func defaultConfig() Config {
+	return Config{
+		DatabasePath:      "memories.db",
+		EmbeddingBaseURL:  "http://localhost:1234/v1",
+		EmbeddingModel:    "text-embedding-embeddinggemma-300m-qat",
+		EmbeddingAPIKey:   "not-needed",
+		EmbeddingTimeout:  30 * time.Second,
+		RetentionInterval: time.Hour,
+		Settings: Settings{
+			LogLevel:    slog.LevelInfo,
+			SearchLimit: 5,
+		},
+	}
+}
+
+// configFile is the layout of the YAML config file. Its fields point into a
+// Config, so decoding overwrites only the keys the file sets.
+type configFile struct {
+	DatabasePath *string        `yaml:"database_path"`
+	Embeddings   embeddingsFile `yaml:"embeddings"`
+	Retention    retentionFile  `yaml:"retention"`
+	Search       searchFile     `yaml:"search"`
+	LogLevel     *slog.Level    `yaml:"log_level"`
+}
+
+// embeddingsFile is the embeddings section of the config file
+type embeddingsFile struct {
+	BaseURL *string        `yaml:"base_url"`
+	Model   *string        `yaml:"model"`
+	APIKey  *string        `yaml:"api_key"`
+	Timeout *time.Duration `yaml:"timeout"`
+}
+
+// retentionFile is the retention section of the config file
+type retentionFile struct {
+	Interval      *time.Duration `yaml:"interval"`
+	MaxAgeDays    *float64       `yaml:"max_age_days"`
+	MaxCount      *int           `yaml:"max_count"`
+	MinImportance *float64       `yaml:"min_importance"`
+}
+
+// searchFile is the search section of the config file
+type searchFile struct {
+	Limit         *int     `yaml:"limit"`
+	MinSimilarity *float64 `yaml:"min_similarity"`
+}
+
+// loadConfig builds the configuration from the defaults, the YAML file at
+// path (skipped when empty) and the environment, and validates it
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	envString := func(key string, dst *string) {
+		if value := os.Getenv(key); value != "" {
+			*dst = value
+		}
+	}
+	envParse := func(key string, parse func(string) error) {
+		if value := os.Getenv(key); value != "" {
+			if err := parse(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q", key, value))
+			}
+		}
+	}
+	envString("DATABASE_PATH", &cfg.DatabasePath)
+	envString("EMBEDDING_BASE_URL", &cfg.EmbeddingBaseURL)
+	envString("EMBEDDING_MODEL", &cfg.EmbeddingModel)
+	envString("EMBEDDING_API_KEY", &cfg.EmbeddingAPIKey)
+	envParse("RETENTION_INTERVAL", func(value string) (err error) {
+		cfg.RetentionInterval, err = time.ParseDuration(value)
+		return err
+	})
+	envParse("RETENTION_MAX_AGE_DAYS", func(value string) (err error) {
+		cfg.Settings.Retention.MaxAgeDays, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	envParse("RETENTION_MAX_COUNT", func(value string) (err error) {
+		cfg.Settings.Retention.MaxCount, err = strconv.Atoi(value)
+		return err
+	})
+	envParse("RETENTION_MIN_IMPORTANCE", func(value string) (err error) {
+		cfg.Settings.Retention.MinImportance, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	envParse("LOG_LEVEL", func(value string) error {
+		return cfg.Settings.LogLevel.UnmarshalText([]byte(value))
+	})
+	// DEBUG=true predates LOG_LEVEL and still turns on debug logging
+	if os.Getenv("LOG_LEVEL") == "" && os.Getenv("DEBUG") == "true" {
+		cfg.Settings.LogLevel = slog.LevelDebug
+	}
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("invalid environment:\n%w", errors.Join(errs...))
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// loadFile overrides settings with the keys set in the YAML file at path.
+// Unknown keys are errors, so a typo can't silently leave a default in place.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	loaded := *c
+	var schema configFile
+	schema.DatabasePath = &loaded.DatabasePath
+	schema.Embeddings.BaseURL = &loaded.EmbeddingBaseURL
+	schema.Embeddings.Model = &loaded.EmbeddingModel
+	schema.Embeddings.APIKey = &loaded.EmbeddingAPIKey
+	schema.Embeddings.Timeout = &loaded.EmbeddingTimeout
+	schema.Retention.Interval = &loaded.RetentionInterval
+	schema.Retention.MaxAgeDays = &loaded.Settings.Retention.MaxAgeDays
+	schema.Retention.MaxCount = &loaded.Settings.Retention.MaxCount
+	schema.Retention.MinImportance = &loaded.Settings.Retention.MinImportance
+	schema.Search.Limit = &loaded.Settings.SearchLimit
+	schema.Search.MinSimilarity = &loaded.Settings.SearchMinSimilarity
+	schema.LogLevel = &loaded.Settings.LogLevel
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&schema); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	*c = loaded
+	return nil
+}
+
+// validate reports every invalid setting, named by its config file key
+func (c Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DatabasePath != "", "database_path must be set")
+	u, err := url.Parse(c.EmbeddingBaseURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"embeddings.base_url must be an http or https URL, got %q", c.EmbeddingBaseURL)
+	check(c.EmbeddingModel != "", "embeddings.model must be set")
+	check(c.EmbeddingTimeout > 0, "embeddings.timeout must be positive, got %s", c.EmbeddingTimeout)
+	check(c.RetentionInterval >= 0, "retention.interval must not be negative, got %s", c.RetentionInterval)
+
+	s := c.Settings
+	check(s.Retention.MaxAgeDays >= 0, "retention.max_age_days must not be negative, got %v", s.Retention.MaxAgeDays)
+	check(s.Retention.MaxCount >= 0, "retention.max_count must not be negative, got %d", s.Retention.MaxCount)
+	check(s.Retention.MinImportance >= 0 && s.Retention.MinImportance <= 1,
+		"retention.min_importance must be between 0 and 1, got %v", s.Retention.MinImportance)
+	check(s.SearchLimit >= 1 && s.SearchLimit <= 100, "search.limit must be between 1 and 100, got %d", s.SearchLimit)
+	check(s.SearchMinSimilarity >= 0 && s.SearchMinSimilarity <= 1,
+		"search.min_similarity must be between 0 and 1, got %v", s.SearchMinSimilarity)
+
+	return errors.Join(errs...)
+}
+
+// watchReload reloads the configuration on SIGHUP until ctx is cancelled.
+// Only Settings take effect; an invalid reload keeps the current settings.
+func watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		next, err := loadConfig(os.Getenv("CONFIG_FILE"))
+		if err != nil {
+			log.Printf("Reload failed, keeping the current settings: %v", err)
+			continue
+		}
+
+		// Everything outside Settings is only read at startup
+		restart := next
+		restart.Settings = config.Settings
+		if restart != config {
+			log.Printf("Changes outside log_level, search and retention need a restart and were not applied")
+		}
+		applySettings(next.Settings)
+		log.Printf("Reloaded settings (log level %s, search limit %d, retention %+v)",
+			next.Settings.LogLevel, next.Settings.SearchLimit, next.Settings.Retention)
+	}
+}
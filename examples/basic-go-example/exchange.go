@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Portable export format, shared with the advanced example's pkg/exchange.
+// The first JSONL line is a header, followed by memory and edge records.
+// Memories are matched by external ID, so imports are idempotent.
+const (
+	exportFormat  = "mcp-memory-export"
+	exportVersion = 1
+)
+
+// exportHeader is the first line of every export
+type exportHeader struct {
+	Type                string    `json:"type"` // Always "header"
+	Format              string    `json:"format"`
+	Version             int       `json:"version"`
+	ExportedAt          time.Time `json:"exported_at,omitzero"`
+	Backend             string    `json:"backend,omitzero"`
+	EmbeddingModel      string    `json:"embedding_model,omitzero"`
+	EmbeddingDimensions int       `json:"embedding_dimensions,omitzero"`
+}
+
+// exportRecord is a memory or edge line. Type tells which fields are used.
+type exportRecord struct {
+	Type string `json:"type"` // memory or edge
+
+	// Memory fields
+	ExternalID string                 `json:"external_id,omitzero"`
+	Text       string                 `json:"text,omitzero"`
+	GroupID    string                 `json:"group_id,omitzero"` // Stored so groups survive a round trip
+	Tags       []string               `json:"tags,omitzero"`
+	Source     string                 `json:"source,omitzero"`
+	Importance *float64               `json:"importance,omitzero"` // Absent means defaultImportance; 0 is a real value
+	Attributes map[string]interface{} `json:"attributes,omitzero"`
+	CreatedAt  time.Time              `json:"created_at,omitzero"`
+	UpdatedAt  time.Time              `json:"updated_at,omitzero"`
+	ExpiresAt  time.Time              `json:"expires_at,omitzero"`
+	Embedding  []float64              `json:"embedding,omitzero"`
+
+	// Edge fields
+	From             string                 `json:"from,omitzero"`
+	To               string                 `json:"to,omitzero"`
+	RelationshipType string                 `json:"relationship_type,omitzero"`
+	Properties       map[string]interface{} `json:"properties,omitzero"`
+}
+
+// exportStats counts exported or imported records
+type exportStats struct {
+	Memories     int `json:"memories"`
+	Created      int `json:"created,omitzero"`
+	Updated      int `json:"updated,omitzero"`
+	Reembedded   int `json:"reembedded,omitzero"`
+	Edges        int `json:"edges"`
+	SkippedEdges int `json:"skipped_edges,omitzero"`
+}
+
+// exportMemories writes every memory and relationship as JSONL
+func exportMemories(w io.Writer, includeEmbeddings bool) (*exportStats, error) {
+	encoder := json.NewEncoder(w)
+	header := exportHeader{
+		Type:       "header",
+		Format:     exportFormat,
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC(),
+		Backend:    "sqlite",
+	}
+	if includeEmbeddings {
+		header.EmbeddingModel = config.EmbeddingModel
+	}
+
+	rows, err := db.Query("SELECT id, external_id, text, embedding, group_id, tags, source, importance, attributes, created_at, expires_at FROM memories ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	// Buffer records so the header can report the embedding dimensions
+	var records []exportRecord
+	externalIDs := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var record exportRecord
+		var embeddingJSON, tagsJSON, attributesJSON string
+		var groupID, source sql.NullString
+		var expiresAt sql.NullTime
+		var importance float64
+
+		if err := rows.Scan(&id, &record.ExternalID, &record.Text, &embeddingJSON, &groupID, &tagsJSON, &source,
+			&importance, &attributesJSON, &record.CreatedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory row: %w", err)
+		}
+		record.Type = "memory"
+		record.GroupID = groupID.String
+		record.Source = source.String
+		record.ExpiresAt = expiresAt.Time
+		record.Importance = &importance
+
+		if err := json.Unmarshal([]byte(tagsJSON), &record.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags for memory %d: %w", id, err)
+		}
+		if err := json.Unmarshal([]byte(attributesJSON), &record.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attributes for memory %d: %w", id, err)
+		}
+		if len(record.Tags) == 0 {
+			record.Tags = nil
+		}
+		if len(record.Attributes) == 0 {
+			record.Attributes = nil
+		}
+		if includeEmbeddings {
+			if err := json.Unmarshal([]byte(embeddingJSON), &record.Embedding); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal embedding for memory %d: %w", id, err)
+			}
+			header.EmbeddingDimensions = len(record.Embedding)
+		}
+
+		externalIDs[id] = record.ExternalID
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
+	}
+
+	edgeRows, err := db.Query("SELECT from_id, to_id, type, properties FROM relationships ORDER BY from_id, to_id, type")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relationships: %w", err)
+	}
+	defer edgeRows.Close()
+
+	stats := &exportStats{Memories: len(records)}
+	for edgeRows.Next() {
+		var fromID, toID int64
+		var record exportRecord
+		var propertiesJSON string
+		if err := edgeRows.Scan(&fromID, &toID, &record.RelationshipType, &propertiesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(propertiesJSON), &record.Properties); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal relationship properties: %w", err)
+		}
+
+		if len(record.Properties) == 0 {
+			record.Properties = nil
+		}
+
+		// Relationships of purged memories are skipped
+		var fromOK, toOK bool
+		record.From, fromOK = externalIDs[fromID]
+		record.To, toOK = externalIDs[toID]
+		if !fromOK || !toOK {
+			continue
+		}
+		record.Type = "edge"
+		records = append(records, record)
+		stats.Edges++
+	}
+	if err := edgeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relationships: %w", err)
+	}
+
+	if err := encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write export header: %w", err)
+	}
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to write export record: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// importMemories reads a JSONL export. Memories are upserted by external ID
+// and re-embedded when the export has no embeddings or used another model.
+// The returned stats are never nil and count what was written before any error.
+func importMemories(r io.Reader) (*exportStats, error) {
+	stats := &exportStats{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var header exportHeader
+	if !scanner.Scan() {
+		return stats, fmt.Errorf("export is empty")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return stats, fmt.Errorf("invalid export header: %w", err)
+	}
+	if header.Type != "header" || header.Format != exportFormat {
+		return stats, fmt.Errorf("not a memory export: first line must be a %q header", exportFormat)
+	}
+	if header.Version < 1 || header.Version > exportVersion {
+		return stats, fmt.Errorf("unsupported export version %d (supported: 1-%d)", header.Version, exportVersion)
+	}
+	reuseEmbeddings := header.EmbeddingModel == config.EmbeddingModel
+
+	ids := make(map[string]int64)
+	var edges []exportRecord
+	var changedIDs []int64
+	var changedGroups []string
+	defer func() { notifyMemoriesChanged(changedIDs, changedGroups) }()
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record exportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return stats, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+
+		switch record.Type {
+		case "edge":
+			if record.From == "" || record.To == "" || record.RelationshipType == "" {
+				return stats, fmt.Errorf("line %d: edge records need from, to and relationship_type", line)
+			}
+			// Edges may reference memories later in the file
+			edges = append(edges, record)
+		case "memory":
+			if record.ExternalID == "" || record.Text == "" {
+				return stats, fmt.Errorf("line %d: memory records need external_id and text", line)
+			}
+			if !reuseEmbeddings || len(record.Embedding) == 0 {
+				embedding, err := generateEmbedding(record.Text)
+				if err != nil {
+					return stats, fmt.Errorf("line %d: failed to generate embedding: %w", line, err)
+				}
+				record.Embedding = embedding
+				stats.Reembedded++
+			}
+
+			id, created, err := importMemory(record)
+			if err != nil {
+				return stats, fmt.Errorf("line %d: %w", line, err)
+			}
+			ids[record.ExternalID] = id
+			changedIDs = append(changedIDs, id)
+			changedGroups = append(changedGroups, record.GroupID)
+			stats.Memories++
+			if created {
+				stats.Created++
+			} else {
+				stats.Updated++
+			}
+		default:
+			return stats, fmt.Errorf("line %d: unknown record type %q", line, record.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	for _, edge := range edges {
+		fromID, fromErr := resolveExternalID(ids, edge.From)
+		toID, toErr := resolveExternalID(ids, edge.To)
+		if fromErr == sql.ErrNoRows || toErr == sql.ErrNoRows {
+			stats.SkippedEdges++
+			continue
+		}
+		if fromErr != nil || toErr != nil {
+			return stats, fmt.Errorf("failed to resolve edge %s -> %s: %v %v", edge.From, edge.To, fromErr, toErr)
+		}
+
+		propertiesJSON, err := json.Marshal(edge.Properties)
+		if err != nil || edge.Properties == nil {
+			propertiesJSON = []byte("{}")
+		}
+		if _, err := db.Exec(
+			"INSERT OR REPLACE INTO relationships (from_id, to_id, type, properties) VALUES (?, ?, ?, ?)",
+			fromID, toID, edge.RelationshipType, string(propertiesJSON),
+		); err != nil {
+			return stats, fmt.Errorf("failed to import edge %s -> %s: %w", edge.From, edge.To, err)
+		}
+		stats.Edges++
+	}
+
+	return stats, nil
+}
+
+// importMemory inserts a record, or updates the memory with the same external ID
+func importMemory(record exportRecord) (int64, bool, error) {
+	embeddingJSON, err := json.Marshal(record.Embedding)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to marshal embedding: %w", err)
+	}
+	tagsJSON, err := json.Marshal(normalizeTags(record.Tags))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	attributesJSON := []byte("{}")
+	if len(record.Attributes) > 0 {
+		if attributesJSON, err = json.Marshal(record.Attributes); err != nil {
+			return 0, false, fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+	}
+
+	importance := defaultImportance
+	if record.Importance != nil {
+		importance = *record.Importance
+	}
+
+	// Timestamps use the CURRENT_TIMESTAMP format so they compare as text
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var expiresAt interface{}
+	if !record.ExpiresAt.IsZero() {
+		expiresAt = record.ExpiresAt.UTC().Format(time.DateTime)
+	}
+
+	var id int64
+	err = db.QueryRow("SELECT id FROM memories WHERE external_id = ?", record.ExternalID).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := db.Exec(
+			"INSERT INTO memories (external_id, text, embedding, group_id, tags, source, importance, attributes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			record.ExternalID, record.Text, string(embeddingJSON), record.GroupID, string(tagsJSON), record.Source, importance,
+			string(attributesJSON), createdAt.UTC().Format(time.DateTime), expiresAt,
+		)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to import memory %s: %w", record.ExternalID, err)
+		}
+		id, _ = result.LastInsertId()
+		return id, true, nil
+	case err != nil:
+		return 0, false, fmt.Errorf("failed to look up memory %s: %w", record.ExternalID, err)
+	}
+
+	if _, err := db.Exec(
+		"UPDATE memories SET text = ?, embedding = ?, group_id = ?, tags = ?, source = ?, importance = ?, attributes = ?, expires_at = ? WHERE id = ?",
+		record.Text, string(embeddingJSON), record.GroupID, string(tagsJSON), record.Source, importance, string(attributesJSON), expiresAt, id,
+	); err != nil {
+		return 0, false, fmt.Errorf("failed to update memory %s: %w", record.ExternalID, err)
+	}
+	return id, false, nil
+}
+
+// resolveExternalID maps an external ID to a memory ID, checking the database
+// for memories that weren't part of the import
+func resolveExternalID(ids map[string]int64, externalID string) (int64, error) {
+	if id, ok := ids[externalID]; ok {
+		return id, nil
+	}
+	var id int64
+	err := db.QueryRow("SELECT id FROM memories WHERE external_id = ?", externalID).Scan(&id)
+	if err == nil {
+		ids[externalID] = id
+	}
+	return id, err
+}
+
+// ExportMemoriesInput defines the input for export_memories tool
+type ExportMemoriesInput struct {
+	Path              string `json:"path" jsonschema:"New file to write the JSONL export to; an existing file is not overwritten"`
+	IncludeEmbeddings *bool  `json:"include_embeddings,omitempty" jsonschema:"Include embedding vectors so imports with the same model skip re-embedding (default: true)"`
+}
+
+// handleExportMemories implements the export_memories tool
+func handleExportMemories(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ExportMemoriesInput,
+) (*mcp.CallToolResult, exportStats, error) {
+	if input.Path == "" {
+		return nil, exportStats{}, fmt.Errorf("path cannot be empty")
+	}
+
+	includeEmbeddings := true
+	if input.IncludeEmbeddings != nil {
+		includeEmbeddings = *input.IncludeEmbeddings
+	}
+
+	stats, err := exportToFile(input.Path, includeEmbeddings)
+	if err != nil {
+		return nil, exportStats{}, err
+	}
+	return nil, *stats, nil
+}
+
+// exportToFile exports into a new file at path. An existing file is never
+// overwritten, so the only file removed on failure is the one created here.
+func exportToFile(path string, includeEmbeddings bool) (*exportStats, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	stats, err := exportMemories(w, includeEmbeddings)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to export memories: %w", err)
+	}
+	return stats, nil
+}
+
+// ImportMemoriesInput defines the input for import_memories tool
+type ImportMemoriesInput struct {
+	Path string `json:"path" jsonschema:"JSONL export file to import"`
+}
+
+// handleImportMemories implements the import_memories tool
+func handleImportMemories(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input ImportMemoriesInput,
+) (*mcp.CallToolResult, exportStats, error) {
+	if input.Path == "" {
+		return nil, exportStats{}, fmt.Errorf("path cannot be empty")
+	}
+
+	file, err := os.Open(input.Path)
+	if err != nil {
+		return nil, exportStats{}, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	stats, err := importMemories(file)
+	if err != nil {
+		// Records before the failure stay imported; importing again is safe
+		return nil, exportStats{}, fmt.Errorf("import stopped after %d memories: %w", stats.Memories, err)
+	}
+	return nil, *stats, nil
+}
+
+// runExchangeCommand implements the export and import subcommands:
+//
+//	basic-go-example export [file]   (stdout when no file is given)
+//	basic-go-example import [file]   (stdin when no file is given)
+func runExchangeCommand(command string, args []string) error {
+	path := "-"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	switch command {
+	case "export":
+		var stats *exportStats
+		var err error
+		if path == "-" {
+			w := bufio.NewWriter(os.Stdout)
+			if stats, err = exportMemories(w, true); err == nil {
+				err = w.Flush()
+			}
+		} else {
+			stats, err = exportToFile(path, true)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d memories and %d edges\n", stats.Memories, stats.Edges)
+		return nil
+	case "import":
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			defer file.Close()
+			r = file
+		}
+		stats, err := importMemories(r)
+		fmt.Fprintf(os.Stderr, "Imported %d new and %d updated memories (%d re-embedded), %d edges (%d skipped)\n",
+			stats.Created, stats.Updated, stats.Reembedded, stats.Edges, stats.SkippedEdges)
+		return err
+	}
+	return fmt.Errorf("unknown command %q (expected export or import)", command)
+}
@@ -7,31 +7,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"math"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"advanced-go-example/pkg/filter"
+
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	_ "modernc.org/sqlite"
 )
 
-// Config holds application configuration
-type Config struct {
-	DatabasePath     string
-	EmbeddingBaseURL string
-	EmbeddingModel   string
-	EmbeddingAPIKey  string
-}
-
-// Memory represents a stored memory with its embedding
+// Memory represents a stored memory with its embedding and metadata
 type Memory struct {
-	ID        int64     `json:"id,omitzero"`
-	Text      string    `json:"text"`
-	Embedding []float64 `json:"embedding,omitzero"`
-	CreatedAt time.Time `json:"created_at,omitzero"`
+	ID         int64                  `json:"id,omitzero"`
+	ExternalID string                 `json:"external_id,omitzero"` // Stable across export and import
+	Text       string                 `json:"text"`
+	GroupID    string                 `json:"group_id,omitzero"`
+	Embedding  []float64              `json:"embedding,omitzero"`
+	Tags       []string               `json:"tags,omitzero"`
+	Source     string                 `json:"source,omitzero"`
+	Importance float64                `json:"importance,omitzero"` // 0 (trivial) to 1 (critical)
+	Attributes map[string]interface{} `json:"attributes,omitzero"` // Free-form JSON attributes
+	CreatedAt  time.Time              `json:"created_at,omitzero"`
+	ExpiresAt  time.Time              `json:"expires_at,omitzero"`
 }
 
+// defaultImportance is used when a memory is stored without an explicit importance
+const defaultImportance = 0.5
+
 // SearchResult pairs a memory with its similarity score
 type SearchResult struct {
 	Memory     Memory  `json:"memory"`
@@ -43,8 +49,13 @@ var db *sql.DB
 var config Config
 
 func main() {
-	// Load configuration
-	config = loadConfig()
+	// Load configuration from $CONFIG_FILE and the environment
+	var err error
+	if config, err = loadConfig(os.Getenv("CONFIG_FILE")); err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel})))
+	applySettings(config.Settings)
 
 	// Initialize database
 	if err := initDatabase(); err != nil {
@@ -52,11 +63,25 @@ func main() {
 	}
 	defer db.Close()
 
+	// Subcommands run once and exit instead of serving MCP
+	if len(os.Args) > 1 {
+		if err := runExchangeCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// Create MCP server
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "basic-go-memory",
 		Version: "1.0.0",
-	}, nil)
+	}, &mcp.ServerOptions{
+		SubscribeHandler:   handleSubscribe,
+		UnsubscribeHandler: handleUnsubscribe,
+	})
+
+	// Log tool calls when LOG_LEVEL is debug
+	server.AddReceivingMiddleware(logToolCalls)
 
 	// Register tools
 	mcp.AddTool(server, &mcp.Tool{
@@ -69,28 +94,39 @@ func main() {
 		Description: "Search for relevant memories using semantic similarity",
 	}, handleSearchMemory)
 
-	// Run server with stdio transport
-	ctx := context.Background()
-	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
-}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "retention_report",
+		Description: "Dry run of the retention sweeper: show which memories would be purged",
+	}, handleRetentionReport)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "export_memories",
+		Description: "Export memories, metadata and embeddings to a portable JSONL file",
+	}, handleExportMemories)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "import_memories",
+		Description: "Import a JSONL export; memories are matched by external ID, so re-importing is safe",
+	}, handleImportMemories)
 
-// loadConfig loads configuration from environment variables
-func loadConfig() Config {
-	return Config{
-		DatabasePath:     getEnv("DATABASE_PATH", "memories.db"),
-		EmbeddingBaseURL: getEnv("EMBEDDING_BASE_URL", "http://localhost:1234/v1"),
-		EmbeddingModel:   getEnv("EMBEDDING_MODEL", "text-embedding-embeddinggemma-300m-qat"),
-		EmbeddingAPIKey:  getEnv("EMBEDDING_API_KEY", "not-needed"),
+	// Expose memories as browsable resources
+	registerResources(server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Purge expired memories and enforce the retention policy in the background
+	if config.RetentionInterval > 0 {
+		go runRetentionSweeper(ctx, config.RetentionInterval)
 	}
-}
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	// Reload log level, search defaults and retention policy on SIGHUP
+	go watchReload(ctx)
+
+	// Run server with stdio transport
+	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
+		log.Fatalf("Server failed: %v", err)
 	}
-	return defaultValue
 }
 
 // initDatabase creates the SQLite database and schema
@@ -102,26 +138,113 @@ func initDatabase() error {
 	}
 
 	// Create memories table
+	// Tags and attributes are stored as JSON text and queried with SQLite's JSON functions
 	schema := `
 	CREATE TABLE IF NOT EXISTS memories (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		text TEXT NOT NULL,
 		embedding TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		tags TEXT NOT NULL DEFAULT '[]',
+		source TEXT,
+		importance REAL NOT NULL DEFAULT 0.5,
+		attributes TEXT NOT NULL DEFAULT '{}',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME,
+		external_id TEXT,
+		group_id TEXT -- Only set by imports, so groups survive a round trip
 	);
 	CREATE INDEX IF NOT EXISTS idx_created_at ON memories(created_at DESC);
+
+	-- Relationships have no tool of their own here, but are kept so exports
+	-- from the graph-backed server survive a round trip through SQLite
+	CREATE TABLE IF NOT EXISTS relationships (
+		from_id INTEGER NOT NULL,
+		to_id INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		properties TEXT NOT NULL DEFAULT '{}',
+		PRIMARY KEY (from_id, to_id, type)
+	);
+
+	-- One row per retention run (or dry run), recording what was purged and why
+	CREATE TABLE IF NOT EXISTS retention_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		dry_run BOOLEAN NOT NULL,
+		purged_count INTEGER NOT NULL,
+		memory_ids TEXT NOT NULL DEFAULT '[]',
+		reasons TEXT NOT NULL DEFAULT '{}'
+	);
 	`
 
 	if _, err := db.Exec(schema); err != nil {
 		return fmt.Errorf("failed to create schema: %w", err)
 	}
 
+	// Databases created by earlier versions lack the metadata columns
+	if err := addMissingColumns(map[string]string{
+		"tags":        "TEXT NOT NULL DEFAULT '[]'",
+		"source":      "TEXT",
+		"importance":  "REAL NOT NULL DEFAULT 0.5",
+		"attributes":  "TEXT NOT NULL DEFAULT '{}'",
+		"expires_at":  "DATETIME",
+		"external_id": "TEXT",
+		"group_id":    "TEXT",
+	}); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	// Give memories from earlier versions an external ID for export and import
+	migrateIDs := `
+	UPDATE memories SET external_id = lower(hex(randomblob(16))) WHERE external_id IS NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_external_id ON memories(external_id);
+	`
+	if _, err := db.Exec(migrateIDs); err != nil {
+		return fmt.Errorf("failed to assign external IDs: %w", err)
+	}
+
+	return nil
+}
+
+// addMissingColumns adds each column (name -> definition) that the memories table doesn't have yet
+func addMissingColumns(columns map[string]string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('memories')")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for name, definition := range columns {
+		if existing[name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE memories ADD COLUMN %s %s", name, definition)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
 // StoreMemoryInput defines the input for store_memory tool
 type StoreMemoryInput struct {
-	Text string `json:"text" jsonschema:"The text to remember"`
+	Text       string                 `json:"text" jsonschema:"The text to remember"`
+	Tags       []string               `json:"tags,omitempty" jsonschema:"Optional tags for categorizing and filtering"`
+	Source     string                 `json:"source,omitempty" jsonschema:"Optional source of the memory (URI, file path, conversation)"`
+	Importance *float64               `json:"importance,omitempty" jsonschema:"Importance from 0 (trivial) to 1 (critical) (default: 0.5)"`
+	Attributes map[string]interface{} `json:"attributes,omitempty" jsonschema:"Optional free-form JSON attributes"`
+	ExpiresAt  string                 `json:"expires_at,omitempty" jsonschema:"Optional RFC 3339 time after which the memory is purged"`
 }
 
 // StoreMemoryOutput defines the output for store_memory tool
@@ -141,6 +264,28 @@ func handleStoreMemory(
 		return nil, StoreMemoryOutput{}, fmt.Errorf("text cannot be empty")
 	}
 
+	// Default importance if not specified
+	importance := defaultImportance
+	if input.Importance != nil {
+		importance = *input.Importance
+	}
+	if importance < 0 || importance > 1 {
+		return nil, StoreMemoryOutput{}, fmt.Errorf("importance must be between 0 and 1, got %v", importance)
+	}
+
+	// Expiry is stored in UTC in the same format as CURRENT_TIMESTAMP so it compares as text
+	var expiresAt interface{}
+	if input.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, input.ExpiresAt)
+		if err != nil {
+			return nil, StoreMemoryOutput{}, fmt.Errorf("expires_at must be an RFC 3339 timestamp: %w", err)
+		}
+		if !t.After(time.Now()) {
+			return nil, StoreMemoryOutput{}, fmt.Errorf("expires_at must be in the future")
+		}
+		expiresAt = t.UTC().Format(time.DateTime)
+	}
+
 	// Generate embedding
 	embedding, err := generateEmbedding(input.Text)
 	if err != nil {
@@ -153,16 +298,35 @@ func handleStoreMemory(
 		return nil, StoreMemoryOutput{}, fmt.Errorf("failed to marshal embedding: %w", err)
 	}
 
+	tagsJSON, err := json.Marshal(normalizeTags(input.Tags))
+	if err != nil {
+		return nil, StoreMemoryOutput{}, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	attributesJSON := []byte("{}")
+	if len(input.Attributes) > 0 {
+		attributesJSON, err = json.Marshal(input.Attributes)
+		if err != nil {
+			return nil, StoreMemoryOutput{}, fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+	}
+
 	result, err := db.Exec(
-		"INSERT INTO memories (text, embedding) VALUES (?, ?)",
+		"INSERT INTO memories (text, embedding, tags, source, importance, attributes, expires_at, external_id) VALUES (?, ?, ?, ?, ?, ?, ?, lower(hex(randomblob(16))))",
 		input.Text,
 		string(embeddingJSON),
+		string(tagsJSON),
+		input.Source,
+		importance,
+		string(attributesJSON),
+		expiresAt,
 	)
 	if err != nil {
 		return nil, StoreMemoryOutput{}, fmt.Errorf("failed to store memory: %w", err)
 	}
 
 	id, _ := result.LastInsertId()
+	notifyMemoriesChanged([]int64{id}, nil)
 
 	return nil, StoreMemoryOutput{
 		Success: true,
@@ -173,9 +337,13 @@ func handleStoreMemory(
 
 // SearchMemoryInput defines the input for search_memory tool
 type SearchMemoryInput struct {
-	Query         string  `json:"query" jsonschema:"The search query"`
-	Limit         int     `json:"limit,omitempty" jsonschema:"Maximum number of results (default: 5)"`
-	MinSimilarity float64 `json:"min_similarity,omitempty" jsonschema:"Minimum similarity score 0-1 (default: 0.0)"`
+	Query         string                 `json:"query" jsonschema:"The search query"`
+	Limit         int                    `json:"limit,omitempty" jsonschema:"Maximum number of results (default: 5)"`
+	MinSimilarity float64                `json:"min_similarity,omitempty" jsonschema:"Minimum similarity score 0-1 (default: 0.0)"`
+	Tags          []string               `json:"tags,omitempty" jsonschema:"Optional tag filter"`
+	TagMode       string                 `json:"tag_mode,omitempty" jsonschema:"How tags are matched: any or all (default: any)"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty" jsonschema:"Optional attribute equality filter, e.g. {\"env\": \"prod\"}"`
+	Filter        map[string]interface{} `json:"filter,omitempty" jsonschema:"Optional structured filter: {\"and\"|\"or\": [...]}, {\"not\": {...}} or {\"field\", \"op\", \"value\"} over group_id, source, importance, created_at, tags, relationship and attributes.<key> (see README)"`
 }
 
 // SearchMemoryOutput defines the output for search_memory tool
@@ -195,10 +363,30 @@ func handleSearchMemory(
 	}
 
 	// Set defaults
+	defaults := currentSettings()
 	if input.Limit == 0 {
-		input.Limit = 5
+		input.Limit = defaults.SearchLimit
+	}
+	// MinSimilarity defaults to 0.0 (no filtering) unless the config sets a threshold
+	if input.MinSimilarity == 0 {
+		input.MinSimilarity = defaults.SearchMinSimilarity
+	}
+
+	// Parse the structured filter before spending time on the embedding
+	var expr *filter.Expr
+	if len(input.Filter) > 0 {
+		data, err := json.Marshal(input.Filter)
+		if err != nil {
+			return nil, SearchMemoryOutput{}, fmt.Errorf("failed to encode filter: %w", err)
+		}
+		if expr, err = filter.Parse(data); err != nil {
+			return nil, SearchMemoryOutput{}, err
+		}
+		// Memories here are never edited, so there is no updated_at column
+		if expr.UsesField(filter.FieldUpdatedAt) {
+			return nil, SearchMemoryOutput{}, fmt.Errorf("filter field %q is not supported by this server", filter.FieldUpdatedAt)
+		}
 	}
-	// MinSimilarity defaults to 0.0 (no filtering), users can optionally set a threshold
 
 	// Generate query embedding
 	queryEmbedding, err := generateEmbedding(input.Query)
@@ -206,8 +394,21 @@ func handleSearchMemory(
 		return nil, SearchMemoryOutput{}, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
+	// Metadata filters are applied in SQL so only matching rows are scored
+	where, args, err := metadataFilter(input.Tags, input.TagMode, input.Attributes)
+	if err != nil {
+		return nil, SearchMemoryOutput{}, err
+	}
+	if expr != nil {
+		condition, filterArgs, err := filter.Compile(expr, filter.SQLite, args)
+		if err != nil {
+			return nil, SearchMemoryOutput{}, err
+		}
+		where, args = where+" AND "+condition, filterArgs
+	}
+
 	// Search database
-	rows, err := db.Query("SELECT id, text, embedding, created_at FROM memories")
+	rows, err := db.Query("SELECT id, text, embedding, tags, source, importance, attributes, created_at FROM memories"+where, args...)
 	if err != nil {
 		return nil, SearchMemoryOutput{}, fmt.Errorf("failed to query memories: %w", err)
 	}
@@ -216,11 +417,27 @@ func handleSearchMemory(
 	var results []SearchResult
 	for rows.Next() {
 		var memory Memory
-		var embeddingJSON string
+		var embeddingJSON, tagsJSON, attributesJSON string
+		var source sql.NullString
 
-		if err := rows.Scan(&memory.ID, &memory.Text, &embeddingJSON, &memory.CreatedAt); err != nil {
+		if err := rows.Scan(&memory.ID, &memory.Text, &embeddingJSON, &tagsJSON, &source,
+			&memory.Importance, &attributesJSON, &memory.CreatedAt); err != nil {
 			return nil, SearchMemoryOutput{}, fmt.Errorf("failed to scan memory row: %w", err)
 		}
+		memory.Source = source.String
+
+		if err := json.Unmarshal([]byte(tagsJSON), &memory.Tags); err != nil {
+			return nil, SearchMemoryOutput{}, fmt.Errorf("failed to unmarshal tags for memory %d: %w", memory.ID, err)
+		}
+		if err := json.Unmarshal([]byte(attributesJSON), &memory.Attributes); err != nil {
+			return nil, SearchMemoryOutput{}, fmt.Errorf("failed to unmarshal attributes for memory %d: %w", memory.ID, err)
+		}
+		if len(memory.Tags) == 0 {
+			memory.Tags = nil
+		}
+		if len(memory.Attributes) == 0 {
+			memory.Attributes = nil
+		}
 
 		if err := json.Unmarshal([]byte(embeddingJSON), &memory.Embedding); err != nil {
 			return nil, SearchMemoryOutput{}, fmt.Errorf("failed to unmarshal embedding for memory %d: %w", memory.ID, err)
@@ -260,6 +477,62 @@ func handleSearchMemory(
 	}, nil
 }
 
+// normalizeTags trims whitespace and drops empty and duplicate tags
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// metadataFilter builds a WHERE clause (with its arguments) for expiry, tag and attribute filters.
+// Tags are matched against the JSON array with json_each, attributes with json_extract.
+func metadataFilter(tags []string, tagMode string, attributes map[string]interface{}) (string, []interface{}, error) {
+	// Expired memories stay hidden until the retention sweeper removes them
+	conditions := []string{"(expires_at IS NULL OR expires_at > datetime('now'))"}
+	var args []interface{}
+
+	tags = normalizeTags(tags)
+	switch tagMode {
+	case "", "any":
+		if len(tags) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tags)), ",")
+			conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value IN ("+placeholders+"))")
+			for _, tag := range tags {
+				args = append(args, tag)
+			}
+		}
+	case "all":
+		for _, tag := range tags {
+			conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(memories.tags) WHERE value = ?)")
+			args = append(args, tag)
+		}
+	default:
+		return "", nil, fmt.Errorf("tag_mode must be \"any\" or \"all\", got %q", tagMode)
+	}
+
+	for key, value := range attributes {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return "", nil, fmt.Errorf("attribute filter %q must be a string, number or boolean", key)
+		}
+		// Quote the key so dots and spaces aren't treated as JSON path syntax
+		path := `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+		conditions = append(conditions, "json_extract(attributes, ?) = ?")
+		args = append(args, path, value)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
 // generateEmbedding generates an embedding vector using LM Studio
 func generateEmbedding(text string) ([]float64, error) {
 	reqBody := map[string]interface{}{
@@ -283,7 +556,7 @@ func generateEmbedding(text string) ([]float64, error) {
 		req.Header.Set("Authorization", "Bearer "+config.EmbeddingAPIKey)
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: config.EmbeddingTimeout}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -330,3 +603,18 @@ func cosineSimilarity(a, b []float64) float64 {
 
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
+
+// logToolCalls logs every tool call at debug level
+func logToolCalls(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Params == nil || !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		slog.DebugContext(ctx, "tool call", "tool", call.Params.Name, "duration", time.Since(start), "error", err)
+		return result, err
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Memories are exposed as MCP resources:
+//
+//	memory://recent            the newest memories
+//	memory://{id}              one memory
+//	memory://group/{group_id}  the newest memories in a group
+const (
+	resourceScheme    = "memory://"
+	recentResourceURI = resourceScheme + "recent"
+	resourceMIMEType  = "application/json"
+
+	recentResourceLimit = 20  // Memories in memory://recent
+	groupResourceLimit  = 100 // Memories in memory://group/{group_id}
+	resourcePageSize    = 50  // Memories per resources/list page
+)
+
+// resourceServer receives resources/updated notifications; nil for subcommands
+var resourceServer *mcp.Server
+
+// registerResources adds the memory resources and replaces resources/list
+// with a paginated listing of stored memories
+func registerResources(server *mcp.Server) {
+	server.AddResource(&mcp.Resource{
+		URI:         recentResourceURI,
+		Name:        "recent-memories",
+		Title:       "Recent memories",
+		Description: fmt.Sprintf("The %d most recently stored memories", recentResourceLimit),
+		MIMEType:    resourceMIMEType,
+	}, handleReadRecent)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "{id}",
+		Name:        "memory",
+		Title:       "Memory by ID",
+		Description: "A single memory with its metadata",
+		MIMEType:    resourceMIMEType,
+	}, handleReadMemory)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "group/{group_id}",
+		Name:        "memory-group",
+		Title:       "Memories in a group",
+		Description: fmt.Sprintf("The %d most recent memories in a group", groupResourceLimit),
+		MIMEType:    resourceMIMEType,
+	}, handleReadGroup)
+
+	server.AddReceivingMiddleware(listResourcesMiddleware)
+	resourceServer = server
+}
+
+// handleSubscribe accepts subscriptions to memory resources
+func handleSubscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	if !strings.HasPrefix(req.Params.URI, resourceScheme) {
+		return fmt.Errorf("cannot subscribe to %s: only %s resources are supported", req.Params.URI, resourceScheme)
+	}
+	return nil
+}
+
+// handleUnsubscribe accepts unsubscribing from any resource
+func handleUnsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	return nil
+}
+
+func handleReadRecent(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	memories, err := recentMemories("", 0, recentResourceLimit)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, memories)
+}
+
+func handleReadMemory(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(req.Params.URI, resourceScheme), 10, 64)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+
+	memories, err := queryMemories(" AND id = ?", []interface{}{id}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	return jsonResource(req.Params.URI, memories[0])
+}
+
+func handleReadGroup(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	groupID, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, resourceScheme+"group/"))
+	if err != nil || groupID == "" {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+
+	memories, err := recentMemories(groupID, 0, groupResourceLimit)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, memories)
+}
+
+// listResourcesMiddleware answers resources/list from the database: the first
+// page starts with memory://recent, then every memory newest first. The
+// opaque cursor encodes the last memory ID of the previous page.
+func listResourcesMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "resources/list" {
+			return next(ctx, method, req)
+		}
+
+		var cursor string
+		if params, ok := req.GetParams().(*mcp.ListResourcesParams); ok && params != nil {
+			cursor = params.Cursor
+		}
+
+		result := &mcp.ListResourcesResult{Resources: []*mcp.Resource{}}
+		var beforeID int64
+		if cursor == "" {
+			result.Resources = append(result.Resources, &mcp.Resource{
+				URI:      recentResourceURI,
+				Name:     "recent-memories",
+				Title:    "Recent memories",
+				MIMEType: resourceMIMEType,
+			})
+		} else {
+			data, err := base64.RawURLEncoding.DecodeString(cursor)
+			if err == nil {
+				beforeID, err = strconv.ParseInt(string(data), 10, 64)
+			}
+			if err != nil || beforeID <= 0 {
+				return nil, fmt.Errorf("invalid cursor %q", cursor)
+			}
+		}
+
+		memories, err := recentMemories("", beforeID, resourcePageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, memory := range memories {
+			title := strings.Join(strings.Fields(memory.Text), " ")
+			if runes := []rune(title); len(runes) > 60 {
+				title = string(runes[:59]) + "…"
+			}
+			result.Resources = append(result.Resources, &mcp.Resource{
+				URI:      memoryResourceURI(memory.ID),
+				Name:     fmt.Sprintf("memory-%d", memory.ID),
+				Title:    title,
+				MIMEType: resourceMIMEType,
+			})
+		}
+		if len(memories) == resourcePageSize {
+			lastID := memories[len(memories)-1].ID
+			result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+		}
+
+		return result, nil
+	}
+}
+
+// notifyMemoriesChanged tells subscribers that memories, their groups and
+// memory://recent changed. Notifications wait on slow clients, so they are
+// sent in the background.
+func notifyMemoriesChanged(ids []int64, groupIDs []string) {
+	if resourceServer == nil || len(ids) == 0 {
+		return
+	}
+
+	uris := []string{recentResourceURI}
+	for _, id := range ids {
+		uris = append(uris, memoryResourceURI(id))
+	}
+	seen := make(map[string]bool)
+	for _, groupID := range groupIDs {
+		if groupID != "" && !seen[groupID] {
+			seen[groupID] = true
+			uris = append(uris, resourceScheme+"group/"+url.PathEscape(groupID))
+		}
+	}
+
+	go func() {
+		for _, uri := range uris {
+			resourceServer.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+		}
+	}()
+}
+
+func memoryResourceURI(id int64) string {
+	return resourceScheme + strconv.FormatInt(id, 10)
+}
+
+// recentMemories returns unexpired memories newest first, optionally in one
+// group and only with IDs below beforeID
+func recentMemories(groupID string, beforeID int64, limit int) ([]Memory, error) {
+	var conditions string
+	var args []interface{}
+	if groupID != "" {
+		conditions += " AND group_id = ?"
+		args = append(args, groupID)
+	}
+	if beforeID > 0 {
+		conditions += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	return queryMemories(conditions, args, limit)
+}
+
+// queryMemories loads unexpired memories (without embeddings) matching the
+// extra conditions, newest first
+func queryMemories(conditions string, args []interface{}, limit int) ([]Memory, error) {
+	where, filterArgs, err := metadataFilter(nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(filterArgs, append(args, limit)...)
+
+	rows, err := db.Query("SELECT id, external_id, text, group_id, tags, source, importance, attributes, created_at, expires_at FROM memories"+
+		where+conditions+" ORDER BY id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	memories := []Memory{}
+	for rows.Next() {
+		var memory Memory
+		var tagsJSON, attributesJSON string
+		var externalID, groupID, source sql.NullString
+		var expiresAt sql.NullTime
+
+		if err := rows.Scan(&memory.ID, &externalID, &memory.Text, &groupID, &tagsJSON, &source,
+			&memory.Importance, &attributesJSON, &memory.CreatedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory row: %w", err)
+		}
+		memory.ExternalID = externalID.String
+		memory.GroupID = groupID.String
+		memory.Source = source.String
+		memory.ExpiresAt = expiresAt.Time
+
+		if err := json.Unmarshal([]byte(tagsJSON), &memory.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags for memory %d: %w", memory.ID, err)
+		}
+		if err := json.Unmarshal([]byte(attributesJSON), &memory.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attributes for memory %d: %w", memory.ID, err)
+		}
+		if len(memory.Tags) == 0 {
+			memory.Tags = nil
+		}
+		if len(memory.Attributes) == 0 {
+			memory.Attributes = nil
+		}
+		memories = append(memories, memory)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
+	}
+	return memories, nil
+}
+
+// jsonResource renders value as an indented JSON resource
+func jsonResource(uri string, value interface{}) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: resourceMIMEType, Text: string(data)}},
+	}, nil
+}
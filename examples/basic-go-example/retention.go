@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RetentionPolicy limits how long memories are kept. Zero-valued rules are
+// not enforced. The basic server has no groups, so one policy covers every memory.
+type RetentionPolicy struct {
+	MaxAgeDays    float64 `json:"max_age_days,omitzero"`   // Purge memories older than this
+	MaxCount      int     `json:"max_count,omitzero"`      // Keep only the newest MaxCount memories
+	MinImportance float64 `json:"min_importance,omitzero"` // Purge memories less important than this
+}
+
+// PurgeCandidate is a memory that retention rules would remove
+type PurgeCandidate struct {
+	ID        int64     `json:"id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"` // expired, max_age, max_count or min_importance
+}
+
+// RetentionRun summarizes one enforcement pass
+type RetentionRun struct {
+	AuditID    int64            `json:"audit_id"`
+	DryRun     bool             `json:"dry_run"`
+	Candidates []PurgeCandidate `json:"candidates"`
+	Reasons    map[string]int   `json:"reasons"` // Candidate count per reason
+	Purged     int              `json:"purged"`
+}
+
+// findPurgeCandidates lists memories that are expired or violate the policy.
+// Each memory is reported once, with the first reason that applies.
+func findPurgeCandidates(policy RetentionPolicy) ([]PurgeCandidate, error) {
+	var maxAge, maxCount, minImportance interface{}
+	if policy.MaxAgeDays > 0 {
+		// Same format as CURRENT_TIMESTAMP, so created_at compares as text
+		maxAge = time.Now().Add(-time.Duration(policy.MaxAgeDays * float64(24*time.Hour))).UTC().Format(time.DateTime)
+	}
+	if policy.MaxCount > 0 {
+		maxCount = policy.MaxCount
+	}
+	if policy.MinImportance > 0 {
+		minImportance = policy.MinImportance
+	}
+
+	query := `
+	WITH ranked AS (
+		SELECT id, text, created_at, expires_at, importance,
+			row_number() OVER (ORDER BY created_at DESC, id DESC) AS newest_rank
+		FROM memories
+	), reasoned AS (
+		SELECT id, text, created_at,
+			CASE
+				WHEN expires_at IS NOT NULL AND expires_at <= datetime('now') THEN 'expired'
+				WHEN ?1 IS NOT NULL AND created_at < ?1 THEN 'max_age'
+				WHEN ?2 IS NOT NULL AND newest_rank > ?2 THEN 'max_count'
+				WHEN ?3 IS NOT NULL AND importance < ?3 THEN 'min_importance'
+			END AS reason
+		FROM ranked
+	)
+	SELECT id, text, created_at, reason FROM reasoned WHERE reason IS NOT NULL ORDER BY id
+	`
+
+	rows, err := db.Query(query, maxAge, maxCount, minImportance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find retention candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []PurgeCandidate{}
+	for rows.Next() {
+		var candidate PurgeCandidate
+		if err := rows.Scan(&candidate.ID, &candidate.Text, &candidate.CreatedAt, &candidate.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan retention candidate: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// runRetention enforces expiry and the policy. Deletes and the audit entry
+// are written in one transaction; dry runs are audited too.
+func runRetention(policy RetentionPolicy, dryRun bool) (*RetentionRun, error) {
+	candidates, err := findPurgeCandidates(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &RetentionRun{
+		DryRun:     dryRun,
+		Candidates: candidates,
+		Reasons:    map[string]int{},
+	}
+	ids := make([]int64, len(candidates))
+	for i, candidate := range candidates {
+		run.Reasons[candidate.Reason]++
+		ids[i] = candidate.ID
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin retention transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !dryRun && len(ids) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		args := make([]interface{}, len(ids))
+		for i, id := range ids {
+			args[i] = id
+		}
+		if _, err := tx.Exec("DELETE FROM memories WHERE id IN ("+placeholders+")", args...); err != nil {
+			return nil, fmt.Errorf("failed to delete memories: %w", err)
+		}
+		relationshipArgs := append(append([]interface{}{}, args...), args...)
+		if _, err := tx.Exec("DELETE FROM relationships WHERE from_id IN ("+placeholders+") OR to_id IN ("+placeholders+")", relationshipArgs...); err != nil {
+			return nil, fmt.Errorf("failed to delete relationships: %w", err)
+		}
+		run.Purged = len(ids)
+	}
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode purged ids: %w", err)
+	}
+	reasonsJSON, err := json.Marshal(run.Reasons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode retention reasons: %w", err)
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO retention_audit (dry_run, purged_count, memory_ids, reasons) VALUES (?, ?, ?, ?)",
+		dryRun, run.Purged, string(idsJSON), string(reasonsJSON),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write retention audit entry: %w", err)
+	}
+	run.AuditID, _ = result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit retention run: %w", err)
+	}
+	if run.Purged > 0 {
+		notifyMemoriesChanged(ids, nil)
+	}
+
+	return run, nil
+}
+
+// runRetentionSweeper enforces retention every interval until ctx is
+// cancelled. Each pass uses the current policy, so reloads apply to the next one.
+func runRetentionSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		run, err := runRetention(currentSettings().Retention, false)
+		if err != nil {
+			log.Printf("Retention sweep failed: %v", err)
+		} else if run.Purged > 0 {
+			log.Printf("Retention sweep purged %d memories %v (audit %d)", run.Purged, run.Reasons, run.AuditID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RetentionReportInput defines the input for retention_report tool
+type RetentionReportInput struct {
+	MaxCandidates int `json:"max_candidates,omitempty" jsonschema:"Maximum purge candidates to list (default: 50)"`
+}
+
+// RetentionReportOutput defines the output for retention_report tool
+type RetentionReportOutput struct {
+	Policy         RetentionPolicy  `json:"policy"`
+	Candidates     []PurgeCandidate `json:"candidates"`
+	CandidateCount int              `json:"candidate_count"`
+	Reasons        map[string]int   `json:"reasons"`
+	AuditID        int64            `json:"audit_id"`
+}
+
+// handleRetentionReport implements the retention_report tool as a dry run of the sweeper
+func handleRetentionReport(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	input RetentionReportInput,
+) (*mcp.CallToolResult, RetentionReportOutput, error) {
+	// Set defaults
+	if input.MaxCandidates == 0 {
+		input.MaxCandidates = 50
+	}
+
+	policy := currentSettings().Retention
+	run, err := runRetention(policy, true)
+	if err != nil {
+		return nil, RetentionReportOutput{}, fmt.Errorf("failed to evaluate retention: %w", err)
+	}
+
+	output := RetentionReportOutput{
+		Policy:         policy,
+		Candidates:     run.Candidates,
+		CandidateCount: len(run.Candidates),
+		Reasons:        run.Reasons,
+		AuditID:        run.AuditID,
+	}
+	if len(output.Candidates) > input.MaxCandidates {
+		output.Candidates = output.Candidates[:input.MaxCandidates]
+	}
+
+	return nil, output, nil
+}